@@ -0,0 +1,10 @@
+package openapi
+
+import (
+	_ "embed"
+)
+
+//go:generate go run ../../cmd/openapi -out user.openapi.json
+
+//go:embed user.openapi.json
+var Spec []byte
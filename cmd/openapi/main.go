@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/infrastructure/transport/http/v1"
+)
+
+func main() {
+	out := flag.String("out", "api/openapi/user.openapi.json", "path to write the OpenAPI document to")
+	flag.Parse()
+
+	spec, err := v1.GenerateOpenAPI(pb.File_api_proto_user_proto)
+	if err != nil {
+		log.Fatalf("failed to generate OpenAPI document: %v", err)
+	}
+
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		log.Fatalf("failed to write OpenAPI document: %v", err)
+	}
+}
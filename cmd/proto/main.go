@@ -2,18 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/dig"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 	pb "userCRUD/api/proto"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/config"
 	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/idempotency"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/common/requestid"
+	"userCRUD/internal/common/tracing"
 	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/federation"
+	"userCRUD/internal/user/infrastructure/health"
+	"userCRUD/internal/user/infrastructure/messaging"
+	"userCRUD/internal/user/infrastructure/oidc"
 	"userCRUD/internal/user/infrastructure/persistence"
+	federationv1 "userCRUD/internal/user/infrastructure/transport/federation/v1"
+	graphqlv1 "userCRUD/internal/user/infrastructure/transport/graphql/v1"
+	httpv1 "userCRUD/internal/user/infrastructure/transport/http/v1"
+	ldapv3 "userCRUD/internal/user/infrastructure/transport/ldap/v3"
+	oidcv1 "userCRUD/internal/user/infrastructure/transport/oidc/v1"
 	"userCRUD/internal/user/infrastructure/transport/proto/v1"
+	scimv2 "userCRUD/internal/user/infrastructure/transport/scim/v2"
+	"userCRUD/internal/user/infrastructure/webhook"
+	"userCRUD/pkg/common/password"
 )
 
 func main() {
@@ -28,34 +60,234 @@ func main() {
 func buildContainer() *dig.Container {
 	container := dig.New()
 
+	container.Provide(config.NewConfig)
 	container.Provide(deps.NewZapLogger, dig.As(new(deps.Logger)))
 	container.Provide(deps.NewGoPlaygroundValidator, dig.As(new(deps.Validator)))
-	container.Provide(persistence.NewUserRepositoryMemory, dig.As(new(persistence.UserRepository)))
-	container.Provide(func(ur persistence.UserRepository, v deps.Validator) *command.User {
-		return command.NewUserCommand(ur, v)
+	container.Provide(func(c *config.Config) deps.PasswordHasher {
+		return metrics.InstrumentHasher(tracing.TraceHasher(password.NewPool(c.PasswordHashCost, c.PasswordHashConcurrency, c.PasswordHashQueueDepth)))
+	})
+	container.Provide(func(c *config.Config) (*sdktrace.TracerProvider, error) {
+		exporter, err := tracing.NewExporter(context.Background(), c.TracingExporter, c.TracingEndpoint, c.TracingInsecure)
+		if err != nil {
+			return nil, err
+		}
+
+		tp := tracing.NewProvider(exporter, c.TracingSampleRatio)
+		tracing.Install(tp)
+
+		return tp, nil
+	})
+	container.Provide(func(c *config.Config, l deps.Logger, h deps.PasswordHasher) *persistence.UserRepositoryMemory {
+		return persistence.NewUserRepositoryMemory(l, h, c.OutboxRetention)
+	})
+	container.Provide(func(ur *persistence.UserRepositoryMemory) persistence.Outbox {
+		return ur
+	})
+	container.Provide(func(ur *persistence.UserRepositoryMemory) persistence.UserRepository {
+		metrics.RegisterUserCount(func() (int, error) {
+			return ur.CountUsers(context.Background())
+		})
+
+		return persistence.NewInstrumentedUserRepository(ur)
+	})
+	container.Provide(persistence.NewTokenRepositoryMemory, dig.As(new(persistence.TokenRepository)))
+	container.Provide(auditpersistence.NewAuditLogMemory, dig.As(new(auditpersistence.AuditLog)))
+	container.Provide(persistence.NewWebhookRepositoryMemory, dig.As(new(persistence.WebhookRepository)))
+	container.Provide(persistence.NewOIDCRepositoryMemory, dig.As(new(persistence.OIDCRepository)))
+	container.Provide(persistence.NewIdentityRepositoryMemory, dig.As(new(persistence.IdentityRepository)))
+	container.Provide(func(c *config.Config) *command.PasswordPolicy {
+		return command.NewPasswordPolicy(c.PasswordHistoryDepth, c.PasswordMaxAge)
+	})
+	container.Provide(command.NewUserCommand)
+	container.Provide(func(
+		c *config.Config,
+		ur persistence.UserRepository,
+		tr persistence.TokenRepository,
+		al auditpersistence.AuditLog,
+		v deps.Validator,
+		l deps.Logger,
+	) *command.Impersonation {
+		return command.NewImpersonationCommand(ur, tr, al, v, l, c.ImpersonationTTL)
+	})
+
+	container.Provide(func(c *config.Config, tr persistence.TokenRepository, al auditpersistence.AuditLog, l deps.Logger) *command.APIToken {
+		return command.NewAPITokenCommand(tr, al, l, c.APITokenMaxTTL)
+	})
+
+	container.Provide(func(
+		c *config.Config,
+		or persistence.OIDCRepository,
+		ur persistence.UserRepository,
+		al auditpersistence.AuditLog,
+		v deps.Validator,
+	) *command.OIDC {
+		return command.NewOIDCCommand(or, ur, al, v, c.OIDCCodeTTL)
+	})
+	container.Provide(func(c *config.Config, l deps.Logger) (*oidc.KeySet, error) {
+		return oidc.NewKeySet(l, c.OIDCKeyRotationInterval, c.OIDCTokenTTL, c.OIDCKeysFile)
+	})
+
+	container.Provide(func(c *config.Config) ([]*federation.ProviderConfig, error) {
+		return federation.LoadProviders(c.FederationProvidersFile)
+	})
+	container.Provide(func(
+		c *config.Config,
+		providers []*federation.ProviderConfig,
+		ir persistence.IdentityRepository,
+		ur persistence.UserRepository,
+		tr persistence.TokenRepository,
+		al auditpersistence.AuditLog,
+		v deps.Validator,
+		uc *command.User,
+		l deps.Logger,
+	) *command.Federation {
+		return command.NewFederationCommand(ir, ur, tr, al, v, uc, l, federation.Policies(providers), c.FederationSessionTTL)
+	})
+
+	container.Provide(func(c *config.Config) *webhook.Sender {
+		return webhook.NewSender(c.WebhookTimeout)
+	}, dig.As(new(command.WebhookSender)))
+	container.Provide(command.NewWebhookCommand)
+	container.Provide(command.NewWatchCommand)
+	container.Provide(func(c *config.Config, wr persistence.WebhookRepository, s command.WebhookSender, l deps.Logger) *webhook.Dispatcher {
+		return webhook.NewDispatcher(wr, s, l, c.WebhookWorkers, c.WebhookMaxAttempts, c.WebhookBackoffBase, c.WebhookBackoffMax)
+	})
+
+	container.Provide(func(c *config.Config, outbox persistence.Outbox, wd *webhook.Dispatcher, l deps.Logger) *messaging.Relay {
+		return messaging.NewRelay(outbox, l, c.OutboxBatchSize, c.OutboxRetryInterval, messaging.NewLogPublisher(l), wd)
+	})
+
+	container.Provide(func(c *config.Config, ur persistence.UserRepository, l deps.Logger) *health.Monitor {
+		m := health.NewMonitor(l, c.HealthCheckInterval, c.HealthCheckTimeout)
+		m.Register(pb.UserService_ServiceDesc.ServiceName, "user repository", ur.Ping)
+
+		return m
 	})
 
-	container.Provide(func(l deps.Logger, uc *command.User, ur persistence.UserRepository) *grpc.Server {
-		return newGRPCServer(uc, ur, l)
+	container.Provide(auth.NewAuthenticator)
+	container.Provide(graphqlv1.NewHandler)
+	container.Provide(scimv2.NewHandler)
+	container.Provide(func(c *config.Config, ur persistence.UserRepository, a *auth.Authenticator, g *v1.Guard, l deps.Logger) (*ldapv3.Server, error) {
+		return ldapv3.NewServer(ur, a, g, l, c.LDAPBaseDN)
+	})
+	container.Provide(func(
+		c *config.Config,
+		oc *command.OIDC,
+		ur persistence.UserRepository,
+		a *auth.Authenticator,
+		ks *oidc.KeySet,
+		l deps.Logger,
+	) *oidcv1.Handler {
+		return oidcv1.NewHandler(oc, ur, a, ks, l, c.OIDCIssuer, c.OIDCTokenTTL)
 	})
+	container.Provide(func(c *config.Config, providers []*federation.ProviderConfig, fc *command.Federation, l deps.Logger) (*federationv1.Handler, error) {
+		upstream := make([]*federation.Provider, len(providers))
+		for i, p := range providers {
+			upstream[i] = federation.NewProvider(p, federationv1.CallbackURL(c.OIDCIssuer, p.Name))
+		}
+
+		return federationv1.NewHandler(fc, upstream, l, c.OIDCIssuer)
+	})
+	container.Provide(func(c *config.Config, l deps.Logger) (*v1.AccessLog, error) {
+		sampling, err := v1.ParseSampling(c.AccessLogSampling)
+		if err != nil {
+			return nil, err
+		}
+
+		return v1.NewAccessLog(l, c.AccessLogPayloads, sampling), nil
+	})
+	container.Provide(func(c *config.Config, l deps.Logger) *v1.Recovery {
+		return v1.NewRecovery(l, c.CrashDumpDir)
+	})
+	container.Provide(func(c *config.Config) ratelimit.Store {
+		if c.RateLimitRedisAddr == "" {
+			return ratelimit.NewMemoryStore()
+		}
+
+		return ratelimit.NewRedisStore(redis.NewClient(&redis.Options{Addr: c.RateLimitRedisAddr}))
+	})
+	container.Provide(func(c *config.Config, store ratelimit.Store, l deps.Logger) (*v1.RateLimiter, error) {
+		limits, err := ratelimit.ParseLimits(c.RateLimits)
+		if err != nil {
+			return nil, err
+		}
+
+		return v1.NewRateLimiter(limits, store, l), nil
+	})
+	container.Provide(func(c *config.Config) *v1.LoadShedder {
+		if c.ConcurrencyLimit <= 0 {
+			return v1.NewLoadShedder(nil)
+		}
+
+		return v1.NewLoadShedder(loadshed.NewLimiter(c.ConcurrencyLimit, c.ConcurrencyLimitMin, c.ConcurrencyLimitMax,
+			c.ConcurrencyLatencyThreshold, c.ConcurrencyMutationLatency, func(limit int) { metrics.ConcurrencyLimit.Set(float64(limit)) }))
+	})
+	container.Provide(func(c *config.Config) *v1.Idempotency {
+		return v1.NewIdempotency(idempotency.NewStore(c.IdempotencyTTL))
+	})
+	container.Provide(v1.NewGuard)
+	container.Provide(newGRPCServer)
 
 	return container
 }
 
-func newGRPCServer(uc *command.User, ur persistence.UserRepository, l deps.Logger) *grpc.Server {
-	ai := v1.NewAuthInterceptor(ur, l)
+func newGRPCServer(
+	c *config.Config,
+	uc *command.User,
+	ic *command.Impersonation,
+	wc *command.Webhook,
+	watch *command.Watch,
+	tc *command.APIToken,
+	oc *command.OIDC,
+	fc *command.Federation,
+	ur persistence.UserRepository,
+	tr persistence.TokenRepository,
+	pp *command.PasswordPolicy,
+	hm *health.Monitor,
+	al *v1.AccessLog,
+	rec *v1.Recovery,
+	rl *v1.RateLimiter,
+	ls *v1.LoadShedder,
+	idem *v1.Idempotency,
+	l deps.Logger,
+) *grpc.Server {
 	chain := grpc.ChainUnaryInterceptor(
+		v1.MetricsInterceptor,
 		v1.TraceInterceptor,
-		ai,
+		v1.RequestIDInterceptor,
+		v1.PeerInterceptor,
+		al.Interceptor,
+		rec.Interceptor,
+		ls.Interceptor,
+		rl.PreAuthInterceptor,
+		v1.NewAuthInterceptor(ur, tr, pp, l),
+		rl.Interceptor,
+		idem.Interceptor,
 	)
-	server := grpc.NewServer(chain)
-	pb.RegisterUserServiceServer(server, v1.NewServer(l, uc))
+	streamChain := grpc.ChainStreamInterceptor(
+		v1.MetricsStreamInterceptor,
+		v1.TraceStreamInterceptor,
+		v1.RequestIDStreamInterceptor,
+		v1.PeerStreamInterceptor,
+		al.StreamInterceptor,
+		rec.StreamInterceptor,
+		ls.StreamInterceptor,
+		rl.PreAuthStreamInterceptor,
+		v1.NewAuthStreamInterceptor(ur, tr, pp, l),
+		rl.StreamInterceptor,
+	)
+	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()), chain, streamChain)
+	pb.RegisterUserServiceServer(server, v1.NewServer(l, uc, ic, wc, watch, tc, oc, fc))
+	healthpb.RegisterHealthServer(server, hm.Server())
+	if c.GRPCReflection {
+		reflection.Register(server)
+	}
 
 	return server
 }
 
-func runApp(logger deps.Logger, s *grpc.Server) {
-	lis, err := net.Listen("tcp", ":50051")
+func runApp(c *config.Config, logger deps.Logger, s *grpc.Server, gql *graphqlv1.Handler, scim *scimv2.Handler, ldap *ldapv3.Server, op *oidcv1.Handler, fh *federationv1.Handler, ks *oidc.KeySet, hm *health.Monitor, tp *sdktrace.TracerProvider, relay *messaging.Relay, wd *webhook.Dispatcher, watch *command.Watch, g *v1.Guard) {
+	lis, err := net.Listen("tcp", c.GRPCAddr)
 
 	if err != nil {
 		logger.Error(context.Background(), "failed to listen", "error", err)
@@ -67,7 +299,87 @@ func runApp(logger deps.Logger, s *grpc.Server) {
 			logger.Error(context.Background(), "failed to serve", "error", err)
 		}
 	}()
-	logger.Info(context.Background(), "Server started on :50051")
+	logger.Info(context.Background(), "Server started", "addr", c.GRPCAddr)
+
+	gatewayLis := v1.NewInProcessListener()
+	go func() {
+		if err := s.Serve(gatewayLis); err != nil {
+			logger.Error(context.Background(), "failed to serve gateway connections", "error", err)
+		}
+	}()
+
+	conn, err := grpc.Dial(
+		"passthrough:///inprocess",
+		grpc.WithContextDialer(gatewayLis.DialContext),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		logger.Error(context.Background(), "failed to dial gRPC server", "error", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle(graphqlv1.Path, httpv1.Guarded(g, "GraphQL", gql))
+	mux.Handle(scimv2.Prefix+"/", httpv1.Guarded(g, "SCIM", scim))
+	mux.Handle(oidcv1.DiscoveryPath, httpv1.Guarded(g, "OIDC", op))
+	mux.Handle(oidcv1.Prefix+"/", httpv1.Guarded(g, "OIDC", op))
+	mux.Handle(federationv1.Prefix+"/", httpv1.Guarded(g, "Federation", fh))
+	mux.Handle("/", httpv1.NewGateway(pb.NewUserServiceClient(conn)))
+
+	httpServer := &http.Server{
+		Addr: c.HTTPAddr,
+		Handler: otelhttp.NewHandler(requestid.Middleware(mux), "HTTP", otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return operation + " " + r.Method
+		})),
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(context.Background(), "failed to serve HTTP", "error", err)
+		}
+	}()
+	logger.Info(context.Background(), "HTTP gateway started", "addr", c.HTTPAddr)
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle(metrics.Path, metrics.Handler())
+	metricsServer := &http.Server{
+		Addr:    c.MetricsAddr,
+		Handler: metricsMux,
+	}
+	if c.MetricsAddr != "" {
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(context.Background(), "failed to serve metrics", "error", err)
+			}
+		}()
+		logger.Info(context.Background(), "Metrics listener started", "addr", c.MetricsAddr)
+	}
+
+	if c.LDAPAddr != "" {
+		ldapLis, err := net.Listen("tcp", c.LDAPAddr)
+		if err != nil {
+			logger.Error(context.Background(), "failed to listen for LDAP", "error", err)
+			os.Exit(1)
+		}
+
+		go func() {
+			if err := ldap.Serve(ldapLis); err != nil && !errors.Is(err, ldapv3.ErrServerClosed) {
+				logger.Error(context.Background(), "failed to serve LDAP", "error", err)
+			}
+		}()
+		logger.Info(context.Background(), "LDAP server started", "addr", c.LDAPAddr, "baseDN", c.LDAPBaseDN)
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, run := range []func(context.Context){relay.Run, wd.Run, ks.Run, hm.Run} {
+		workers.Add(1)
+		go func(run func(context.Context)) {
+			defer workers.Done()
+			run(workersCtx)
+		}(run)
+	}
 
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -75,6 +387,26 @@ func runApp(logger deps.Logger, s *grpc.Server) {
 
 	logger.Info(context.Background(), "Shutting down server...")
 
-	s.GracefulStop()
+	hm.Shutdown()
+	if c.ShutdownDrainDelay > 0 {
+		time.Sleep(c.ShutdownDrainDelay)
+	}
+
+	if err := httpServer.Shutdown(context.Background()); err != nil {
+		logger.Error(context.Background(), "failed to shut down HTTP gateway", "error", err)
+	}
+	if err := metricsServer.Shutdown(context.Background()); err != nil {
+		logger.Error(context.Background(), "failed to shut down metrics listener", "error", err)
+	}
+	ldap.Close()
+	watch.Close()
+	if !v1.GracefulStop(s, c.ShutdownTimeout) {
+		logger.Error(context.Background(), "gRPC calls did not finish in time, closed them", "timeout", c.ShutdownTimeout)
+	}
+	stopWorkers()
+	workers.Wait()
+	if err := tp.Shutdown(context.Background()); err != nil {
+		logger.Error(context.Background(), "failed to flush traces", "error", err)
+	}
 	logger.Info(context.Background(), "Server stopped")
 }
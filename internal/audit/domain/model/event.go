@@ -0,0 +1,74 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+const Redacted = "[REDACTED]"
+
+type Event struct {
+	Sequence       uint64
+	Timestamp      time.Time
+	ActorID        string
+	ImpersonatorID string
+	Action         string
+	TargetID       string
+	Changes        []Change
+	TraceID        string
+	PeerAddr       string
+	PrevHash       string
+	Hash           string
+}
+
+type Change struct {
+	Field  string
+	Before string
+	After  string
+}
+
+type Filter struct {
+	ActorID       string
+	TargetID      string
+	From          time.Time
+	To            time.Time
+	AfterSequence uint64
+	Limit         int `validate:"min=0,max=1000"`
+}
+
+func (f *Filter) Match(e *Event) bool {
+	if f.ActorID != "" && e.ActorID != f.ActorID && e.ImpersonatorID != f.ActorID {
+		return false
+	}
+	if f.TargetID != "" && e.TargetID != f.TargetID {
+		return false
+	}
+	if !f.From.IsZero() && e.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Timestamp.Before(f.To) {
+		return false
+	}
+
+	return e.Sequence > f.AfterSequence
+}
+
+func Diff(before, after map[string]string) []Change {
+	fields := make(map[string]struct{}, len(before)+len(after))
+	for field := range before {
+		fields[field] = struct{}{}
+	}
+	for field := range after {
+		fields[field] = struct{}{}
+	}
+
+	changes := make([]Change, 0, len(fields))
+	for field := range fields {
+		if before[field] != after[field] {
+			changes = append(changes, Change{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes
+}
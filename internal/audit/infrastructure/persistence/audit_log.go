@@ -0,0 +1,114 @@
+package persistence
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"userCRUD/internal/audit/domain/model"
+)
+
+var (
+	ErrChainBroken = errors.New("audit log hash chain is broken")
+)
+
+type AuditLog interface {
+	Append(ctx context.Context, event *model.Event) (*model.Event, error)
+	List(ctx context.Context, filter *model.Filter) ([]*model.Event, error)
+	Verify(ctx context.Context) error
+}
+
+type AuditLogMemory struct {
+	sync.RWMutex
+	events []*model.Event
+}
+
+func NewAuditLogMemory() *AuditLogMemory {
+	return &AuditLogMemory{
+		events: make([]*model.Event, 0, 64),
+	}
+}
+
+func (a *AuditLogMemory) Append(ctx context.Context, event *model.Event) (*model.Event, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	stored := *event
+	stored.Changes = append([]model.Change(nil), event.Changes...)
+	stored.Sequence = uint64(len(a.events)) + 1
+	stored.PrevHash = ""
+	if len(a.events) > 0 {
+		stored.PrevHash = a.events[len(a.events)-1].Hash
+	}
+
+	hash, err := hashEvent(&stored)
+	if err != nil {
+		return nil, err
+	}
+	stored.Hash = hash
+
+	a.events = append(a.events, &stored)
+
+	result := stored
+	return &result, nil
+}
+
+func (a *AuditLogMemory) List(ctx context.Context, filter *model.Filter) ([]*model.Event, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	events := make([]*model.Event, 0)
+	for _, e := range a.events {
+		if !filter.Match(e) {
+			continue
+		}
+
+		event := *e
+		events = append(events, &event)
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+func (a *AuditLogMemory) Verify(ctx context.Context) error {
+	a.RLock()
+	defer a.RUnlock()
+
+	prevHash := ""
+	for i, e := range a.events {
+		if e.Sequence != uint64(i)+1 || e.PrevHash != prevHash {
+			return fmt.Errorf("%w at sequence %d", ErrChainBroken, i+1)
+		}
+
+		hash, err := hashEvent(e)
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("%w at sequence %d", ErrChainBroken, e.Sequence)
+		}
+
+		prevHash = e.Hash
+	}
+
+	return nil
+}
+
+func hashEvent(e *model.Event) (string, error) {
+	unsigned := *e
+	unsigned.Hash = ""
+
+	payload, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
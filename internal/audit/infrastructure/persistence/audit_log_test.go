@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"userCRUD/internal/audit/domain/model"
+)
+
+var ctx = context.TODO()
+
+func TestAppendAndVerify(t *testing.T) {
+	log := NewAuditLogMemory()
+
+	first, err := log.Append(ctx, &model.Event{Timestamp: time.Now(), ActorID: "actor", Action: "user.create", TargetID: "first"})
+	if err != nil {
+		t.Fatalf("Failed to append event: %v", err)
+	}
+	second, err := log.Append(ctx, &model.Event{Timestamp: time.Now(), ActorID: "actor", Action: "user.delete", TargetID: "second"})
+	if err != nil {
+		t.Fatalf("Failed to append event: %v", err)
+	}
+
+	if first.Sequence != 1 || second.Sequence != 2 || second.PrevHash != first.Hash {
+		t.Errorf("Events are not chained: %+v, %+v", first, second)
+	}
+
+	if err := log.Verify(ctx); err != nil {
+		t.Errorf("Expected intact chain, got %v", err)
+	}
+
+	log.events[0].TargetID = "tampered"
+	if err := log.Verify(ctx); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("Expected ErrChainBroken, got %v", err)
+	}
+}
+
+func TestListFiltersEvents(t *testing.T) {
+	log := NewAuditLogMemory()
+	start := time.Now()
+
+	log.Append(ctx, &model.Event{Timestamp: start, ActorID: "alice", Action: "user.create", TargetID: "bob"})
+	log.Append(ctx, &model.Event{Timestamp: start.Add(time.Minute), ActorID: "carol", ImpersonatorID: "alice", Action: "user.update", TargetID: "dave"})
+	log.Append(ctx, &model.Event{Timestamp: start.Add(2 * time.Minute), ActorID: "carol", Action: "user.delete", TargetID: "bob"})
+
+	events, _ := log.List(ctx, &model.Filter{ActorID: "alice"})
+	if len(events) != 2 {
+		t.Errorf("Expected 2 events for alice including impersonation, got %d", len(events))
+	}
+
+	events, _ = log.List(ctx, &model.Filter{TargetID: "bob", From: start.Add(time.Second)})
+	if len(events) != 1 || events[0].Action != "user.delete" {
+		t.Errorf("Expected only the delete of bob, got %+v", events)
+	}
+
+	events, _ = log.List(ctx, &model.Filter{To: start.Add(time.Minute)})
+	if len(events) != 1 || events[0].Sequence != 1 {
+		t.Errorf("Expected events strictly before the upper bound, got %+v", events)
+	}
+
+	events, _ = log.List(ctx, &model.Filter{AfterSequence: 1, Limit: 1})
+	if len(events) != 1 || events[0].Sequence != 2 {
+		t.Errorf("Expected paging to return sequence 2, got %+v", events)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	GRPCAddr    string
+	HTTPAddr    string
+	MetricsAddr string
+
+	GRPCReflection      bool
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	ShutdownDrainDelay  time.Duration
+	ShutdownTimeout     time.Duration
+
+	AccessLogPayloads bool
+	AccessLogSampling string
+	CrashDumpDir      string
+
+	RateLimits         string
+	RateLimitRedisAddr string
+
+	ConcurrencyLimit            int
+	ConcurrencyLimitMin         int
+	ConcurrencyLimitMax         int
+	ConcurrencyLatencyThreshold time.Duration
+	ConcurrencyMutationLatency  time.Duration
+
+	IdempotencyTTL time.Duration
+
+	TracingExporter    string
+	TracingEndpoint    string
+	TracingInsecure    bool
+	TracingSampleRatio float64
+
+	LDAPAddr   string
+	LDAPBaseDN string
+
+	PasswordHashCost        int
+	PasswordHashConcurrency int
+	PasswordHashQueueDepth  int
+
+	PasswordHistoryDepth int
+	PasswordMaxAge       time.Duration
+
+	ImpersonationTTL time.Duration
+	APITokenMaxTTL   time.Duration
+
+	OIDCIssuer              string
+	OIDCTokenTTL            time.Duration
+	OIDCCodeTTL             time.Duration
+	OIDCKeyRotationInterval time.Duration
+	OIDCKeysFile            string
+
+	FederationProvidersFile string
+	FederationSessionTTL    time.Duration
+
+	OutboxBatchSize     int
+	OutboxRetryInterval time.Duration
+	OutboxRetention     int
+
+	WebhookWorkers     int
+	WebhookMaxAttempts int
+	WebhookBackoffBase time.Duration
+	WebhookBackoffMax  time.Duration
+	WebhookTimeout     time.Duration
+}
+
+func NewConfig() *Config {
+	return &Config{
+		GRPCAddr:    getEnv("GRPC_ADDR", ":50051"),
+		HTTPAddr:    getEnv("HTTP_ADDR", ":8080"),
+		MetricsAddr: lookupEnv("METRICS_ADDR", ":9090"),
+
+		GRPCReflection:      getEnvBool("GRPC_REFLECTION", false),
+		HealthCheckInterval: getEnvDuration("HEALTH_CHECK_INTERVAL", 10*time.Second),
+		HealthCheckTimeout:  getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		ShutdownDrainDelay:  getEnvDuration("SHUTDOWN_DRAIN_DELAY", 0),
+		ShutdownTimeout:     getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second),
+
+		AccessLogPayloads: getEnvBool("ACCESS_LOG_PAYLOADS", false),
+		AccessLogSampling: getEnv("ACCESS_LOG_SAMPLING", ""),
+		CrashDumpDir:      getEnv("CRASH_DUMP_DIR", ""),
+
+		RateLimits:         getEnv("RATE_LIMITS", "*.anonymous=20/s:40"),
+		RateLimitRedisAddr: getEnv("RATE_LIMIT_REDIS_ADDR", ""),
+
+		ConcurrencyLimit:            getEnvInt("CONCURRENCY_LIMIT", 64),
+		ConcurrencyLimitMin:         getEnvInt("CONCURRENCY_LIMIT_MIN", 4),
+		ConcurrencyLimitMax:         getEnvInt("CONCURRENCY_LIMIT_MAX", 512),
+		ConcurrencyLatencyThreshold: getEnvDuration("CONCURRENCY_LATENCY_THRESHOLD", time.Second),
+		ConcurrencyMutationLatency:  getEnvDuration("CONCURRENCY_MUTATION_LATENCY_THRESHOLD", 5*time.Second),
+
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingEndpoint:    getEnv("TRACING_OTLP_ENDPOINT", "localhost:4317"),
+		TracingInsecure:    getEnvBool("TRACING_OTLP_INSECURE", false),
+		TracingSampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+
+		LDAPAddr:   getEnv("LDAP_ADDR", ""),
+		LDAPBaseDN: getEnv("LDAP_BASE_DN", "ou=users,dc=usercrud,dc=local"),
+
+		PasswordHashCost:        getEnvInt("PASSWORD_HASH_COST", 14),
+		PasswordHashConcurrency: getEnvInt("PASSWORD_HASH_CONCURRENCY", runtime.NumCPU()),
+		PasswordHashQueueDepth:  getEnvInt("PASSWORD_HASH_QUEUE_DEPTH", 64),
+
+		PasswordHistoryDepth: getEnvInt("PASSWORD_HISTORY_DEPTH", 5),
+		PasswordMaxAge:       getEnvDuration("PASSWORD_MAX_AGE", 0),
+
+		ImpersonationTTL: getEnvDuration("IMPERSONATION_TTL", 15*time.Minute),
+		APITokenMaxTTL:   getEnvDuration("API_TOKEN_MAX_TTL", 90*24*time.Hour),
+
+		OIDCIssuer:              getEnv("OIDC_ISSUER", "http://localhost:8080"),
+		OIDCTokenTTL:            getEnvDuration("OIDC_TOKEN_TTL", time.Hour),
+		OIDCCodeTTL:             getEnvDuration("OIDC_CODE_TTL", time.Minute),
+		OIDCKeyRotationInterval: getEnvDuration("OIDC_KEY_ROTATION_INTERVAL", 24*time.Hour),
+		OIDCKeysFile:            getEnv("OIDC_KEYS_FILE", ""),
+
+		FederationProvidersFile: getEnv("FEDERATION_PROVIDERS_FILE", ""),
+		FederationSessionTTL:    getEnvDuration("FEDERATION_SESSION_TTL", 12*time.Hour),
+
+		OutboxBatchSize:     getEnvInt("OUTBOX_BATCH_SIZE", 100),
+		OutboxRetryInterval: getEnvDuration("OUTBOX_RETRY_INTERVAL", time.Second),
+		OutboxRetention:     getEnvInt("OUTBOX_RETENTION", 10000),
+
+		WebhookWorkers:     getEnvInt("WEBHOOK_WORKERS", 4),
+		WebhookMaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase: getEnvDuration("WEBHOOK_BACKOFF_BASE", time.Second),
+		WebhookBackoffMax:  getEnvDuration("WEBHOOK_BACKOFF_MAX", 5*time.Minute),
+		WebhookTimeout:     getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func lookupEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
@@ -1,6 +1,12 @@
 package constants
 
 const (
-	TraceId        = "TraceID"
-	UserContextKey = "UserContextKey"
+	TraceId         = "TraceID"
+	SpanID          = "SpanID"
+	RequestID       = "RequestID"
+	UserID          = "UserID"
+	ActorID         = "ActorID"
+	PeerAddr        = "PeerAddr"
+	UserContextKey  = "UserContextKey"
+	ActorContextKey = "ActorContextKey"
 )
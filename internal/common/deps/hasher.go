@@ -0,0 +1,8 @@
+package deps
+
+import "context"
+
+type PasswordHasher interface {
+	Hash(ctx context.Context, password string) (string, error)
+	Compare(ctx context.Context, password, hash string) (bool, error)
+}
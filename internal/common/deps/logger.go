@@ -2,6 +2,7 @@ package deps
 
 import (
 	"context"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"userCRUD/internal/common/constants"
@@ -49,20 +50,30 @@ func NewZapLogger() *ZapLogger {
 }
 
 func (l *ZapLogger) Info(ctx context.Context, msg string, keysAndValues ...interface{}) {
-	l.Sugar().Infow(msg, withTraceId(ctx, keysAndValues)...)
+	l.Sugar().Infow(msg, withContextFields(ctx, keysAndValues)...)
 }
 
 func (l *ZapLogger) Debug(ctx context.Context, msg string, keysAndValues ...interface{}) {
-	l.Sugar().Debugw(msg, withTraceId(ctx, keysAndValues)...)
+	l.Sugar().Debugw(msg, withContextFields(ctx, keysAndValues)...)
 }
 
 func (l *ZapLogger) Error(ctx context.Context, msg string, keysAndValues ...interface{}) {
-	l.Sugar().Errorw(msg, withTraceId(ctx, keysAndValues)...)
+	l.Sugar().Errorw(msg, withContextFields(ctx, keysAndValues)...)
 }
 
-func withTraceId(ctx context.Context, keysAndValues []interface{}) []interface{} {
-	if traceID, ok := ctx.Value(constants.TraceId).(string); ok {
-		return append([]interface{}{constants.TraceId, traceID}, keysAndValues...)
+func withContextFields(ctx context.Context, keysAndValues []interface{}) []interface{} {
+	fields := make([]interface{}, 0, 10+len(keysAndValues))
+	for _, key := range []string{constants.TraceId, constants.RequestID, constants.UserID, constants.ActorID} {
+		if value, ok := ctx.Value(key).(string); ok {
+			fields = append(fields, key, value)
+		}
 	}
-	return keysAndValues
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		if _, ok := ctx.Value(constants.TraceId).(string); !ok {
+			fields = append(fields, constants.TraceId, sc.TraceID().String())
+		}
+		fields = append(fields, constants.SpanID, sc.SpanID().String())
+	}
+
+	return append(fields, keysAndValues...)
 }
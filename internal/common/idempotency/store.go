@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+var (
+	ErrInvalidKey = errors.New("idempotency key must be 1-255 printable ASCII characters")
+	ErrKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+var pattern = regexp.MustCompile(`^[\x21-\x7e]{1,255}$`)
+
+type Result struct {
+	Response []byte
+	Status   []byte
+}
+
+type entry struct {
+	fingerprint string
+	result      *Result
+	expires     time.Time
+}
+
+type Store struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		entries: make(map[string]*entry),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func ValidKey(key string) bool {
+	return pattern.MatchString(key)
+}
+
+func (s *Store) Begin(key, fingerprint string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, ErrKeyReused
+		case e.result == nil:
+			return nil, ErrInProgress
+		default:
+			return e.result, nil
+		}
+	}
+
+	s.entries[key] = &entry{fingerprint: fingerprint, expires: now.Add(s.ttl)}
+
+	return nil, nil
+}
+
+func (s *Store) Finish(key string, result *Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	if result == nil {
+		delete(s.entries, key)
+		return
+	}
+
+	e.result = result
+	e.expires = s.now().Add(s.ttl)
+}
+
+func (s *Store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package idempotency
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewStore(time.Hour)
+	s.now = func() time.Time { return now }
+
+	if result, err := s.Begin("key", "a"); result != nil || err != nil {
+		t.Fatalf("Expected the first call to reserve the key, got %v, %v", result, err)
+	}
+	if _, err := s.Begin("key", "a"); !errors.Is(err, ErrInProgress) {
+		t.Errorf("Expected ErrInProgress while the first call runs, got %v", err)
+	}
+
+	s.Finish("key", &Result{Response: []byte("response")})
+	if result, err := s.Begin("key", "a"); err != nil || result == nil || string(result.Response) != "response" {
+		t.Errorf("Expected the stored result to be replayed, got %v, %v", result, err)
+	}
+	if _, err := s.Begin("key", "b"); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("Expected ErrKeyReused for another payload, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if result, err := s.Begin("key", "b"); result != nil || err != nil {
+		t.Errorf("Expected an expired key to be reusable, got %v, %v", result, err)
+	}
+
+	s.Finish("key", nil)
+	if result, err := s.Begin("key", "c"); result != nil || err != nil {
+		t.Errorf("Expected a released key to be reusable, got %v, %v", result, err)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"3f1c9a7e-6d2b-4c55-8f0e-1a2b3c4d5e6f", true},
+		{"", false},
+		{"has space", false},
+		{"ключ", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.valid {
+			t.Errorf("ValidKey(%q) = %v, expected %v", tt.key, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	PriorityMutation Priority = iota
+	PriorityRead
+	PriorityCritical
+
+	backoffRatio  = 0.9
+	mutationShare = 0.8
+)
+
+var (
+	ErrOverloaded = errors.New("server is overloaded, try again later")
+)
+
+type Priority int
+
+type slotKey struct{}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PriorityRead:
+		return "read"
+	default:
+		return "mutation"
+	}
+}
+
+type Limiter struct {
+	mu        sync.Mutex
+	limit     float64
+	min       float64
+	max       float64
+	inflight  int
+	threshold time.Duration
+	mutation  time.Duration
+	onChange  func(limit int)
+	now       func() time.Time
+}
+
+type Slot struct {
+	l        *Limiter
+	priority Priority
+	start    time.Time
+	untimed  bool
+}
+
+func NewLimiter(initial, min, max int, threshold, mutationThreshold time.Duration, onChange func(limit int)) *Limiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+
+	l := &Limiter{
+		limit:     math.Min(math.Max(float64(initial), float64(min)), float64(max)),
+		min:       float64(min),
+		max:       float64(max),
+		threshold: threshold,
+		mutation:  mutationThreshold,
+		onChange:  onChange,
+		now:       time.Now,
+	}
+	l.changed()
+
+	return l
+}
+
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+func (l *Limiter) Allow(p Priority) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.admits(p)
+}
+
+func (l *Limiter) Acquire(p Priority) (*Slot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.admits(p) {
+		return nil, ErrOverloaded
+	}
+	l.inflight++
+
+	return &Slot{l: l, priority: p, start: l.now()}, nil
+}
+
+func (s *Slot) Restart() {
+	s.l.mu.Lock()
+	defer s.l.mu.Unlock()
+
+	s.start = s.l.now()
+}
+
+func (s *Slot) Untimed() {
+	s.untimed = true
+}
+
+func (s *Slot) Release(overloaded bool) {
+	s.l.mu.Lock()
+	latency := s.l.now().Sub(s.start)
+	s.l.mu.Unlock()
+
+	slow := !s.untimed && latency > s.l.thresholdFor(s.priority)
+	s.l.release(overloaded || slow)
+}
+
+func WithSlot(ctx context.Context, s *Slot) context.Context {
+	return context.WithValue(ctx, slotKey{}, s)
+}
+
+func Restart(ctx context.Context) {
+	if s, ok := ctx.Value(slotKey{}).(*Slot); ok {
+		s.Restart()
+	}
+}
+
+func (l *Limiter) admits(p Priority) bool {
+	switch p {
+	case PriorityCritical:
+		return true
+	case PriorityRead:
+		return float64(l.inflight) < math.Floor(l.limit)
+	default:
+		return float64(l.inflight) < math.Max(1, math.Floor(l.limit*mutationShare))
+	}
+}
+
+func (l *Limiter) thresholdFor(p Priority) time.Duration {
+	if p == PriorityMutation && l.mutation > l.threshold {
+		return l.mutation
+	}
+
+	return l.threshold
+}
+
+func (l *Limiter) release(overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	utilized := float64(l.inflight)*2 >= l.limit
+	l.inflight--
+
+	previous := int(l.limit)
+	switch {
+	case overloaded:
+		l.limit = math.Max(l.min, l.limit*backoffRatio)
+	case utilized:
+		l.limit = math.Min(l.max, l.limit+1/l.limit)
+	}
+
+	if int(l.limit) != previous {
+		l.changed()
+	}
+}
+
+func (l *Limiter) changed() {
+	if l.onChange != nil {
+		l.onChange(int(l.limit))
+	}
+}
@@ -0,0 +1,123 @@
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterPriorities(t *testing.T) {
+	l := NewLimiter(10, 1, 100, time.Second, time.Second, nil)
+
+	var slots []*Slot
+	for i := 0; i < 8; i++ {
+		slot, err := l.Acquire(PriorityMutation)
+		if err != nil {
+			t.Fatalf("Expected mutation %d to be admitted, got %v", i+1, err)
+		}
+		slots = append(slots, slot)
+	}
+	if _, err := l.Acquire(PriorityMutation); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Expected mutations beyond their share to be shed, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		slot, err := l.Acquire(PriorityRead)
+		if err != nil {
+			t.Fatalf("Expected read %d to use the reserved capacity, got %v", i+1, err)
+		}
+		slots = append(slots, slot)
+	}
+	if l.Allow(PriorityRead) {
+		t.Error("Expected reads beyond the limit to be shed")
+	}
+	if !l.Allow(PriorityCritical) {
+		t.Error("Expected critical calls to always be admitted")
+	}
+
+	for _, slot := range slots {
+		slot.Release(false)
+	}
+	if !l.Allow(PriorityMutation) {
+		t.Error("Expected capacity to be returned on release")
+	}
+}
+
+func TestLimiterAIMD(t *testing.T) {
+	var reported []int
+	l := NewLimiter(10, 4, 12, 100*time.Millisecond, 100*time.Millisecond, func(limit int) { reported = append(reported, limit) })
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 200; i++ {
+		var slots []*Slot
+		for j := 0; j < 8; j++ {
+			slot, _ := l.Acquire(PriorityRead)
+			slots = append(slots, slot)
+		}
+		for _, slot := range slots {
+			slot.Release(false)
+		}
+	}
+	if got := l.Limit(); got != 12 {
+		t.Errorf("Expected the limit to grow up to the maximum, got %d", got)
+	}
+
+	slot, _ := l.Acquire(PriorityRead)
+	slot.Release(true)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Expected the limit to back off after overload, got %d", got)
+	}
+
+	for i := 0; i < 20; i++ {
+		slot, _ := l.Acquire(PriorityRead)
+		now = now.Add(time.Second)
+		slot.Release(false)
+	}
+	if got := l.Limit(); got != 4 {
+		t.Errorf("Expected slow calls to shrink the limit to the minimum, got %d", got)
+	}
+
+	if len(reported) == 0 || reported[0] != 10 || reported[len(reported)-1] != 4 {
+		t.Errorf("Expected limit changes to be reported, got %v", reported)
+	}
+}
+
+func TestLimiterLatencyWindow(t *testing.T) {
+	l := NewLimiter(10, 1, 10, time.Second, 5*time.Second, nil)
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+
+	slot, _ := l.Acquire(PriorityRead)
+	ctx := WithSlot(context.Background(), slot)
+	now = now.Add(2 * time.Second)
+	Restart(ctx)
+	now = now.Add(500 * time.Millisecond)
+	slot.Release(false)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Expected time before Restart not to count, got limit %d", got)
+	}
+
+	slot, _ = l.Acquire(PriorityMutation)
+	now = now.Add(3 * time.Second)
+	slot.Release(false)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Expected mutations to use their own threshold, got limit %d", got)
+	}
+
+	slot, _ = l.Acquire(PriorityRead)
+	slot.Untimed()
+	now = now.Add(time.Minute)
+	slot.Release(false)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Expected untimed slots not to count latency, got limit %d", got)
+	}
+
+	slot, _ = l.Acquire(PriorityRead)
+	now = now.Add(2 * time.Second)
+	slot.Release(false)
+	if got := l.Limit(); got != 9 {
+		t.Errorf("Expected slow reads to shrink the limit, got %d", got)
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/pkg/common/password"
+)
+
+type instrumentedHasher struct {
+	h deps.PasswordHasher
+}
+
+func InstrumentHasher(h deps.PasswordHasher) deps.PasswordHasher {
+	return &instrumentedHasher{h: h}
+}
+
+func (i *instrumentedHasher) Hash(ctx context.Context, rawPassword string) (string, error) {
+	start := time.Now()
+	hash, err := i.h.Hash(ctx, rawPassword)
+	PasswordHashDuration.WithLabelValues("hash", hashResult(err)).Observe(time.Since(start).Seconds())
+
+	return hash, err
+}
+
+func (i *instrumentedHasher) Compare(ctx context.Context, rawPassword, hash string) (bool, error) {
+	start := time.Now()
+	match, err := i.h.Compare(ctx, rawPassword, hash)
+	PasswordHashDuration.WithLabelValues("compare", hashResult(err)).Observe(time.Since(start).Seconds())
+
+	return match, err
+}
+
+func hashResult(err error) string {
+	switch {
+	case err == nil:
+		return ResultOK
+	case errors.Is(err, password.ErrHashingOverloaded):
+		return ResultOverloaded
+	}
+
+	return ResultError
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const (
+	Path      = "/metrics"
+	namespace = "usercrud"
+
+	ResultOK         = "ok"
+	ResultError      = "error"
+	ResultFailure    = "failure"
+	ResultOverloaded = "overloaded"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	RPCRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "RPCs completed by the server, by method and status code.",
+	}, []string{"method", "code"})
+
+	RPCDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Time spent handling RPCs, by method.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16),
+	}, []string{"method"})
+
+	RPCInFlight = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_in_flight",
+		Help:      "RPCs currently being handled, by method.",
+	}, []string{"method"})
+
+	RPCPanics = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "panics_total",
+		Help:      "Panics recovered while handling RPCs, by method.",
+	}, []string{"method"})
+
+	RPCRateLimited = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "rate_limited_total",
+		Help:      "RPCs rejected by the rate limiter, by method and caller class.",
+	}, []string{"method", "class"})
+
+	RPCShed = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "shed_total",
+		Help:      "RPCs rejected by the concurrency limiter, by method and priority.",
+	}, []string{"method", "priority"})
+
+	ConcurrencyLimit = promauto.With(Registry).NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "concurrency_limit",
+		Help:      "Current adaptive limit on concurrently handled RPCs.",
+	})
+
+	RepositoryDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "operation_duration_seconds",
+		Help:      "User repository operation latency, by operation and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 12),
+	}, []string{"operation", "result"})
+
+	RepositoryLockWait = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "lock_wait_seconds",
+		Help:      "Time spent waiting for the user storage lock, by lock mode.",
+		Buckets:   prometheus.ExponentialBuckets(0.000001, 4, 12),
+	}, []string{"mode"})
+
+	PasswordHashDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "password",
+		Name:      "hash_duration_seconds",
+		Help:      "Password hashing latency including queueing, by operation and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"operation", "result"})
+
+	AuthAttempts = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "attempts_total",
+		Help:      "Authentication attempts, by credential type and result.",
+	}, []string{"method", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+func RegisterUserCount(count func() (int, error)) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "users",
+		Help:      "Number of stored users.",
+	}, func() float64 {
+		n, err := count()
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	}))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"userCRUD/pkg/common/password"
+)
+
+type stubHasher struct {
+	err error
+}
+
+func (s *stubHasher) Hash(ctx context.Context, rawPassword string) (string, error) {
+	return "hash", s.err
+}
+
+func (s *stubHasher) Compare(ctx context.Context, rawPassword, hash string) (bool, error) {
+	return s.err == nil, s.err
+}
+
+func TestInstrumentHasher(t *testing.T) {
+	stub := &stubHasher{}
+	h := InstrumentHasher(stub)
+
+	h.Hash(context.Background(), "password")
+	stub.err = password.ErrHashingOverloaded
+	h.Compare(context.Background(), "password", "hash")
+	stub.err = errors.New("boom")
+	h.Compare(context.Background(), "password", "hash")
+
+	body := scrape(t)
+	for _, series := range []string{
+		`usercrud_password_hash_duration_seconds_count{operation="hash",result="ok"} 1`,
+		`usercrud_password_hash_duration_seconds_count{operation="compare",result="overloaded"} 1`,
+		`usercrud_password_hash_duration_seconds_count{operation="compare",result="error"} 1`,
+	} {
+		if !strings.Contains(body, series) {
+			t.Errorf("Expected %s in scrape output", series)
+		}
+	}
+}
+
+func TestRegisterUserCount(t *testing.T) {
+	RegisterUserCount(func() (int, error) { return 42, nil })
+
+	if body := scrape(t); !strings.Contains(body, "usercrud_repository_users 42") {
+		t.Errorf("Expected the user count gauge in scrape output")
+	}
+}
+
+func scrape(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", Path, nil))
+
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), "go_goroutines") {
+		t.Errorf("Expected runtime metrics in scrape output")
+	}
+
+	return string(body)
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrLimited = errors.New("rate limit exceeded")
+)
+
+type checkKey struct{}
+
+type Check func(ctx context.Context, class, id string) error
+
+func WithCheck(ctx context.Context, c Check) context.Context {
+	return context.WithValue(ctx, checkKey{}, c)
+}
+
+func CheckCaller(ctx context.Context, class, id string) error {
+	if c, ok := ctx.Value(checkKey{}).(Check); ok {
+		return c(ctx, class, id)
+	}
+
+	return nil
+}
+
+func TokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ClassAnonymous = "anonymous"
+	ClassUser      = "user"
+	ClassAPIKey    = "apikey"
+
+	wildcard = "*"
+)
+
+var (
+	ErrInvalidLimits = errors.New("rate limits must be a comma-separated list of method.class=count/unit[:burst]")
+)
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type Limits map[string]Limit
+
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+func ParseLimits(spec string) (Limits, error) {
+	limits := make(Limits)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		target, value, ok := strings.Cut(item, "=")
+		dot := strings.LastIndex(target, ".")
+		if !ok || dot <= 0 || dot == len(target)-1 {
+			return nil, ErrInvalidLimits
+		}
+		method, class := target[:dot], target[dot+1:]
+		if class != ClassAnonymous && class != ClassUser && class != ClassAPIKey && class != wildcard {
+			return nil, ErrInvalidLimits
+		}
+
+		limit, err := parseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[method+"."+class] = limit
+	}
+
+	return limits, nil
+}
+
+func (l Limits) For(fullMethod, class string) (Limit, bool) {
+	short := path.Base(fullMethod)
+	for _, method := range []string{fullMethod, short, wildcard} {
+		for _, c := range []string{class, wildcard} {
+			if limit, ok := l[method+"."+c]; ok {
+				return limit, true
+			}
+		}
+	}
+
+	return Limit{}, false
+}
+
+func parseLimit(value string) (Limit, error) {
+	value, burstValue, hasBurst := strings.Cut(value, ":")
+	countValue, unit, ok := strings.Cut(value, "/")
+	period, known := units[unit]
+	count, err := strconv.ParseFloat(countValue, 64)
+	if !ok || !known || err != nil || count <= 0 {
+		return Limit{}, ErrInvalidLimits
+	}
+
+	limit := Limit{
+		Rate:  count / period.Seconds(),
+		Burst: int(math.Max(1, math.Ceil(count))),
+	}
+	if hasBurst {
+		burst, err := strconv.Atoi(burstValue)
+		if err != nil || burst < 1 {
+			return Limit{}, ErrInvalidLimits
+		}
+		limit.Burst = burst
+	}
+
+	return limit, nil
+}
+
+func retryAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / limit.Rate * float64(time.Second)))
+}
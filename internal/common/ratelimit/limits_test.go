@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("*.anonymous=20/s:40, NewUser.user=10/m, /user.UserService/GetUsers.*=0.5/s")
+	if err != nil {
+		t.Fatalf("Failed to parse limits: %v", err)
+	}
+
+	expected := Limits{
+		"*.anonymous":                  {Rate: 20, Burst: 40},
+		"NewUser.user":                 {Rate: 10.0 / 60, Burst: 10},
+		"/user.UserService/GetUsers.*": {Rate: 0.5, Burst: 1},
+	}
+	if len(limits) != len(expected) {
+		t.Fatalf("Expected %d limits, got %v", len(expected), limits)
+	}
+	for key, limit := range expected {
+		if limits[key] != limit {
+			t.Errorf("Expected %s=%v, got %v", key, limit, limits[key])
+		}
+	}
+
+	for _, spec := range []string{"NewUser=1/s", "NewUser.admin=1/s", ".user=1/s", "NewUser.user=1/d", "NewUser.user=0/s", "NewUser.user=1/s:0", "NewUser.user"} {
+		if _, err := ParseLimits(spec); !errors.Is(err, ErrInvalidLimits) {
+			t.Errorf("Expected ErrInvalidLimits for %q, got %v", spec, err)
+		}
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := Limits{
+		"*.*":                              {Rate: 1, Burst: 1},
+		"*.anonymous":                      {Rate: 2, Burst: 2},
+		"NewUser.*":                        {Rate: 3, Burst: 3},
+		"/user.UserService/NewUser.apikey": {Rate: 4, Burst: 4},
+		"/grpc.health.v1.Health/Check.*":   {Rate: 5, Burst: 5},
+	}
+
+	tests := []struct {
+		method string
+		class  string
+		burst  int
+	}{
+		{"/user.UserService/NewUser", ClassAPIKey, 4},
+		{"/user.UserService/NewUser", ClassAnonymous, 3},
+		{"/user.UserService/GetUsers", ClassAnonymous, 2},
+		{"/user.UserService/GetUsers", ClassUser, 1},
+		{"/grpc.health.v1.Health/Check", ClassAnonymous, 5},
+	}
+
+	for _, tt := range tests {
+		limit, ok := limits.For(tt.method, tt.class)
+		if !ok || limit.Burst != tt.burst {
+			t.Errorf("For(%s, %s) = %v, expected burst %d", tt.method, tt.class, limit, tt.burst)
+		}
+	}
+
+	if _, ok := (Limits{"NewUser.user": {Rate: 1, Burst: 1}}).For("/user.UserService/GetUsers", ClassUser); ok {
+		t.Error("Expected no limit for an unconfigured method")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	for i := 0; i < limit.Burst; i++ {
+		if allowed, _, _ := store.Allow(ctx, "alice", limit); !allowed {
+			t.Fatalf("Expected call %d to be allowed", i+1)
+		}
+	}
+
+	allowed, retryAfter, err := store.Allow(ctx, "alice", limit)
+	if err != nil || allowed {
+		t.Fatalf("Expected the bucket to be exhausted, got allowed=%v err=%v", allowed, err)
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("Expected retry after 500ms, got %v", retryAfter)
+	}
+	if allowed, _, _ := store.Allow(ctx, "bob", limit); !allowed {
+		t.Error("Expected buckets to be independent per key")
+	}
+
+	now = now.Add(retryAfter)
+	if allowed, _, _ := store.Allow(ctx, "alice", limit); !allowed {
+		t.Error("Expected the bucket to refill over time")
+	}
+
+	now = now.Add(sweepInterval)
+	store.Allow(ctx, "carol", limit)
+	if _, ok := store.buckets["alice"]; ok {
+		t.Error("Expected full buckets to be swept")
+	}
+}
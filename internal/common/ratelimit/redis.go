@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const keyPrefix = "usercrud:ratelimit:"
+
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+type RedisStore struct {
+	client redis.Scripter
+}
+
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (r *RedisStore) Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	now := time.Now().UnixMilli()
+	result, err := takeScript.Run(ctx, r.client, []string{keyPrefix + key}, limit.Rate, limit.Burst, now).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	allowed, _ := result[0].(int64)
+	if allowed == 1 {
+		return true, 0, nil
+	}
+
+	value, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return false, retryAfter(tokens, limit), nil
+}
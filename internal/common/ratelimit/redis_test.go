@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+)
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	first, second := NewRedisStore(client), NewRedisStore(client)
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for _, store := range []*RedisStore{first, second} {
+		allowed, _, err := store.Allow(ctx, "alice", limit)
+		if err != nil || !allowed {
+			t.Fatalf("Expected the call to be allowed, got allowed=%v err=%v", allowed, err)
+		}
+	}
+
+	allowed, retryAfter, err := first.Allow(ctx, "alice", limit)
+	if err != nil || allowed {
+		t.Fatalf("Expected instances to share the bucket, got allowed=%v err=%v", allowed, err)
+	}
+	if retryAfter <= 0 {
+		t.Errorf("Expected a positive retry delay, got %v", retryAfter)
+	}
+	if ttl := server.TTL(keyPrefix + "alice"); ttl <= 0 {
+		t.Errorf("Expected the bucket to expire, got ttl %v", ttl)
+	}
+
+	if allowed, _, _ := second.Allow(ctx, "bob", limit); !allowed {
+		t.Error("Expected buckets to be independent per key")
+	}
+}
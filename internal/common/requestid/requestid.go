@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+	"regexp"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/tracing"
+)
+
+const (
+	Header      = "x-request-id"
+	TraceHeader = "x-trace-id"
+)
+
+var pattern = regexp.MustCompile(`^[A-Za-z0-9._:+=/-]{1,128}$`)
+
+func Valid(id string) bool {
+	return pattern.MatchString(id)
+}
+
+func WithRequestID(ctx context.Context, candidate string) context.Context {
+	if !Valid(candidate) {
+		candidate = uuid.New().String()
+	}
+
+	return context.WithValue(ctx, constants.RequestID, candidate)
+}
+
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(constants.RequestID).(string)
+	return id
+}
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithRequestID(r.Context(), r.Header.Get(Header))
+		w.Header().Set(Header, FromContext(ctx))
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			w.Header().Set(TraceHeader, traceID)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,52 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"3f1c9a7e-6d2b-4c55-8f0e-1a2b3c4d5e6f", true},
+		{"client.retry:2/abc+def=", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.id); got != tt.valid {
+			t.Errorf("Valid(%q) = %v, expected %v", tt.id, got, tt.valid)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(Header, "client-id-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if seen != "client-id-1" || w.Header().Get(Header) != "client-id-1" {
+		t.Errorf("Expected the client request ID to be kept, got %q in context and %q in header", seen, w.Header().Get(Header))
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(Header, "bad id")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if seen == "" || seen == "bad id" || w.Header().Get(Header) != seen {
+		t.Errorf("Expected a generated request ID, got %q in context and %q in header", seen, w.Header().Get(Header))
+	}
+}
@@ -0,0 +1,28 @@
+package tracing
+
+import (
+	"context"
+	"userCRUD/internal/common/deps"
+)
+
+type tracedHasher struct {
+	h deps.PasswordHasher
+}
+
+func TraceHasher(h deps.PasswordHasher) deps.PasswordHasher {
+	return &tracedHasher{h: h}
+}
+
+func (t *tracedHasher) Hash(ctx context.Context, rawPassword string) (hash string, err error) {
+	ctx, span := Start(ctx, "PasswordHasher.Hash")
+	defer func() { End(span, err) }()
+
+	return t.h.Hash(ctx, rawPassword)
+}
+
+func (t *tracedHasher) Compare(ctx context.Context, rawPassword, hash string) (match bool, err error) {
+	ctx, span := Start(ctx, "PasswordHasher.Compare")
+	defer func() { End(span, err) }()
+
+	return t.h.Compare(ctx, rawPassword, hash)
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"userCRUD/internal/common/constants"
+	"userCRUD/pkg/common/password"
+)
+
+type stubHasher struct {
+	err error
+}
+
+func (s *stubHasher) Hash(ctx context.Context, rawPassword string) (string, error) {
+	return "hash", s.err
+}
+
+func (s *stubHasher) Compare(ctx context.Context, rawPassword, hash string) (bool, error) {
+	return true, s.err
+}
+
+func newRecorder() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	Install(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	return exporter
+}
+
+func TestWithTraceID(t *testing.T) {
+	newRecorder()
+
+	ctx, span := Start(context.Background(), "test")
+	defer span.End()
+
+	traceID, _ := WithTraceID(ctx).Value(constants.TraceId).(string)
+	if traceID != span.SpanContext().TraceID().String() {
+		t.Errorf("Expected trace ID %s, got %s", span.SpanContext().TraceID(), traceID)
+	}
+
+	fallback, _ := WithTraceID(context.Background()).Value(constants.TraceId).(string)
+	if fallback == "" {
+		t.Error("Expected a generated trace ID without an active span")
+	}
+	if TraceID(context.Background()) != "" {
+		t.Error("Expected no trace ID without an active span")
+	}
+}
+
+func TestTraceHasher(t *testing.T) {
+	exporter := newRecorder()
+
+	ctx, parent := Start(context.Background(), "parent")
+	if _, err := TraceHasher(&stubHasher{}).Hash(ctx, "password"); err != nil {
+		t.Fatalf("Failed to hash: %v", err)
+	}
+	TraceHasher(&stubHasher{err: password.ErrHashingOverloaded}).Compare(ctx, "password", "hash")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	hash, compare := spans[0], spans[1]
+	if hash.Name != "PasswordHasher.Hash" || hash.Status.Code != codes.Unset {
+		t.Errorf("Unexpected hash span: %s %v", hash.Name, hash.Status)
+	}
+	if compare.Name != "PasswordHasher.Compare" || compare.Status.Code != codes.Error {
+		t.Errorf("Unexpected compare span: %s %v", compare.Name, compare.Status)
+	}
+	for _, span := range spans[:2] {
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the parent span", span.Name)
+		}
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	if exporter, err := NewExporter(context.Background(), ExporterNone, "", false); exporter != nil || err != nil {
+		t.Errorf("Expected no exporter, got %v, %v", exporter, err)
+	}
+	if _, err := NewExporter(context.Background(), "zipkin", "", false); !errors.Is(err, ErrUnknownExporter) {
+		t.Errorf("Expected ErrUnknownExporter, got %v", err)
+	}
+}
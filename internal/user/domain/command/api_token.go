@@ -0,0 +1,76 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var (
+	ErrAPITokenTTL = errors.New("api token ttl exceeds the allowed maximum")
+)
+
+type APIToken struct {
+	tr     persistence.TokenRepository
+	al     auditpersistence.AuditLog
+	l      deps.Logger
+	maxTTL time.Duration
+}
+
+func NewAPITokenCommand(tr persistence.TokenRepository, al auditpersistence.AuditLog, l deps.Logger, maxTTL time.Duration) *APIToken {
+	return &APIToken{
+		tr:     tr,
+		al:     al,
+		l:      l,
+		maxTTL: maxTTL,
+	}
+}
+
+func (a *APIToken) IssueAPIToken(ctx context.Context, ttl time.Duration) (*model.Token, error) {
+	user, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+
+	if _, impersonating := ctx.Value(constants.ActorContextKey).(*model.User); impersonating {
+		return nil, ErrNestedImpersonate
+	}
+
+	if !user.Admin {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	if ttl <= 0 {
+		ttl = a.maxTTL
+	}
+	if ttl > a.maxTTL {
+		return nil, ErrAPITokenTTL
+	}
+
+	value, err := newTokenValue()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &model.Token{
+		Value:     value,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := a.tr.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, a.al, ActionAPITokenIssue, user.ID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	a.l.Info(ctx, "API token issued", "userID", user.ID, "expiresAt", token.ExpiresAt)
+
+	return token, nil
+}
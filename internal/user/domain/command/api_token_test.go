@@ -0,0 +1,78 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	auditmodel "userCRUD/internal/audit/domain/model"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+func TestIssueAPIToken(t *testing.T) {
+	tr := persistence.NewTokenRepositoryMemory()
+	apiToken := NewAPITokenCommand(tr, auditLog, &deps.MockLogger{}, time.Hour)
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	regular, err := command.CreateUser(adminCtx, &model.User{
+		Username: "apiTokenUser",
+		Email:    "apiTokenUser@gmail.com",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+
+	if _, err := apiToken.IssueAPIToken(context.Background(), 0); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+
+	regularCtx := context.WithValue(context.Background(), constants.UserContextKey, regular)
+	if _, err := apiToken.IssueAPIToken(regularCtx, 0); !errors.Is(err, ErrNotEnoughPermissions) {
+		t.Errorf("Expected ErrNotEnoughPermissions, got %v", err)
+	}
+
+	impersonatedCtx := context.WithValue(adminCtx, constants.ActorContextKey, regular)
+	if _, err := apiToken.IssueAPIToken(impersonatedCtx, 0); !errors.Is(err, ErrNestedImpersonate) {
+		t.Errorf("Expected ErrNestedImpersonate, got %v", err)
+	}
+
+	if _, err := apiToken.IssueAPIToken(adminCtx, 2*time.Hour); !errors.Is(err, ErrAPITokenTTL) {
+		t.Errorf("Expected ErrAPITokenTTL, got %v", err)
+	}
+
+	token, err := apiToken.IssueAPIToken(adminCtx, 0)
+	if err != nil {
+		t.Fatalf("Failed to issue API token: %s", err)
+	}
+	if token.UserID != admin.ID || token.ExpiresAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("Unexpected token %+v", token)
+	}
+
+	stored, err := tr.GetToken(context.Background(), token.Value)
+	if err != nil || stored.UserID != admin.ID {
+		t.Errorf("Expected token to be stored, got %v %v", stored, err)
+	}
+
+	short, err := apiToken.IssueAPIToken(adminCtx, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to issue API token: %s", err)
+	}
+	if short.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("Expected requested ttl to be used, got %v", short.ExpiresAt)
+	}
+
+	events, _ := auditLog.List(context.Background(), &auditmodel.Filter{ActorID: admin.ID})
+	issued := 0
+	for _, e := range events {
+		if e.Action == ActionAPITokenIssue {
+			issued++
+		}
+	}
+	if issued != 2 {
+		t.Errorf("Expected 2 audit events, got %d", issued)
+	}
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	auditmodel "userCRUD/internal/audit/domain/model"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+const (
+	ActionUserCreate         = "user.create"
+	ActionUserUpdate         = "user.update"
+	ActionUserDelete         = "user.delete"
+	ActionPasswordChange     = "user.password_change"
+	ActionImpersonate        = "user.impersonate"
+	ActionAPITokenIssue      = "user.api_token_issue"
+	ActionOIDCClientRegister = "oidc.client_register"
+	ActionOIDCClientDelete   = "oidc.client_delete"
+	ActionIdentityLink       = "user.identity_link"
+	ActionIdentityUnlink     = "user.identity_unlink"
+	ActionFederatedSignIn    = "user.federated_sign_in"
+
+	defaultAuditLimit = 100
+)
+
+func (u *User) ListAuditEvents(ctx context.Context, filter *auditmodel.Filter) (events []*auditmodel.Event, err error) {
+	ctx, span := tracing.Start(ctx, "User.ListAuditEvents")
+	defer func() { tracing.End(span, err) }()
+
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	if err := u.validator.Struct(filter); err != nil {
+		return nil, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+
+	return u.al.List(ctx, filter)
+}
+
+func recordAudit(ctx context.Context, al auditpersistence.AuditLog, action, targetID string, before, after *model.User) error {
+	event := &auditmodel.Event{
+		Timestamp: time.Now(),
+		Action:    action,
+		TargetID:  targetID,
+		Changes:   auditChanges(before, after),
+	}
+
+	if actor, ok := ctx.Value(constants.UserContextKey).(*model.User); ok {
+		event.ActorID = actor.ID
+	}
+	if impersonator, ok := ctx.Value(constants.ActorContextKey).(*model.User); ok {
+		event.ImpersonatorID = impersonator.ID
+	}
+	event.TraceID, _ = ctx.Value(constants.TraceId).(string)
+	event.PeerAddr, _ = ctx.Value(constants.PeerAddr).(string)
+
+	_, err := al.Append(ctx, event)
+	return err
+}
+
+func auditMutation(ctx context.Context, al auditpersistence.AuditLog, action string) persistence.AuditFunc {
+	return func(before, after *model.User) error {
+		target := after
+		if target == nil {
+			target = before
+		}
+
+		return recordAudit(ctx, al, action, target.ID, before, after)
+	}
+}
+
+func auditChanges(before, after *model.User) []auditmodel.Change {
+	changes := auditmodel.Diff(auditFields(before), auditFields(after))
+	for i := range changes {
+		if changes[i].Field != "password" {
+			continue
+		}
+		if changes[i].Before != "" {
+			changes[i].Before = auditmodel.Redacted
+		}
+		if changes[i].After != "" {
+			changes[i].After = auditmodel.Redacted
+		}
+	}
+
+	return changes
+}
+
+func auditFields(user *model.User) map[string]string {
+	if user == nil {
+		return nil
+	}
+
+	permissions := make([]string, len(user.Permissions))
+	for i, p := range user.Permissions {
+		permissions[i] = string(p)
+	}
+
+	return map[string]string{
+		"email":                user.Email,
+		"username":             user.Username,
+		"password":             user.Password,
+		"admin":                strconv.FormatBool(user.Admin),
+		"permissions":          strings.Join(permissions, ","),
+		"must_change_password": strconv.FormatBool(user.MustChangePassword),
+	}
+}
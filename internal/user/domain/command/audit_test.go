@@ -0,0 +1,85 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	auditmodel "userCRUD/internal/audit/domain/model"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/user/domain/model"
+)
+
+func TestAuditTrail(t *testing.T) {
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+	adminCtx = context.WithValue(adminCtx, constants.TraceId, "trace-1")
+	adminCtx = context.WithValue(adminCtx, constants.PeerAddr, "127.0.0.1:4242")
+
+	user, err := command.CreateUser(adminCtx, &model.User{
+		Username: "auditedUser",
+		Email:    "auditedUser@gmail.com",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+
+	_, err = command.UpdateUser(adminCtx, &model.UpdateUser{
+		ID:       user.ID,
+		Email:    "audited@gmail.com",
+		Username: user.Username,
+		Password: "password2",
+		Admin:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update user: %s", err)
+	}
+
+	if err := command.DeleteUser(adminCtx, &model.UserByID{ID: user.ID}); err != nil {
+		t.Fatalf("Failed to delete user: %s", err)
+	}
+
+	userCtx := context.WithValue(context.Background(), constants.UserContextKey, user)
+	if _, err := command.ListAuditEvents(userCtx, &auditmodel.Filter{}); !errors.Is(err, ErrNotEnoughPermissions) {
+		t.Errorf("Expected ErrNotEnoughPermissions, got %v", err)
+	}
+
+	events, err := command.ListAuditEvents(adminCtx, &auditmodel.Filter{TargetID: user.ID})
+	if err != nil {
+		t.Fatalf("Failed to list audit events: %s", err)
+	}
+
+	actions := []string{ActionUserCreate, ActionUserUpdate, ActionUserDelete}
+	if len(events) != len(actions) {
+		t.Fatalf("Expected %d events, got %d", len(actions), len(events))
+	}
+
+	for i, e := range events {
+		if e.Action != actions[i] || e.TraceID != "trace-1" || e.PeerAddr != "127.0.0.1:4242" {
+			t.Errorf("Unexpected event %+v", e)
+		}
+		for _, c := range e.Changes {
+			if c.Field == "password" && (c.Before != "" && c.Before != auditmodel.Redacted || c.After != "" && c.After != auditmodel.Redacted) {
+				t.Errorf("Password must be redacted, got %+v", c)
+			}
+		}
+	}
+
+	update := events[1]
+	fields := map[string]auditmodel.Change{}
+	for _, c := range update.Changes {
+		fields[c.Field] = c
+	}
+	if fields["email"].Before != "auditedUser@gmail.com" || fields["email"].After != "audited@gmail.com" {
+		t.Errorf("Expected email diff, got %+v", fields["email"])
+	}
+	if _, ok := fields["password"]; !ok {
+		t.Errorf("Expected redacted password change to be recorded")
+	}
+	if _, ok := fields["username"]; ok {
+		t.Errorf("Unchanged fields must not be recorded")
+	}
+
+	if err := auditLog.Verify(context.Background()); err != nil {
+		t.Errorf("Audit chain must verify, got %v", err)
+	}
+}
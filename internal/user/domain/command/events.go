@@ -0,0 +1,26 @@
+package command
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+)
+
+func newEvent(ctx context.Context, t event.Type, userID string, user *model.User) event.Event {
+	e := event.Event{
+		ID:         uuid.New().String(),
+		Type:       t,
+		UserID:     userID,
+		OccurredAt: time.Now(),
+	}
+	e.TraceID, _ = ctx.Value(constants.TraceId).(string)
+
+	if user != nil {
+		e.User = event.NewUserData(user)
+	}
+
+	return e
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"context"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/model"
+)
+
+func (u *User) ExportUsers(ctx context.Context, includePasswordHash bool) (users []*model.User, revision uint64, err error) {
+	ctx, span := tracing.Start(ctx, "User.ExportUsers")
+	defer func() { tracing.End(span, err) }()
+
+	if !isAdmin(ctx) {
+		return nil, 0, ErrNotEnoughPermissions
+	}
+
+	if includePasswordHash {
+		ctxUser, _ := ctx.Value(constants.UserContextKey).(*model.User)
+		if !ctxUser.HasPermission(model.PermissionExportSecrets) {
+			return nil, 0, ErrNotEnoughPermissions
+		}
+	}
+
+	return u.ur.Snapshot(ctx)
+}
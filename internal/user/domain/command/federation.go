@@ -0,0 +1,258 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("identity provider is not configured")
+	ErrFederatedUserUnknown = errors.New("no account is linked to the external identity")
+)
+
+type FederationPolicy struct {
+	LinkByEmail   bool
+	AutoProvision bool
+	SyncAdmin     bool
+}
+
+type Federation struct {
+	ir         persistence.IdentityRepository
+	ur         persistence.UserRepository
+	tr         persistence.TokenRepository
+	al         auditpersistence.AuditLog
+	validator  deps.Validator
+	uc         *User
+	l          deps.Logger
+	policies   map[string]FederationPolicy
+	sessionTTL time.Duration
+}
+
+func NewFederationCommand(
+	ir persistence.IdentityRepository,
+	ur persistence.UserRepository,
+	tr persistence.TokenRepository,
+	al auditpersistence.AuditLog,
+	v deps.Validator,
+	uc *User,
+	l deps.Logger,
+	policies map[string]FederationPolicy,
+	sessionTTL time.Duration,
+) *Federation {
+	return &Federation{
+		ir:         ir,
+		ur:         ur,
+		tr:         tr,
+		al:         al,
+		validator:  v,
+		uc:         uc,
+		l:          l,
+		policies:   policies,
+		sessionTTL: sessionTTL,
+	}
+}
+
+func (f *Federation) SignIn(ctx context.Context, login *model.FederatedLogin) (*model.User, *model.Token, error) {
+	policy, ok := f.policies[login.Provider]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+
+	if err := f.validator.Struct(login); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := f.resolve(ctx, login, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = context.WithValue(ctx, constants.UserContextKey, user)
+
+	if policy.SyncAdmin && user.Admin != login.Admin {
+		if user, err = f.syncAdmin(ctx, user, login); err != nil {
+			return nil, nil, err
+		}
+		ctx = context.WithValue(ctx, constants.UserContextKey, user)
+	}
+
+	value, err := newTokenValue()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := &model.Token{
+		Value:     value,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(f.sessionTTL),
+	}
+	if err := f.tr.CreateToken(ctx, token); err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordAudit(ctx, f.al, ActionFederatedSignIn, user.ID, nil, nil); err != nil {
+		return nil, nil, err
+	}
+
+	f.l.Info(ctx, "Federated sign-in", "provider", login.Provider, "userID", user.ID)
+
+	return user, token, nil
+}
+
+func (f *Federation) LinkIdentity(ctx context.Context, identity *model.ExternalIdentity) (*model.ExternalIdentity, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	if err := f.validator.Struct(identity); err != nil {
+		return nil, err
+	}
+	if _, ok := f.policies[identity.Provider]; !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	if _, err := f.ur.GetUserByID(ctx, identity.UserID); err != nil {
+		return nil, err
+	}
+
+	return f.link(ctx, identity)
+}
+
+func (f *Federation) UnlinkIdentity(ctx context.Context, ref *model.ExternalIdentityRef) error {
+	if !isAdmin(ctx) {
+		return ErrNotEnoughPermissions
+	}
+
+	if err := f.validator.Struct(ref); err != nil {
+		return err
+	}
+
+	identity, err := f.ir.GetIdentity(ctx, ref.Provider, ref.Subject)
+	if err != nil {
+		return err
+	}
+
+	if err := f.ir.UnlinkIdentity(ctx, ref.Provider, ref.Subject); err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, f.al, ActionIdentityUnlink, identity.UserID, nil, nil)
+}
+
+func (f *Federation) ListIdentities(ctx context.Context, userID *model.UserByID) ([]*model.ExternalIdentity, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	if err := f.validator.Struct(userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.ur.GetUserByID(ctx, userID.ID); err != nil {
+		return nil, err
+	}
+
+	return f.ir.ListIdentities(ctx, userID.ID)
+}
+
+func (f *Federation) resolve(ctx context.Context, login *model.FederatedLogin, policy FederationPolicy) (*model.User, error) {
+	identity, err := f.ir.GetIdentity(ctx, login.Provider, login.Subject)
+	switch {
+	case err == nil:
+		user, err := f.ur.GetUserByID(ctx, identity.UserID)
+		if !errors.Is(err, persistence.ErrUserNotFound) {
+			return user, err
+		}
+		if err := f.ir.UnlinkIdentity(ctx, login.Provider, login.Subject); err != nil && !errors.Is(err, persistence.ErrIdentityNotFound) {
+			return nil, err
+		}
+	case !errors.Is(err, persistence.ErrIdentityNotFound):
+		return nil, err
+	}
+
+	var user *model.User
+	if policy.LinkByEmail && login.EmailVerified && login.Email != "" {
+		user, err = f.ur.GetUserByEmail(ctx, login.Email)
+		if err != nil && !errors.Is(err, persistence.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		if !policy.AutoProvision {
+			return nil, ErrFederatedUserUnknown
+		}
+		if user, err = f.provision(ctx, login); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := f.link(context.WithValue(ctx, constants.UserContextKey, user), &model.ExternalIdentity{
+		Provider: login.Provider,
+		Subject:  login.Subject,
+		UserID:   user.ID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (f *Federation) provision(ctx context.Context, login *model.FederatedLogin) (*model.User, error) {
+	rawPassword, err := newTokenValue()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := f.uc.create(ctx, &model.User{
+		Email:    login.Email,
+		Username: login.Username,
+		Password: rawPassword,
+		Admin:    login.Admin,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	f.l.Info(ctx, "User provisioned from identity provider", "provider", login.Provider, "userID", user.ID)
+
+	return user, nil
+}
+
+func (f *Federation) syncAdmin(ctx context.Context, user *model.User, login *model.FederatedLogin) (*model.User, error) {
+	updated, err := f.uc.update(ctx, &model.UpdateUser{
+		ID:                 user.ID,
+		Email:              user.Email,
+		Username:           user.Username,
+		Admin:              login.Admin,
+		Permissions:        user.Permissions,
+		MustChangePassword: user.MustChangePassword,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f.l.Info(ctx, "Admin flag synchronized from identity provider", "provider", login.Provider, "userID", user.ID, "admin", login.Admin)
+
+	return updated, nil
+}
+
+func (f *Federation) link(ctx context.Context, identity *model.ExternalIdentity) (*model.ExternalIdentity, error) {
+	identity.LinkedAt = time.Now()
+
+	identity, err := f.ir.LinkIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, f.al, ActionIdentityLink, identity.UserID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
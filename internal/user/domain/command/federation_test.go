@@ -0,0 +1,161 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+func newFederationCommand(tr persistence.TokenRepository) *Federation {
+	return NewFederationCommand(
+		persistence.NewIdentityRepositoryMemory(),
+		ur,
+		tr,
+		auditLog,
+		deps.NewGoPlaygroundValidator(),
+		command,
+		&deps.MockLogger{},
+		map[string]FederationPolicy{
+			"corp":    {LinkByEmail: true, AutoProvision: true},
+			"partner": {},
+			"staff":   {AutoProvision: true, SyncAdmin: true},
+		},
+		time.Hour,
+	)
+}
+
+func TestFederatedSignIn(t *testing.T) {
+	tr := persistence.NewTokenRepositoryMemory()
+	federation := newFederationCommand(tr)
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	if _, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{Provider: "unknown", Subject: "s"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Expected ErrUnknownProvider, got %v", err)
+	}
+	if _, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{Provider: "partner", Subject: "s"}); !errors.Is(err, ErrFederatedUserUnknown) {
+		t.Errorf("Expected ErrFederatedUserUnknown, got %v", err)
+	}
+
+	provisioned, token, err := federation.SignIn(context.Background(), &model.FederatedLogin{
+		Provider: "corp",
+		Subject:  "sub-1",
+		Username: "jitUser",
+		Email:    "jit@corp.example",
+		Admin:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign in: %s", err)
+	}
+	if provisioned.Username != "jitUser" || !provisioned.Admin || !provisioned.PasswordChangedAt.IsZero() {
+		t.Errorf("Unexpected provisioned user: %+v", provisioned)
+	}
+	if stored, err := tr.GetToken(context.Background(), token.Value); err != nil || stored.UserID != provisioned.ID {
+		t.Errorf("Expected a session token for the provisioned user, got %+v %v", stored, err)
+	}
+
+	again, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{Provider: "corp", Subject: "sub-1", Username: "renamed"})
+	if err != nil || again.ID != provisioned.ID || !again.Admin {
+		t.Errorf("Expected the linked account to keep its admin flag without admin sync, got %+v %v", again, err)
+	}
+
+	local, err := command.CreateUser(adminCtx, &model.User{Username: "localFed", Email: "local@corp.example", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+	if _, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{
+		Provider: "corp", Subject: "sub-2", Username: "localFed2", Email: "local@corp.example",
+	}); !errors.Is(err, persistence.ErrEmailTaken) {
+		t.Errorf("Expected unverified email not to be linked, got %v", err)
+	}
+	linked, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{
+		Provider: "corp", Subject: "sub-2", Email: "local@corp.example", EmailVerified: true,
+	})
+	if err != nil || linked.ID != local.ID {
+		t.Errorf("Expected verified email to link the local account, got %+v %v", linked, err)
+	}
+
+	if err := command.DeleteUser(adminCtx, &model.UserByID{ID: provisioned.ID}); err != nil {
+		t.Fatalf("Failed to delete user: %s", err)
+	}
+	reprovisioned, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{
+		Provider: "corp", Subject: "sub-1", Username: "jitUser", Email: "jit@corp.example",
+	})
+	if err != nil || reprovisioned.ID == provisioned.ID {
+		t.Errorf("Expected a stale link to be replaced by a new account, got %+v %v", reprovisioned, err)
+	}
+}
+
+func TestFederatedSignInSyncsAdmin(t *testing.T) {
+	federation := newFederationCommand(persistence.NewTokenRepositoryMemory())
+	login := &model.FederatedLogin{Provider: "staff", Subject: "staff-1", Username: "staffUser", Email: "staff@corp.example", Admin: true}
+
+	provisioned, _, err := federation.SignIn(context.Background(), login)
+	if err != nil || !provisioned.Admin {
+		t.Fatalf("Expected an admin to be provisioned, got %+v %v", provisioned, err)
+	}
+
+	login.Admin = false
+	demoted, _, err := federation.SignIn(context.Background(), login)
+	if err != nil || demoted.ID != provisioned.ID || demoted.Admin {
+		t.Errorf("Expected the admin flag to follow the claims on sign-in, got %+v %v", demoted, err)
+	}
+	if stored, err := ur.GetUserByID(context.Background(), provisioned.ID); err != nil || stored.Admin {
+		t.Errorf("Expected the stored user to be demoted, got %+v %v", stored, err)
+	}
+
+	login.Admin = true
+	if promoted, _, err := federation.SignIn(context.Background(), login); err != nil || !promoted.Admin {
+		t.Errorf("Expected the admin flag to be restored from the claims, got %+v %v", promoted, err)
+	}
+}
+
+func TestLinkExternalIdentity(t *testing.T) {
+	federation := newFederationCommand(persistence.NewTokenRepositoryMemory())
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	user, err := command.CreateUser(adminCtx, &model.User{Username: "partnerUser", Email: "partnerUser@gmail.com", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+	userCtx := context.WithValue(context.Background(), constants.UserContextKey, user)
+
+	identity := &model.ExternalIdentity{Provider: "partner", Subject: "p-1", UserID: user.ID}
+	if _, err := federation.LinkIdentity(userCtx, identity); !errors.Is(err, ErrNotEnoughPermissions) {
+		t.Errorf("Expected ErrNotEnoughPermissions, got %v", err)
+	}
+	if _, err := federation.LinkIdentity(adminCtx, &model.ExternalIdentity{Provider: "other", Subject: "p-1", UserID: user.ID}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Expected ErrUnknownProvider, got %v", err)
+	}
+	if _, err := federation.LinkIdentity(adminCtx, identity); err != nil {
+		t.Fatalf("Failed to link identity: %s", err)
+	}
+	if _, err := federation.LinkIdentity(adminCtx, &model.ExternalIdentity{Provider: "partner", Subject: "p-1", UserID: user.ID}); !errors.Is(err, persistence.ErrIdentityAlreadyUsed) {
+		t.Errorf("Expected ErrIdentityAlreadyUsed, got %v", err)
+	}
+
+	signedIn, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{Provider: "partner", Subject: "p-1"})
+	if err != nil || signedIn.ID != user.ID {
+		t.Errorf("Expected sign-in through the linked identity, got %+v %v", signedIn, err)
+	}
+
+	identities, err := federation.ListIdentities(adminCtx, &model.UserByID{ID: user.ID})
+	if err != nil || len(identities) != 1 || identities[0].Subject != "p-1" {
+		t.Errorf("Expected one identity, got %v %v", identities, err)
+	}
+
+	ref := &model.ExternalIdentityRef{Provider: "partner", Subject: "p-1"}
+	if err := federation.UnlinkIdentity(adminCtx, ref); err != nil {
+		t.Fatalf("Failed to unlink identity: %s", err)
+	}
+	if err := federation.UnlinkIdentity(adminCtx, ref); !errors.Is(err, persistence.ErrIdentityNotFound) {
+		t.Errorf("Expected ErrIdentityNotFound, got %v", err)
+	}
+	if _, _, err := federation.SignIn(context.Background(), &model.FederatedLogin{Provider: "partner", Subject: "p-1"}); !errors.Is(err, ErrFederatedUserUnknown) {
+		t.Errorf("Expected ErrFederatedUserUnknown after unlink, got %v", err)
+	}
+}
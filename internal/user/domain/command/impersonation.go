@@ -0,0 +1,106 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var (
+	ErrImpersonateAdmin  = errors.New("administrators cannot be impersonated")
+	ErrNestedImpersonate = errors.New("impersonation cannot be started from an impersonated session")
+)
+
+type Impersonation struct {
+	ur        persistence.UserRepository
+	tr        persistence.TokenRepository
+	al        auditpersistence.AuditLog
+	validator deps.Validator
+	l         deps.Logger
+	ttl       time.Duration
+}
+
+func NewImpersonationCommand(
+	ur persistence.UserRepository,
+	tr persistence.TokenRepository,
+	al auditpersistence.AuditLog,
+	v deps.Validator,
+	l deps.Logger,
+	ttl time.Duration,
+) *Impersonation {
+	return &Impersonation{
+		ur:        ur,
+		tr:        tr,
+		al:        al,
+		validator: v,
+		l:         l,
+		ttl:       ttl,
+	}
+}
+
+func (i *Impersonation) Impersonate(ctx context.Context, target *model.UserByID) (*model.Token, *model.User, error) {
+	actor, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		return nil, nil, ErrAuthFailed
+	}
+
+	if _, impersonating := ctx.Value(constants.ActorContextKey).(*model.User); impersonating {
+		return nil, nil, ErrNestedImpersonate
+	}
+
+	if !actor.HasPermission(model.PermissionImpersonate) {
+		return nil, nil, ErrNotEnoughPermissions
+	}
+
+	if err := i.validator.Struct(target); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := i.ur.GetUserByID(ctx, target.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.Admin {
+		return nil, nil, ErrImpersonateAdmin
+	}
+
+	value, err := newTokenValue()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := &model.Token{
+		Value:     value,
+		UserID:    user.ID,
+		ActorID:   actor.ID,
+		ExpiresAt: time.Now().Add(i.ttl),
+	}
+	if err := i.tr.CreateToken(ctx, token); err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordAudit(ctx, i.al, ActionImpersonate, user.ID, nil, nil); err != nil {
+		return nil, nil, err
+	}
+
+	i.l.Info(ctx, "Impersonation started", "actorID", actor.ID, "targetID", user.ID, "expiresAt", token.ExpiresAt)
+
+	return token, user, nil
+}
+
+func newTokenValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
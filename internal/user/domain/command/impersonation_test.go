@@ -0,0 +1,73 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+func TestImpersonate(t *testing.T) {
+	tr := persistence.NewTokenRepositoryMemory()
+	impersonation := NewImpersonationCommand(ur, tr, auditLog, deps.NewGoPlaygroundValidator(), &deps.MockLogger{}, time.Minute)
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	support, err := command.CreateUser(adminCtx, &model.User{
+		Username:    "supportUser",
+		Email:       "supportUser@gmail.com",
+		Password:    "password",
+		Permissions: []model.Permission{model.PermissionImpersonate},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create support user: %s", err)
+	}
+	customer, err := command.CreateUser(adminCtx, &model.User{
+		Username: "customerUser",
+		Email:    "customerUser@gmail.com",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create customer: %s", err)
+	}
+	otherAdmin, err := command.CreateUser(adminCtx, &model.User{
+		Username: "otherAdmin",
+		Email:    "otherAdmin@gmail.com",
+		Password: "password",
+		Admin:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create admin: %s", err)
+	}
+
+	customerCtx := context.WithValue(context.Background(), constants.UserContextKey, customer)
+	if _, _, err := impersonation.Impersonate(customerCtx, &model.UserByID{ID: support.ID}); !errors.Is(err, ErrNotEnoughPermissions) {
+		t.Errorf("Expected ErrNotEnoughPermissions, got %v", err)
+	}
+
+	supportCtx := context.WithValue(context.Background(), constants.UserContextKey, support)
+	if _, _, err := impersonation.Impersonate(supportCtx, &model.UserByID{ID: otherAdmin.ID}); !errors.Is(err, ErrImpersonateAdmin) {
+		t.Errorf("Expected ErrImpersonateAdmin, got %v", err)
+	}
+
+	token, user, err := impersonation.Impersonate(supportCtx, &model.UserByID{ID: customer.ID})
+	if err != nil {
+		t.Fatalf("Failed to impersonate: %s", err)
+	}
+	if user.ID != customer.ID || token.UserID != customer.ID || token.ActorID != support.ID {
+		t.Errorf("Token must carry target %s and actor %s, got %+v", customer.ID, support.ID, token)
+	}
+
+	stored, err := tr.GetToken(context.Background(), token.Value)
+	if err != nil || stored.ActorID != support.ID {
+		t.Errorf("Expected token to be retrievable, got %+v, %v", stored, err)
+	}
+
+	nestedCtx := context.WithValue(customerCtx, constants.ActorContextKey, support)
+	if _, _, err := impersonation.Impersonate(nestedCtx, &model.UserByID{ID: customer.ID}); !errors.Is(err, ErrNestedImpersonate) {
+		t.Errorf("Expected ErrNestedImpersonate, got %v", err)
+	}
+}
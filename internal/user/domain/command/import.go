@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+const (
+	MaxImportUsers    = 10000
+	importHashWorkers = 4
+)
+
+var (
+	ErrImportTooLarge = errors.New("import exceeds the maximum number of users")
+)
+
+func (u *User) AuthorizeImport(ctx context.Context) error {
+	if !isAdmin(ctx) {
+		return ErrNotEnoughPermissions
+	}
+
+	return nil
+}
+
+func (u *User) ImportUsers(ctx context.Context, users []*model.User, allOrNothing bool, offset int) (imported []*model.ImportResult, err error) {
+	ctx, span := tracing.Start(ctx, "User.ImportUsers")
+	defer func() { tracing.End(span, err) }()
+
+	if err := u.AuthorizeImport(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]*model.ImportResult, len(users))
+	valid := make([]int, 0, len(users))
+	for i, user := range users {
+		results[i] = &model.ImportResult{Index: offset + i}
+		user.ID = uuid.New().String()
+
+		if err := u.validator.Struct(user); err != nil {
+			results[i].Status = model.ImportInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+
+		valid = append(valid, i)
+	}
+
+	if allOrNothing && len(valid) != len(users) {
+		return abortImport(results, valid), nil
+	}
+
+	hashErrs := u.hashImportedPasswords(ctx, users, valid)
+
+	hashed := valid[:0]
+	for _, i := range valid {
+		if hashErrs[i] != nil {
+			results[i].Status = model.ImportFailed
+			results[i].Error = hashErrs[i].Error()
+			continue
+		}
+		hashed = append(hashed, i)
+	}
+
+	if allOrNothing && len(hashed) != len(users) {
+		return abortImport(results, hashed), nil
+	}
+
+	if allOrNothing {
+		return u.importAll(ctx, users, results)
+	}
+
+	audit := auditMutation(ctx, u.al, ActionUserCreate)
+	for _, i := range hashed {
+		user := users[i]
+		if _, err := u.ur.CreateUser(ctx, user, audit, newEvent(ctx, event.UserCreated, user.ID, user)); err != nil {
+			results[i].Status, results[i].Error = importStatus(err), err.Error()
+			continue
+		}
+		results[i].Status, results[i].ID = model.ImportCreated, user.ID
+	}
+
+	return results, nil
+}
+
+func (u *User) importAll(ctx context.Context, users []*model.User, results []*model.ImportResult) ([]*model.ImportResult, error) {
+	events := make([]event.Event, len(users))
+	for i, user := range users {
+		events[i] = newEvent(ctx, event.UserCreated, user.ID, user)
+	}
+
+	errs, err := u.ur.CreateUsers(ctx, users, auditMutation(ctx, u.al, ActionUserCreate), events...)
+	if errors.Is(err, persistence.ErrBatchRejected) {
+		for i, err := range errs {
+			if err != nil {
+				results[i].Status, results[i].Error = importStatus(err), err.Error()
+			} else {
+				results[i].Status, results[i].Error = model.ImportAborted, persistence.ErrBatchRejected.Error()
+			}
+		}
+
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		results[i].Status, results[i].ID = model.ImportCreated, user.ID
+	}
+
+	return results, nil
+}
+
+func (u *User) hashImportedPasswords(ctx context.Context, users []*model.User, indexes []int) []error {
+	errs := make([]error, len(users))
+	jobs := make(chan int)
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < importHashWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				hashedPass, err := u.hasher.Hash(ctx, users[i].Password)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				users[i].Password = hashedPass
+				users[i].PasswordChangedAt = now
+			}
+		}()
+	}
+
+	for _, i := range indexes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errs
+}
+
+func abortImport(results []*model.ImportResult, pending []int) []*model.ImportResult {
+	for _, i := range pending {
+		results[i].Status = model.ImportAborted
+		results[i].Error = persistence.ErrBatchRejected.Error()
+	}
+
+	return results
+}
+
+func importStatus(err error) model.ImportStatus {
+	switch {
+	case errors.Is(err, persistence.ErrUsernameTaken):
+		return model.ImportDuplicateUsername
+	case errors.Is(err, persistence.ErrEmailTaken):
+		return model.ImportDuplicateEmail
+	default:
+		return model.ImportFailed
+	}
+}
@@ -0,0 +1,188 @@
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	"net/url"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var (
+	ErrInvalidClient      = errors.New("invalid oidc client credentials")
+	ErrInvalidRedirectURI = errors.New("redirect uri must be an absolute url without a fragment")
+	ErrInvalidGrant       = errors.New("authorization code is invalid, expired or was issued to another client")
+)
+
+type OIDC struct {
+	or        persistence.OIDCRepository
+	ur        persistence.UserRepository
+	al        auditpersistence.AuditLog
+	validator deps.Validator
+	codeTTL   time.Duration
+}
+
+func NewOIDCCommand(or persistence.OIDCRepository, ur persistence.UserRepository, al auditpersistence.AuditLog, v deps.Validator, codeTTL time.Duration) *OIDC {
+	return &OIDC{
+		or:        or,
+		ur:        ur,
+		al:        al,
+		validator: v,
+		codeTTL:   codeTTL,
+	}
+}
+
+func (o *OIDC) RegisterClient(ctx context.Context, client *model.OIDCClient) (*model.OIDCClient, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	if err := o.validator.Struct(client); err != nil {
+		return nil, err
+	}
+	for _, uri := range client.RedirectURIs {
+		if u, err := url.Parse(uri); err != nil || !u.IsAbs() || u.Fragment != "" {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+
+	client.ID = uuid.New().String()
+	client.CreatedAt = time.Now()
+	client.Secret = ""
+	client.SecretHash = ""
+
+	if !client.Public {
+		secret, err := newTokenValue()
+		if err != nil {
+			return nil, err
+		}
+		client.Secret = secret
+		client.SecretHash = hashClientSecret(secret)
+	}
+
+	stored := *client
+	stored.Secret = ""
+	if _, err := o.or.CreateClient(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, o.al, ActionOIDCClientRegister, client.ID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (o *OIDC) ListClients(ctx context.Context) ([]*model.OIDCClient, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	return o.or.ListClients(ctx)
+}
+
+func (o *OIDC) DeleteClient(ctx context.Context, clientID *model.OIDCClientByID) error {
+	if !isAdmin(ctx) {
+		return ErrNotEnoughPermissions
+	}
+
+	if err := o.validator.Struct(clientID); err != nil {
+		return err
+	}
+
+	if err := o.or.DeleteClient(ctx, clientID.ID); err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, o.al, ActionOIDCClientDelete, clientID.ID, nil, nil)
+}
+
+func (o *OIDC) GetClient(ctx context.Context, id string) (*model.OIDCClient, error) {
+	client, err := o.or.GetClient(ctx, id)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func (o *OIDC) AuthenticateClient(ctx context.Context, id, secret string) (*model.OIDCClient, error) {
+	client, err := o.GetClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public {
+		if secret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashClientSecret(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func (o *OIDC) IssueCode(ctx context.Context, code *model.AuthorizationCode) (*model.AuthorizationCode, error) {
+	user, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+
+	value, err := newTokenValue()
+	if err != nil {
+		return nil, err
+	}
+
+	code.Code = value
+	code.UserID = user.ID
+	code.AuthTime = time.Now()
+	code.ExpiresAt = code.AuthTime.Add(o.codeTTL)
+
+	if err := o.or.SaveCode(ctx, code); err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
+
+func (o *OIDC) RedeemCode(ctx context.Context, client *model.OIDCClient, code, redirectURI, codeVerifier string) (*model.AuthorizationCode, *model.User, error) {
+	authCode, err := o.or.TakeCode(ctx, code)
+	if err != nil {
+		return nil, nil, ErrInvalidGrant
+	}
+
+	if authCode.ClientID != client.ID || authCode.RedirectURI != redirectURI || time.Now().After(authCode.ExpiresAt) {
+		return nil, nil, ErrInvalidGrant
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != authCode.CodeChallenge {
+		return nil, nil, ErrInvalidGrant
+	}
+
+	user, err := o.ur.GetUserByID(ctx, authCode.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidGrant
+	}
+
+	return authCode, user, nil
+}
+
+func hashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
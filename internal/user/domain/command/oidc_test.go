@@ -0,0 +1,160 @@
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestRegisterOIDCClient(t *testing.T) {
+	or := persistence.NewOIDCRepositoryMemory()
+	oidc := NewOIDCCommand(or, ur, auditLog, deps.NewGoPlaygroundValidator(), time.Minute)
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	regular, err := command.CreateUser(adminCtx, &model.User{Username: "oidcUser", Email: "oidcUser@gmail.com", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+	regularCtx := context.WithValue(context.Background(), constants.UserContextKey, regular)
+
+	if _, err := oidc.RegisterClient(regularCtx, &model.OIDCClient{Name: "app", RedirectURIs: []string{"https://app.example.com/cb"}}); !errors.Is(err, ErrNotEnoughPermissions) {
+		t.Errorf("Expected ErrNotEnoughPermissions, got %v", err)
+	}
+	if _, err := oidc.RegisterClient(adminCtx, &model.OIDCClient{Name: "app", RedirectURIs: []string{"https://app.example.com/cb#frag"}}); !errors.Is(err, ErrInvalidRedirectURI) {
+		t.Errorf("Expected ErrInvalidRedirectURI, got %v", err)
+	}
+	if _, err := oidc.RegisterClient(adminCtx, &model.OIDCClient{Name: "app"}); err == nil {
+		t.Error("Expected validation error without redirect uris")
+	}
+
+	confidential, err := oidc.RegisterClient(adminCtx, &model.OIDCClient{Name: "app", RedirectURIs: []string{"https://app.example.com/cb"}})
+	if err != nil {
+		t.Fatalf("Failed to register client: %s", err)
+	}
+	if confidential.Secret == "" {
+		t.Fatal("Expected a client secret")
+	}
+
+	public, err := oidc.RegisterClient(adminCtx, &model.OIDCClient{Name: "cli", RedirectURIs: []string{"http://127.0.0.1/cb"}, Public: true})
+	if err != nil || public.Secret != "" {
+		t.Fatalf("Expected a public client without secret, got %+v %v", public, err)
+	}
+
+	stored, _ := or.GetClient(context.Background(), confidential.ID)
+	if stored.Secret != "" || stored.SecretHash == "" {
+		t.Error("Expected only the secret hash to be stored")
+	}
+
+	if _, err := oidc.AuthenticateClient(context.Background(), confidential.ID, confidential.Secret); err != nil {
+		t.Errorf("Expected confidential client to authenticate, got %v", err)
+	}
+	if _, err := oidc.AuthenticateClient(context.Background(), confidential.ID, "wrong"); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("Expected ErrInvalidClient, got %v", err)
+	}
+	if _, err := oidc.AuthenticateClient(context.Background(), public.ID, ""); err != nil {
+		t.Errorf("Expected public client to authenticate, got %v", err)
+	}
+	if _, err := oidc.AuthenticateClient(context.Background(), public.ID, "secret"); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("Expected ErrInvalidClient for public client with secret, got %v", err)
+	}
+
+	if err := oidc.DeleteClient(adminCtx, &model.OIDCClientByID{ID: public.ID}); err != nil {
+		t.Fatalf("Failed to delete client: %s", err)
+	}
+	clients, _ := oidc.ListClients(adminCtx)
+	if len(clients) != 1 || clients[0].ID != confidential.ID {
+		t.Errorf("Expected one client left, got %v", clients)
+	}
+	if err := oidc.DeleteClient(adminCtx, &model.OIDCClientByID{ID: public.ID}); !errors.Is(err, persistence.ErrOIDCClientNotFound) {
+		t.Errorf("Expected ErrOIDCClientNotFound, got %v", err)
+	}
+}
+
+func TestRedeemAuthorizationCode(t *testing.T) {
+	oidc := NewOIDCCommand(persistence.NewOIDCRepositoryMemory(), ur, auditLog, deps.NewGoPlaygroundValidator(), time.Minute)
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	client, err := oidc.RegisterClient(adminCtx, &model.OIDCClient{Name: "app", RedirectURIs: []string{"https://app.example.com/cb"}})
+	if err != nil {
+		t.Fatalf("Failed to register client: %s", err)
+	}
+	other, err := oidc.RegisterClient(adminCtx, &model.OIDCClient{Name: "other", RedirectURIs: []string{"https://app.example.com/cb"}})
+	if err != nil {
+		t.Fatalf("Failed to register client: %s", err)
+	}
+
+	owner, err := command.CreateUser(adminCtx, &model.User{Username: "codeOwner", Email: "codeOwner@gmail.com", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+	ownerCtx := context.WithValue(context.Background(), constants.UserContextKey, owner)
+
+	if _, err := oidc.IssueCode(context.Background(), &model.AuthorizationCode{ClientID: client.ID}); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed without a user, got %v", err)
+	}
+
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	issue := func() string {
+		code, err := oidc.IssueCode(ownerCtx, &model.AuthorizationCode{
+			ClientID:      client.ID,
+			RedirectURI:   "https://app.example.com/cb",
+			Scopes:        []string{model.ScopeOpenID},
+			CodeChallenge: pkceChallenge(verifier),
+		})
+		if err != nil {
+			t.Fatalf("Failed to issue code: %s", err)
+		}
+
+		return code.Code
+	}
+
+	tests := []struct {
+		name        string
+		client      *model.OIDCClient
+		redirectURI string
+		verifier    string
+	}{
+		{"wrong client", other, "https://app.example.com/cb", verifier},
+		{"wrong redirect uri", client, "https://app.example.com/other", verifier},
+		{"wrong verifier", client, "https://app.example.com/cb", "wrong"},
+	}
+	for _, tt := range tests {
+		if _, _, err := oidc.RedeemCode(context.Background(), tt.client, issue(), tt.redirectURI, tt.verifier); !errors.Is(err, ErrInvalidGrant) {
+			t.Errorf("%s: expected ErrInvalidGrant, got %v", tt.name, err)
+		}
+	}
+
+	code := issue()
+	authCode, user, err := oidc.RedeemCode(context.Background(), client, code, "https://app.example.com/cb", verifier)
+	if err != nil {
+		t.Fatalf("Failed to redeem code: %s", err)
+	}
+	if user.ID != owner.ID || !authCode.HasScope(model.ScopeOpenID) {
+		t.Errorf("Unexpected redeemed code %+v for user %s", authCode, user.ID)
+	}
+	if _, _, err := oidc.RedeemCode(context.Background(), client, code, "https://app.example.com/cb", verifier); !errors.Is(err, ErrInvalidGrant) {
+		t.Errorf("Expected code reuse to fail, got %v", err)
+	}
+
+	expiring := NewOIDCCommand(persistence.NewOIDCRepositoryMemory(), ur, auditLog, deps.NewGoPlaygroundValidator(), -time.Second)
+	expired, err := expiring.IssueCode(ownerCtx, &model.AuthorizationCode{ClientID: client.ID, RedirectURI: "https://app.example.com/cb", CodeChallenge: pkceChallenge(verifier)})
+	if err != nil {
+		t.Fatalf("Failed to issue code: %s", err)
+	}
+	if _, _, err := expiring.RedeemCode(context.Background(), client, expired.Code, "https://app.example.com/cb", verifier); !errors.Is(err, ErrInvalidGrant) {
+		t.Errorf("Expected expired code to fail, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"time"
+	"userCRUD/internal/user/domain/model"
+)
+
+type PasswordPolicy struct {
+	HistoryDepth int
+	MaxAge       time.Duration
+}
+
+func NewPasswordPolicy(historyDepth int, maxAge time.Duration) *PasswordPolicy {
+	return &PasswordPolicy{
+		HistoryDepth: historyDepth,
+		MaxAge:       maxAge,
+	}
+}
+
+func (p *PasswordPolicy) RotationRequired(user *model.User) bool {
+	if user.MustChangePassword {
+		return true
+	}
+
+	if p.MaxAge <= 0 || user.PasswordChangedAt.IsZero() {
+		return false
+	}
+
+	return time.Since(user.PasswordChangedAt) > p.MaxAge
+}
@@ -4,81 +4,169 @@ import (
 	"context"
 	"errors"
 	"github.com/google/uuid"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
 	"userCRUD/internal/common"
 	"userCRUD/internal/common/constants"
 	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/event"
 	"userCRUD/internal/user/domain/model"
 	"userCRUD/internal/user/infrastructure/persistence"
-	"userCRUD/pkg/common/password"
 )
 
 var (
 	ErrAuthFailed           = errors.New("authentication failed")
 	ErrNotEnoughPermissions = errors.New("operation requires more privileges")
+	ErrPasswordReused       = errors.New("password was used recently, choose a different one")
+	ErrPasswordRotation     = errors.New("password must be changed before continuing")
 )
 
 type User struct {
 	ur        persistence.UserRepository
 	validator deps.Validator
+	hasher    deps.PasswordHasher
+	policy    *PasswordPolicy
+	al        auditpersistence.AuditLog
 }
 
-func NewUserCommand(ur persistence.UserRepository, v deps.Validator) *User {
+func NewUserCommand(
+	ur persistence.UserRepository,
+	v deps.Validator,
+	h deps.PasswordHasher,
+	p *PasswordPolicy,
+	al auditpersistence.AuditLog,
+) *User {
 	return &User{
 		ur:        ur,
 		validator: v,
+		hasher:    h,
+		policy:    p,
+		al:        al,
 	}
 }
 
-func (u *User) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+func (u *User) CreateUser(ctx context.Context, user *model.User) (created *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "User.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
 	if !isAdmin(ctx) {
 		return nil, ErrNotEnoughPermissions
 	}
 
+	return u.create(ctx, user, false)
+}
+
+func (u *User) create(ctx context.Context, user *model.User, passwordless bool) (*model.User, error) {
 	user.ID = uuid.New().String()
 
 	if err := u.validator.Struct(user); err != nil {
 		return nil, err
 	}
 
-	hashedPass, err := password.HashPassword(user.Password)
+	hashedPass, err := u.hasher.Hash(ctx, user.Password)
 	if err != nil {
 		return nil, err
 	}
 	user.Password = hashedPass
+	if !passwordless {
+		user.PasswordChangedAt = time.Now()
+	}
 
-	if user, err = u.ur.CreateUser(ctx, user); err != nil || user == nil {
+	audit := auditMutation(ctx, u.al, ActionUserCreate)
+	if user, err = u.ur.CreateUser(ctx, user, audit, newEvent(ctx, event.UserCreated, user.ID, user)); err != nil || user == nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (u *User) UpdateUser(ctx context.Context, userU *model.UpdateUser) (*model.User, error) {
+func (u *User) UpdateUser(ctx context.Context, userU *model.UpdateUser) (updated *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "User.UpdateUser")
+	defer func() { tracing.End(span, err) }()
+
 	if !isAdmin(ctx) {
 		return nil, ErrNotEnoughPermissions
 	}
 
+	return u.update(ctx, userU)
+}
+
+func (u *User) update(ctx context.Context, userU *model.UpdateUser) (*model.User, error) {
 	if err := u.validator.Struct(userU); err != nil {
 		return nil, err
 	}
 
 	if userU.Password != "" {
-		hashedPass, err := password.HashPassword(userU.Password)
+		if err := u.checkPasswordReuse(ctx, userU.ID, userU.Password); err != nil {
+			return nil, err
+		}
+
+		hashedPass, err := u.hasher.Hash(ctx, userU.Password)
 		if err != nil {
 			return nil, err
 		}
 		userU.Password = hashedPass
 	}
 
-	user, err := u.ur.UpdateUser(ctx, userU)
+	events := []event.Event{newEvent(ctx, event.UserUpdated, userU.ID, &model.User{
+		ID:                 userU.ID,
+		Email:              userU.Email,
+		Username:           userU.Username,
+		Admin:              userU.Admin,
+		Permissions:        userU.Permissions,
+		MustChangePassword: userU.MustChangePassword,
+	})}
+	if userU.Password != "" {
+		events = append(events, newEvent(ctx, event.PasswordChanged, userU.ID, nil))
+	}
+
+	return u.ur.UpdateUser(ctx, userU, auditMutation(ctx, u.al, ActionUserUpdate), events...)
+}
+
+func (u *User) ChangePassword(ctx context.Context, change *model.ChangePassword) (updated *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "User.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+
+	ctxUser, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+
+	if err := u.validator.Struct(change); err != nil {
+		return nil, err
+	}
+
+	user, err := u.ur.GetUserByID(ctx, ctxUser.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	match, err := u.hasher.Compare(ctx, change.OldPassword, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, ErrAuthFailed
+	}
+
+	if err := u.checkPasswordReuse(ctx, user.ID, change.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPass, err := u.hasher.Hash(ctx, change.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := auditMutation(ctx, u.al, ActionPasswordChange)
+	return u.ur.ChangePassword(ctx, user.ID, hashedPass, audit, newEvent(ctx, event.PasswordChanged, user.ID, nil))
 }
 
-func (u *User) DeleteUser(ctx context.Context, userID *model.UserByID) error {
+func (u *User) DeleteUser(ctx context.Context, userID *model.UserByID) (err error) {
+	ctx, span := tracing.Start(ctx, "User.DeleteUser")
+	defer func() { tracing.End(span, err) }()
+
 	if !isAdmin(ctx) {
 		return ErrNotEnoughPermissions
 	}
@@ -87,14 +175,14 @@ func (u *User) DeleteUser(ctx context.Context, userID *model.UserByID) error {
 		return err
 	}
 
-	if err := u.ur.DeleteUser(ctx, userID.ID); err != nil {
-		return err
-	}
-
-	return nil
+	audit := auditMutation(ctx, u.al, ActionUserDelete)
+	return u.ur.DeleteUser(ctx, userID.ID, audit, newEvent(ctx, event.UserDeleted, userID.ID, nil))
 }
 
-func (u *User) GetUserByID(ctx context.Context, userID *model.UserByID) (*model.User, error) {
+func (u *User) GetUserByID(ctx context.Context, userID *model.UserByID) (found *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "User.GetUserByID")
+	defer func() { tracing.End(span, err) }()
+
 	if err := u.validator.Struct(userID); err != nil {
 		return nil, err
 	}
@@ -107,7 +195,10 @@ func (u *User) GetUserByID(ctx context.Context, userID *model.UserByID) (*model.
 	return user, nil
 }
 
-func (u *User) GetUserByUsername(ctx context.Context, username *model.UserByUsername) (*model.User, error) {
+func (u *User) GetUserByUsername(ctx context.Context, username *model.UserByUsername) (found *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "User.GetUserByUsername")
+	defer func() { tracing.End(span, err) }()
+
 	if err := u.validator.Struct(username); err != nil {
 		return nil, err
 	}
@@ -120,7 +211,36 @@ func (u *User) GetUserByUsername(ctx context.Context, username *model.UserByUser
 	return user, nil
 }
 
-func (u *User) GetUsers(ctx context.Context, pagination *common.Pagination) ([]*model.User, error) {
+func (u *User) GetUsersByIDs(ctx context.Context, ids []string) ([]*model.User, []error) {
+	ctx, span := tracing.Start(ctx, "User.GetUsersByIDs")
+	defer span.End()
+
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = u.validator.Struct(&model.UserByID{ID: id})
+	}
+
+	users, err := u.ur.GetUsersByIDs(ctx, ids)
+	return batchResults(users, errs, err)
+}
+
+func (u *User) GetUsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, []error) {
+	ctx, span := tracing.Start(ctx, "User.GetUsersByUsernames")
+	defer span.End()
+
+	errs := make([]error, len(usernames))
+	for i, username := range usernames {
+		errs[i] = u.validator.Struct(&model.UserByUsername{Username: username})
+	}
+
+	users, err := u.ur.GetUsersByUsernames(ctx, usernames)
+	return batchResults(users, errs, err)
+}
+
+func (u *User) GetUsers(ctx context.Context, pagination *common.Pagination) (found []*model.User, err error) {
+	ctx, span := tracing.Start(ctx, "User.GetUsers")
+	defer func() { tracing.End(span, err) }()
+
 	if err := u.validator.Struct(pagination); err != nil {
 		return nil, err
 	}
@@ -133,6 +253,58 @@ func (u *User) GetUsers(ctx context.Context, pagination *common.Pagination) ([]*
 	return users, nil
 }
 
+func (u *User) checkPasswordReuse(ctx context.Context, userID, rawPassword string) error {
+	if u.policy.HistoryDepth <= 0 {
+		return nil
+	}
+
+	user, err := u.ur.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	history, err := u.ur.GetPasswordHistory(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	recent := append([]string{user.Password}, history...)
+	if len(recent) > u.policy.HistoryDepth {
+		recent = recent[:u.policy.HistoryDepth]
+	}
+
+	for _, hash := range recent {
+		match, err := u.hasher.Compare(ctx, rawPassword, hash)
+		if err != nil {
+			return err
+		}
+		if match {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+func batchResults(users []*model.User, errs []error, err error) ([]*model.User, []error) {
+	if len(users) != len(errs) {
+		users = make([]*model.User, len(errs))
+	}
+
+	for i := range errs {
+		switch {
+		case errs[i] != nil:
+			users[i] = nil
+		case err != nil:
+			errs[i] = err
+		case users[i] == nil:
+			errs[i] = persistence.ErrUserNotFound
+		}
+	}
+
+	return users, errs
+}
+
 func isAdmin(ctx context.Context) bool {
 	ctxUser, ok := ctx.Value(constants.UserContextKey).(*model.User)
 	if !ok {
@@ -4,22 +4,28 @@ import (
 	"context"
 	"errors"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
 	"testing"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
 	"userCRUD/internal/common/constants"
 	"userCRUD/internal/common/deps"
 	"userCRUD/internal/user/domain/model"
 	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
 )
 
 var (
-	ur    = persistence.NewUserRepositoryMemory(&deps.MockLogger{})
-	admin = &model.User{
+	hasher = password.NewPool(bcrypt.MinCost, 4, 16)
+	ur     = persistence.NewUserRepositoryMemory(&deps.MockLogger{}, hasher, 1000)
+	admin  = &model.User{
 		Email:    "admin@gmail.com",
 		Username: "admin",
 		Password: "admin",
 		Admin:    true,
 	}
-	command = NewUserCommand(ur, deps.NewGoPlaygroundValidator())
+	auditLog = auditpersistence.NewAuditLogMemory()
+	command  = NewUserCommand(ur, deps.NewGoPlaygroundValidator(), hasher, NewPasswordPolicy(3, 0), auditLog)
 )
 
 func TestCreateUser(t *testing.T) {
@@ -66,3 +72,103 @@ func TestCreateUser(t *testing.T) {
 		t.Errorf("Expected validation error, got different type of error")
 	}
 }
+
+func TestUpdateUserDemotesAdmin(t *testing.T) {
+	ctx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	user, err := command.CreateUser(ctx, &model.User{
+		Username: "demotedAdmin",
+		Email:    "demotedAdmin@gmail.com",
+		Password: "password",
+		Admin:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create admin: %s", err)
+	}
+
+	updated, err := command.UpdateUser(ctx, &model.UpdateUser{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Admin:    false,
+	})
+	if err != nil {
+		t.Fatalf("Expected demotion to pass validation, got %s", err)
+	}
+	if updated.Admin {
+		t.Errorf("Expected the user to lose admin rights")
+	}
+
+	found, err := ur.GetUserByID(ctx, user.ID)
+	if err != nil || found.Admin {
+		t.Errorf("Expected the stored user to be demoted, got %+v, %v", found, err)
+	}
+}
+
+func TestChangePasswordRejectsReuse(t *testing.T) {
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	user, err := command.CreateUser(adminCtx, &model.User{
+		Username: "rotatingUser",
+		Email:    "rotatingUser@gmail.com",
+		Password: "password0",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+	ctx := context.WithValue(context.Background(), constants.UserContextKey, user)
+
+	_, err = command.ChangePassword(ctx, &model.ChangePassword{OldPassword: "wrong", NewPassword: "password1"})
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+
+	_, err = command.ChangePassword(ctx, &model.ChangePassword{OldPassword: "password0", NewPassword: "password0"})
+	if !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("Expected ErrPasswordReused, got %v", err)
+	}
+
+	passwords := []string{"password0", "password1", "password2", "password3"}
+	for i := 1; i < len(passwords); i++ {
+		change := &model.ChangePassword{OldPassword: passwords[i-1], NewPassword: passwords[i]}
+		if _, err = command.ChangePassword(ctx, change); err != nil {
+			t.Fatalf("Failed to change password to %s: %s", passwords[i], err)
+		}
+	}
+
+	_, err = command.UpdateUser(adminCtx, &model.UpdateUser{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Password: "password2",
+		Admin:    true,
+	})
+	if !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("Expected ErrPasswordReused for a password within history depth, got %v", err)
+	}
+
+	changed, err := command.ChangePassword(ctx, &model.ChangePassword{OldPassword: "password3", NewPassword: "password0"})
+	if err != nil {
+		t.Errorf("Password outside history depth must be accepted, got %s", err)
+	}
+	if changed != nil && changed.MustChangePassword {
+		t.Errorf("MustChangePassword must be reset after a password change")
+	}
+}
+
+func TestPasswordPolicyRotationRequired(t *testing.T) {
+	policy := NewPasswordPolicy(5, time.Hour)
+
+	if !policy.RotationRequired(&model.User{MustChangePassword: true, PasswordChangedAt: time.Now()}) {
+		t.Errorf("Expected rotation when MustChangePassword is set")
+	}
+	if !policy.RotationRequired(&model.User{PasswordChangedAt: time.Now().Add(-2 * time.Hour)}) {
+		t.Errorf("Expected rotation for an expired password")
+	}
+	if policy.RotationRequired(&model.User{PasswordChangedAt: time.Now()}) {
+		t.Errorf("Expected no rotation for a fresh password")
+	}
+	if NewPasswordPolicy(5, 0).RotationRequired(&model.User{PasswordChangedAt: time.Now().Add(-24 * time.Hour)}) {
+		t.Errorf("Expected no rotation when max age is disabled")
+	}
+}
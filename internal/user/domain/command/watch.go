@@ -0,0 +1,78 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var (
+	ErrRevisionAhead = errors.New("revision is ahead of the current revision")
+	ErrWatchClosed   = errors.New("server is shutting down, resume the watch from the last revision")
+)
+
+const watchBatchSize = 100
+
+type Watch struct {
+	ur     persistence.UserRepository
+	outbox persistence.Outbox
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewWatchCommand(ur persistence.UserRepository, outbox persistence.Outbox) *Watch {
+	return &Watch{
+		ur:     ur,
+		outbox: outbox,
+		done:   make(chan struct{}),
+	}
+}
+
+func (w *Watch) Close() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *Watch) Snapshot(ctx context.Context) ([]*model.User, uint64, error) {
+	return w.ur.Snapshot(ctx)
+}
+
+func (w *Watch) Watch(ctx context.Context, sinceRevision uint64, fn func(record event.Record) error) error {
+	revision, err := w.outbox.Revision(ctx)
+	if err != nil {
+		return err
+	}
+	if sinceRevision > revision {
+		return ErrRevisionAhead
+	}
+
+	for {
+		changed := w.outbox.Changed()
+
+		records, err := w.outbox.Records(ctx, sinceRevision, watchBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return err
+			}
+			sinceRevision = record.Offset
+		}
+
+		if len(records) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.done:
+			return ErrWatchClosed
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var (
+	ErrWebhookDeliveryFailed = errors.New("webhook delivery failed")
+)
+
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *model.Webhook, eventID, eventType string, payload []byte) error
+}
+
+type Webhook struct {
+	wr        persistence.WebhookRepository
+	sender    WebhookSender
+	validator deps.Validator
+}
+
+func NewWebhookCommand(wr persistence.WebhookRepository, s WebhookSender, v deps.Validator) *Webhook {
+	return &Webhook{
+		wr:        wr,
+		sender:    s,
+		validator: v,
+	}
+}
+
+func (w *Webhook) RegisterWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	if err := w.validator.Struct(webhook); err != nil {
+		return nil, err
+	}
+
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = time.Now()
+
+	if webhook.Secret == "" {
+		secret, err := newTokenValue()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+
+	return w.wr.CreateWebhook(ctx, webhook)
+}
+
+func (w *Webhook) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	return w.wr.ListWebhooks(ctx)
+}
+
+func (w *Webhook) DeleteWebhook(ctx context.Context, webhookID *model.WebhookByID) error {
+	if !isAdmin(ctx) {
+		return ErrNotEnoughPermissions
+	}
+
+	if err := w.validator.Struct(webhookID); err != nil {
+		return err
+	}
+
+	return w.wr.DeleteWebhook(ctx, webhookID.ID)
+}
+
+func (w *Webhook) ListDeadLetters(ctx context.Context, webhookID string) ([]*model.DeadLetter, error) {
+	if !isAdmin(ctx) {
+		return nil, ErrNotEnoughPermissions
+	}
+
+	return w.wr.ListDeadLetters(ctx, webhookID)
+}
+
+func (w *Webhook) Redeliver(ctx context.Context, deadLetterID *model.WebhookByID) error {
+	if !isAdmin(ctx) {
+		return ErrNotEnoughPermissions
+	}
+
+	if err := w.validator.Struct(deadLetterID); err != nil {
+		return err
+	}
+
+	deadLetter, err := w.wr.GetDeadLetter(ctx, deadLetterID.ID)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := w.wr.GetWebhook(ctx, deadLetter.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	if err := w.sender.Send(ctx, webhook, deadLetter.EventID, deadLetter.EventType, deadLetter.Payload); err != nil {
+		deadLetter.Attempts++
+		deadLetter.LastError = err.Error()
+		deadLetter.FailedAt = time.Now()
+
+		if updateErr := w.wr.UpdateDeadLetter(ctx, deadLetter); updateErr != nil {
+			return updateErr
+		}
+
+		return fmt.Errorf("%w: %v", ErrWebhookDeliveryFailed, err)
+	}
+
+	return w.wr.DeleteDeadLetter(ctx, deadLetter.ID)
+}
@@ -0,0 +1,49 @@
+package event
+
+import (
+	"time"
+	"userCRUD/internal/user/domain/model"
+)
+
+type Type string
+
+const (
+	UserCreated     Type = "user.created"
+	UserUpdated     Type = "user.updated"
+	UserDeleted     Type = "user.deleted"
+	PasswordChanged Type = "user.password_changed"
+)
+
+type Event struct {
+	ID         string
+	Type       Type
+	UserID     string
+	OccurredAt time.Time
+	TraceID    string
+	User       *UserData
+}
+
+type UserData struct {
+	ID                 string
+	Email              string
+	Username           string
+	Admin              bool
+	Permissions        []model.Permission
+	MustChangePassword bool
+}
+
+type Record struct {
+	Offset uint64
+	Event  Event
+}
+
+func NewUserData(user *model.User) *UserData {
+	return &UserData{
+		ID:                 user.ID,
+		Email:              user.Email,
+		Username:           user.Username,
+		Admin:              user.Admin,
+		Permissions:        user.Permissions,
+		MustChangePassword: user.MustChangePassword,
+	}
+}
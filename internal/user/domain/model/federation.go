@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type ExternalIdentity struct {
+	Provider string `validate:"required"`
+	Subject  string `validate:"required"`
+	UserID   string `validate:"required,uuid4"`
+	LinkedAt time.Time
+}
+
+type ExternalIdentityRef struct {
+	Provider string `validate:"required"`
+	Subject  string `validate:"required"`
+}
+
+type FederatedLogin struct {
+	Provider      string `validate:"required"`
+	Subject       string `validate:"required"`
+	Username      string
+	Email         string
+	EmailVerified bool
+	Admin         bool
+}
@@ -0,0 +1,19 @@
+package model
+
+type ImportStatus int
+
+const (
+	ImportCreated ImportStatus = iota + 1
+	ImportDuplicateUsername
+	ImportDuplicateEmail
+	ImportInvalid
+	ImportAborted
+	ImportFailed
+)
+
+type ImportResult struct {
+	Index  int
+	Status ImportStatus
+	ID     string
+	Error  string
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+type OIDCClient struct {
+	ID           string
+	Name         string   `validate:"required"`
+	RedirectURIs []string `validate:"required,min=1,dive,url"`
+	Public       bool
+	Secret       string
+	SecretHash   string
+	CreatedAt    time.Time
+}
+
+type OIDCClientByID struct {
+	ID string `validate:"required,uuid4"`
+}
+
+type AuthorizationCode struct {
+	Code          string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	Nonce         string
+	CodeChallenge string
+	AuthTime      time.Time
+	ExpiresAt     time.Time
+}
+
+func (c *OIDCClient) AllowsRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *AuthorizationCode) HasScope(scope string) bool {
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
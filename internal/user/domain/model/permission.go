@@ -0,0 +1,18 @@
+package model
+
+type Permission string
+
+const (
+	PermissionImpersonate   Permission = "users.impersonate"
+	PermissionExportSecrets Permission = "users.export_secrets"
+)
+
+func (u *User) HasPermission(p Permission) bool {
+	for _, granted := range u.Permissions {
+		if granted == p {
+			return true
+		}
+	}
+
+	return false
+}
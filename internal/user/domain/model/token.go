@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type Token struct {
+	Value     string
+	UserID    string
+	ActorID   string
+	ExpiresAt time.Time
+}
+
+func (t *Token) Expired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -1,18 +1,30 @@
 package model
 
+import "time"
+
 type User struct {
-	ID       string `validate:"uuid4"`
-	Email    string `validate:"required,email"`
-	Username string `validate:"required,min=5"`
-	Password string `validate:"required,min=5"`
-	Admin    bool   `validate:"boolean"`
+	ID                 string       `validate:"uuid4"`
+	Email              string       `validate:"required,email"`
+	Username           string       `validate:"required,min=5"`
+	Password           string       `validate:"required,min=5"`
+	Admin              bool         `validate:"boolean"`
+	Permissions        []Permission `validate:"dive,oneof=users.impersonate users.export_secrets"`
+	MustChangePassword bool
+	PasswordChangedAt  time.Time
 }
 type UpdateUser struct {
-	ID       string `validate:"required,uuid4"`
-	Email    string `validate:"required,email"`
-	Username string `validate:"required,min=5"`
-	Password string `validate:"required,min=5"`
-	Admin    bool   `validate:"required,boolean"`
+	ID                 string       `validate:"required,uuid4"`
+	Email              string       `validate:"required,email"`
+	Username           string       `validate:"required,min=5"`
+	Password           string       `validate:"omitempty,min=5"`
+	Admin              bool         `validate:"boolean"`
+	Permissions        []Permission `validate:"dive,oneof=users.impersonate users.export_secrets"`
+	MustChangePassword bool
+}
+
+type ChangePassword struct {
+	OldPassword string `validate:"required"`
+	NewPassword string `validate:"required,min=5"`
 }
 
 type UserByID struct {
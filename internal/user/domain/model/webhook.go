@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type Webhook struct {
+	ID         string
+	URL        string   `validate:"required,url,startswith=http"`
+	Secret     string   `validate:"omitempty,min=16"`
+	EventTypes []string `validate:"dive,oneof=user.created user.updated user.deleted user.password_changed"`
+	CreatedAt  time.Time
+}
+
+type DeadLetter struct {
+	ID        string
+	WebhookID string
+	EventID   string
+	EventType string
+	Payload   []byte
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+type Delivery struct {
+	ID            string
+	WebhookID     string
+	EventID       string
+	EventType     string
+	Payload       []byte
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+}
+
+type WebhookByID struct {
+	ID string `validate:"required,uuid4"`
+}
+
+func (w *Webhook) Accepts(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const (
+	BasicPrefix  = "Basic "
+	BearerPrefix = "Bearer "
+
+	methodPassword = "password"
+	methodBearer   = "bearer"
+)
+
+var (
+	ErrInvalidBasicAuthFormat = errors.New("invalid basic auth format")
+)
+
+type basicAuthCreds struct {
+	username string
+	password string
+}
+
+type Authenticator struct {
+	ur persistence.UserRepository
+	tr persistence.TokenRepository
+	pp *command.PasswordPolicy
+}
+
+func NewAuthenticator(ur persistence.UserRepository, tr persistence.TokenRepository, pp *command.PasswordPolicy) *Authenticator {
+	return &Authenticator{
+		ur: ur,
+		tr: tr,
+		pp: pp,
+	}
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, authHeader string) (context.Context, error) {
+	switch {
+	case strings.HasPrefix(authHeader, BasicPrefix):
+		creds, err := decodeBasicAuth(authHeader)
+		if err != nil {
+			recordAttempt(methodPassword, false)
+			return ctx, nil
+		}
+
+		return a.AuthenticatePassword(ctx, creds.username, creds.password)
+	case strings.HasPrefix(authHeader, BearerPrefix):
+		token := strings.TrimPrefix(authHeader, BearerPrefix)
+		identityCtx, ok := a.authenticateBearer(ctx, token)
+		recordAttempt(methodBearer, ok)
+		if !ok {
+			return ctx, nil
+		}
+		if err := ratelimit.CheckCaller(ctx, ratelimit.ClassAPIKey, ratelimit.TokenID(token)); err != nil {
+			return nil, err
+		}
+
+		return identityCtx, nil
+	}
+
+	return ctx, nil
+}
+
+func (a *Authenticator) AuthenticatePassword(ctx context.Context, username, rawPassword string) (context.Context, error) {
+	user, err := a.ur.GetUserByUsernameAndPassword(ctx, username, rawPassword)
+	loadshed.Restart(ctx)
+	if errors.Is(err, password.ErrHashingOverloaded) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		metrics.AuthAttempts.WithLabelValues(methodPassword, metrics.ResultError).Inc()
+		return nil, err
+	}
+	if err != nil || user == nil {
+		recordAttempt(methodPassword, false)
+		return ctx, nil
+	}
+
+	recordAttempt(methodPassword, true)
+	if err := ratelimit.CheckCaller(ctx, ratelimit.ClassUser, user.ID); err != nil {
+		return nil, err
+	}
+
+	return WithIdentity(ctx, user, nil), nil
+}
+
+func (a *Authenticator) authenticateBearer(ctx context.Context, value string) (context.Context, bool) {
+	token, err := a.tr.GetToken(ctx, value)
+	if err != nil {
+		return ctx, false
+	}
+
+	user, err := a.ur.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return ctx, false
+	}
+
+	if token.ActorID == "" {
+		return WithIdentity(ctx, user, nil), true
+	}
+
+	actor, err := a.ur.GetUserByID(ctx, token.ActorID)
+	if err != nil || !actor.HasPermission(model.PermissionImpersonate) || user.Admin {
+		return ctx, false
+	}
+
+	return WithIdentity(ctx, user, actor), true
+}
+
+func (a *Authenticator) CheckPasswordRotation(ctx context.Context) error {
+	user, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if ok && a.pp.RotationRequired(user) {
+		return command.ErrPasswordRotation
+	}
+
+	return nil
+}
+
+func WithIdentity(ctx context.Context, user, actor *model.User) context.Context {
+	ctx = context.WithValue(ctx, constants.UserContextKey, user)
+	ctx = context.WithValue(ctx, constants.UserID, user.ID)
+
+	if actor != nil {
+		ctx = context.WithValue(ctx, constants.ActorContextKey, actor)
+		ctx = context.WithValue(ctx, constants.ActorID, actor.ID)
+	}
+
+	return ctx
+}
+
+func recordAttempt(method string, ok bool) {
+	result := metrics.ResultFailure
+	if ok {
+		result = metrics.ResultOK
+	}
+
+	metrics.AuthAttempts.WithLabelValues(method, result).Inc()
+}
+
+func decodeBasicAuth(authHeader string) (*basicAuthCreds, error) {
+	authBase64 := strings.TrimPrefix(authHeader, BasicPrefix)
+	authBytes, err := base64.StdEncoding.DecodeString(authBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(string(authBytes), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidBasicAuthFormat
+	}
+
+	return &basicAuthCreds{
+		username: parts[0],
+		password: parts[1],
+	}, nil
+}
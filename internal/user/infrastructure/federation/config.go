@@ -0,0 +1,78 @@
+package federation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var (
+	ErrInvalidProviderConfig = errors.New("invalid identity provider configuration")
+)
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type ProviderConfig struct {
+	Name          string       `json:"name"`
+	Issuer        string       `json:"issuer"`
+	ClientID      string       `json:"client_id"`
+	ClientSecret  string       `json:"client_secret"`
+	Scopes        []string     `json:"scopes"`
+	LinkByEmail   bool         `json:"link_by_email"`
+	AutoProvision bool         `json:"auto_provision"`
+	Claims        ClaimMapping `json:"claims"`
+}
+
+type ClaimMapping struct {
+	Username      string   `json:"username"`
+	Email         string   `json:"email"`
+	EmailVerified string   `json:"email_verified"`
+	Admin         string   `json:"admin"`
+	AdminValues   []string `json:"admin_values"`
+}
+
+func LoadProviders(path string) ([]*ProviderConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var providers []*ProviderConfig
+	if err := json.Unmarshal(data, &providers); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProviderConfig, err)
+	}
+
+	names := make(map[string]struct{}, len(providers))
+	for _, p := range providers {
+		if !providerName.MatchString(p.Name) || p.Issuer == "" || p.ClientID == "" {
+			return nil, fmt.Errorf("%w: provider %q needs a lowercase name, an issuer and a client_id", ErrInvalidProviderConfig, p.Name)
+		}
+		if _, exists := names[p.Name]; exists {
+			return nil, fmt.Errorf("%w: provider %q is declared twice", ErrInvalidProviderConfig, p.Name)
+		}
+		names[p.Name] = struct{}{}
+	}
+
+	return providers, nil
+}
+
+func (p *ProviderConfig) withDefaults() {
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"profile", "email"}
+	}
+	if p.Claims.Username == "" {
+		p.Claims.Username = "preferred_username"
+	}
+	if p.Claims.Email == "" {
+		p.Claims.Email = "email"
+	}
+	if p.Claims.EmailVerified == "" {
+		p.Claims.EmailVerified = "email_verified"
+	}
+}
@@ -0,0 +1,68 @@
+package federation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "providers.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadProviders(t *testing.T) {
+	if providers, err := LoadProviders(""); err != nil || providers != nil {
+		t.Errorf("Expected no providers without a path, got %v %v", providers, err)
+	}
+
+	providers, err := LoadProviders(writeConfig(t, `[
+		{"name": "corp", "issuer": "https://idp.example.com", "client_id": "app", "auto_provision": true,
+		 "claims": {"admin": "realm_access.roles", "admin_values": ["admin"]}}
+	]`))
+	if err != nil || len(providers) != 1 {
+		t.Fatalf("Expected one provider, got %v %v", providers, err)
+	}
+	if !providers[0].AutoProvision || providers[0].Claims.AdminValues[0] != "admin" {
+		t.Errorf("Unexpected provider: %+v", providers[0])
+	}
+
+	invalid := []string{
+		`{"name": "corp"}`,
+		`[{"name": "Corp!", "issuer": "https://idp.example.com", "client_id": "app"}]`,
+		`[{"name": "corp", "client_id": "app"}]`,
+		`[{"name": "corp", "issuer": "https://a", "client_id": "app"}, {"name": "corp", "issuer": "https://b", "client_id": "app"}]`,
+	}
+	for _, content := range invalid {
+		if _, err := LoadProviders(writeConfig(t, content)); !errors.Is(err, ErrInvalidProviderConfig) {
+			t.Errorf("Expected ErrInvalidProviderConfig for %s, got %v", content, err)
+		}
+	}
+}
+
+func TestMapClaims(t *testing.T) {
+	p := NewProvider(&ProviderConfig{
+		Name:   "corp",
+		Claims: ClaimMapping{Admin: "realm_access.roles", AdminValues: []string{"admin"}},
+	}, "")
+
+	login := p.mapClaims("sub-1", map[string]interface{}{
+		"preferred_username": "jdoe",
+		"email":              "jdoe@example.com",
+		"email_verified":     "true",
+		"realm_access":       map[string]interface{}{"roles": []interface{}{"user", "admin"}},
+	})
+	if login.Provider != "corp" || login.Subject != "sub-1" || login.Username != "jdoe" ||
+		login.Email != "jdoe@example.com" || !login.EmailVerified || !login.Admin {
+		t.Errorf("Unexpected mapping: %+v", login)
+	}
+
+	if login := p.mapClaims("sub-2", map[string]interface{}{"realm_access": "admin-ish"}); login.Admin || login.EmailVerified {
+		t.Errorf("Expected no admin or verified email, got %+v", login)
+	}
+}
@@ -0,0 +1,207 @@
+package federation
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"strings"
+	"sync"
+	"time"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+)
+
+const discoveryTimeout = 10 * time.Second
+
+var (
+	ErrProviderUnavailable = errors.New("identity provider discovery failed")
+	ErrMissingIDToken      = errors.New("token response does not contain an id_token")
+	ErrNonceMismatch       = errors.New("id token nonce does not match the login request")
+)
+
+type Provider struct {
+	cfg         *ProviderConfig
+	redirectURL string
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewProvider(cfg *ProviderConfig, redirectURL string) *Provider {
+	cfg.withDefaults()
+
+	return &Provider{
+		cfg:         cfg,
+		redirectURL: redirectURL,
+	}
+}
+
+func Policies(providers []*ProviderConfig) map[string]command.FederationPolicy {
+	policies := make(map[string]command.FederationPolicy, len(providers))
+	for _, p := range providers {
+		policies[p.Name] = command.FederationPolicy{
+			LinkByEmail:   p.LinkByEmail,
+			AutoProvision: p.AutoProvision,
+			SyncAdmin:     p.Claims.Admin != "",
+		}
+	}
+
+	return policies
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	conf, _, err := p.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return conf.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*model.FederatedLogin, error) {
+	conf, provider, err := p.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := conf.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return p.mapClaims(idToken.Subject, claims), nil
+}
+
+func (p *Provider) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scopes := []string{oidc.ScopeOpenID}
+	for _, s := range p.cfg.Scopes {
+		if s != oidc.ScopeOpenID {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       scopes,
+	}, provider, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	p.provider = provider
+
+	return provider, nil
+}
+
+func (p *Provider) mapClaims(subject string, claims map[string]interface{}) *model.FederatedLogin {
+	m := p.cfg.Claims
+
+	login := &model.FederatedLogin{
+		Provider: p.cfg.Name,
+		Subject:  subject,
+	}
+	login.Username, _ = lookupClaim(claims, m.Username).(string)
+	login.Email, _ = lookupClaim(claims, m.Email).(string)
+	login.EmailVerified = isTrue(lookupClaim(claims, m.EmailVerified))
+
+	if m.Admin != "" {
+		admin := lookupClaim(claims, m.Admin)
+		if len(m.AdminValues) == 0 {
+			login.Admin = isTrue(admin)
+		} else {
+			login.Admin = containsAny(admin, m.AdminValues)
+		}
+	}
+
+	return login
+}
+
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	return value
+}
+
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+
+	return false
+}
+
+func containsAny(value interface{}, wanted []string) bool {
+	var values []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		values = v
+	case string:
+		values = []interface{}{v}
+	}
+
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"sort"
+	"sync"
+	"time"
+	"userCRUD/internal/common/deps"
+)
+
+const overall = ""
+
+type Check func(ctx context.Context) error
+
+type Monitor struct {
+	server   *health.Server
+	l        deps.Logger
+	interval time.Duration
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	checks map[string]map[string]Check
+	status map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+func NewMonitor(l deps.Logger, interval, timeout time.Duration) *Monitor {
+	m := &Monitor{
+		server:   health.NewServer(),
+		l:        l,
+		interval: interval,
+		timeout:  timeout,
+		checks:   make(map[string]map[string]Check),
+		status:   make(map[string]healthpb.HealthCheckResponse_ServingStatus),
+	}
+	m.server.SetServingStatus(overall, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return m
+}
+
+func (m *Monitor) Register(service, name string, check Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.checks[service] == nil {
+		m.checks[service] = make(map[string]Check)
+	}
+	m.checks[service][name] = check
+	m.server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func (m *Monitor) Server() healthpb.HealthServer {
+	return m.server
+}
+
+func (m *Monitor) Run(ctx context.Context) {
+	m.Check(ctx)
+	if m.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+func (m *Monitor) Check(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	services := make([]string, 0, len(m.checks))
+	for service := range m.checks {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	all := healthpb.HealthCheckResponse_SERVING
+	for _, service := range services {
+		status := m.checkService(ctx, service)
+		if status != healthpb.HealthCheckResponse_SERVING {
+			all = status
+		}
+		m.setStatus(ctx, service, status)
+	}
+	m.setStatus(ctx, overall, all)
+}
+
+func (m *Monitor) Shutdown() {
+	m.server.Shutdown()
+}
+
+func (m *Monitor) checkService(ctx context.Context, service string) healthpb.HealthCheckResponse_ServingStatus {
+	status := healthpb.HealthCheckResponse_SERVING
+	for name, check := range m.checks[service] {
+		checkCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		err := check(checkCtx)
+		cancel()
+
+		if err != nil {
+			m.l.Error(ctx, "Health check failed", "service", service, "check", name, "error", err)
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+
+	return status
+}
+
+func (m *Monitor) setStatus(ctx context.Context, service string, status healthpb.HealthCheckResponse_ServingStatus) {
+	if previous, ok := m.status[service]; ok && previous != status {
+		m.l.Info(ctx, "Health status changed", "service", service, "status", status.String())
+	}
+	m.status[service] = status
+	m.server.SetServingStatus(service, status)
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"errors"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"sync/atomic"
+	"testing"
+	"time"
+	"userCRUD/internal/common/deps"
+)
+
+func servingStatus(t *testing.T, m *Monitor, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := m.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Health check for %q failed: %v", service, err)
+	}
+
+	return resp.Status
+}
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor(&deps.MockLogger{}, time.Minute, 50*time.Millisecond)
+
+	var failing atomic.Bool
+	m.Register("user.UserService", "storage", func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("storage unreachable")
+		}
+		return nil
+	})
+	m.Register("user.Reports", "slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if status := servingStatus(t, m, ""); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING before the first check, got %s", status)
+	}
+
+	m.Check(context.Background())
+	if status := servingStatus(t, m, "user.UserService"); status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected UserService SERVING, got %s", status)
+	}
+	if status := servingStatus(t, m, "user.Reports"); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected a timed out check to be NOT_SERVING, got %s", status)
+	}
+	if status := servingStatus(t, m, ""); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected overall NOT_SERVING while a service is down, got %s", status)
+	}
+
+	failing.Store(true)
+	m.Check(context.Background())
+	if status := servingStatus(t, m, "user.UserService"); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected UserService NOT_SERVING, got %s", status)
+	}
+
+	failing.Store(false)
+	m.Shutdown()
+	m.Check(context.Background())
+	if status := servingStatus(t, m, "user.UserService"); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING after shutdown, got %s", status)
+	}
+}
@@ -0,0 +1,32 @@
+package messaging
+
+import (
+	"context"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/event"
+)
+
+type LogPublisher struct {
+	l deps.Logger
+}
+
+func NewLogPublisher(l deps.Logger) *LogPublisher {
+	return &LogPublisher{
+		l: l,
+	}
+}
+
+func (p *LogPublisher) Name() string {
+	return "log"
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, record event.Record) error {
+	p.l.Info(ctx, "Domain event published",
+		"offset", record.Offset,
+		"eventID", record.Event.ID,
+		"type", record.Event.Type,
+		"userID", record.Event.UserID,
+	)
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, record event.Record) error
+}
+
+type Relay struct {
+	outbox        persistence.Outbox
+	publishers    []Publisher
+	l             deps.Logger
+	batchSize     int
+	retryInterval time.Duration
+}
+
+func NewRelay(outbox persistence.Outbox, l deps.Logger, batchSize int, retryInterval time.Duration, publishers ...Publisher) *Relay {
+	return &Relay{
+		outbox:        outbox,
+		publishers:    publishers,
+		l:             l,
+		batchSize:     batchSize,
+		retryInterval: retryInterval,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	for _, p := range r.publishers {
+		if err := r.outbox.Register(ctx, p.Name()); err != nil {
+			r.l.Error(ctx, "Failed to register outbox consumer", "publisher", p.Name(), "error", err)
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range r.publishers {
+		wg.Add(1)
+		go func(p Publisher) {
+			defer wg.Done()
+			r.run(ctx, p)
+		}(p)
+	}
+
+	wg.Wait()
+}
+
+func (r *Relay) run(ctx context.Context, p Publisher) {
+	for {
+		changed := r.outbox.Changed()
+
+		delivered, err := r.deliver(ctx, p)
+		if err != nil {
+			r.l.Error(ctx, "Failed to relay events", "publisher", p.Name(), "error", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.retryInterval):
+			}
+			continue
+		}
+
+		if delivered > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+func (r *Relay) deliver(ctx context.Context, p Publisher) (int, error) {
+	offset, err := r.outbox.Offset(ctx, p.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := r.outbox.Records(ctx, offset, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, record := range records {
+		if err := p.Publish(ctx, record); err != nil {
+			return i, err
+		}
+
+		if err := r.outbox.Commit(ctx, p.Name(), record.Offset); err != nil {
+			return i, err
+		}
+	}
+
+	return len(records), nil
+}
@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+	"testing"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+type flakyPublisher struct {
+	sync.Mutex
+	failures int
+	received []event.Record
+}
+
+func (p *flakyPublisher) Name() string {
+	return "flaky"
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, record event.Record) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+
+	p.received = append(p.received, record)
+	return nil
+}
+
+func (p *flakyPublisher) count() int {
+	p.Lock()
+	defer p.Unlock()
+
+	return len(p.received)
+}
+
+func TestRelayDeliversEventsAtLeastOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ur := persistence.NewUserRepositoryMemory(&deps.MockLogger{}, password.NewPool(bcrypt.MinCost, 1, 1), 1000)
+	publisher := &flakyPublisher{failures: 2}
+	relay := NewRelay(ur, &deps.MockLogger{}, 10, time.Millisecond, publisher)
+
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		user := &model.User{ID: uuid.New().String(), Username: uuid.New().String(), Email: uuid.New().String()}
+		if _, err := ur.CreateUser(ctx, user, nil, event.Event{ID: uuid.New().String(), Type: event.UserCreated, UserID: user.ID}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for publisher.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if publisher.count() != 3 {
+		t.Fatalf("Expected 3 delivered events, got %d", publisher.count())
+	}
+	for i, record := range publisher.received {
+		if record.Offset != uint64(i)+1 {
+			t.Errorf("Expected events in offset order, got %d at %d", record.Offset, i)
+		}
+	}
+
+	offset, _ := ur.Offset(ctx, publisher.Name())
+	if offset != 3 {
+		t.Errorf("Expected committed offset 3, got %d", offset)
+	}
+
+	cancel()
+	<-done
+}
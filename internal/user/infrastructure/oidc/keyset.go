@@ -0,0 +1,285 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"userCRUD/internal/common/deps"
+)
+
+const (
+	keyBits   = 2048
+	Algorithm = jose.RS256
+)
+
+var (
+	ErrUnknownKey       = errors.New("token is signed with an unknown key")
+	ErrUnexpectedHeader = errors.New("token has an unexpected header")
+	ErrInvalidKeyFile   = errors.New("invalid OIDC key file")
+)
+
+type signingKey struct {
+	jwk       jose.JSONWebKey
+	retiredAt time.Time
+}
+
+type storedKey struct {
+	Key       jose.JSONWebKey `json:"key"`
+	RetiredAt time.Time       `json:"retired_at,omitempty"`
+}
+
+type storedKeys struct {
+	Current storedKey   `json:"current"`
+	Next    storedKey   `json:"next"`
+	Retired []storedKey `json:"retired,omitempty"`
+}
+
+type KeySet struct {
+	mu        sync.RWMutex
+	current   *signingKey
+	next      *signingKey
+	retired   []*signingKey
+	rotation  time.Duration
+	retention time.Duration
+	path      string
+	l         deps.Logger
+}
+
+func NewKeySet(l deps.Logger, rotation, retention time.Duration, path string) (*KeySet, error) {
+	k := &KeySet{
+		rotation:  rotation,
+		retention: retention,
+		path:      path,
+		l:         l,
+	}
+
+	if path != "" {
+		loaded, err := k.load()
+		if err != nil {
+			return nil, err
+		}
+		if loaded {
+			return k, nil
+		}
+	}
+
+	current, err := newSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	next, err := newSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := k.save(current, next, nil); err != nil {
+		return nil, err
+	}
+	k.current, k.next = current, next
+
+	return k, nil
+}
+
+func (k *KeySet) Run(ctx context.Context) {
+	if k.rotation <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(k.rotation)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Rotate(); err != nil {
+				k.l.Error(ctx, "failed to rotate signing key", "error", err)
+				continue
+			}
+			k.l.Info(ctx, "OIDC signing key rotated", "kid", k.currentKeyID())
+		}
+	}
+}
+
+func (k *KeySet) Rotate() error {
+	next, err := newSigningKey()
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	retired := []*signingKey{{jwk: k.current.jwk, retiredAt: now}}
+	for _, key := range k.retired {
+		if now.Sub(key.retiredAt) <= k.retention {
+			retired = append(retired, key)
+		}
+	}
+
+	if err := k.save(k.next, next, retired); err != nil {
+		return err
+	}
+	k.current, k.next, k.retired = k.next, next, retired
+
+	return nil
+}
+
+func (k *KeySet) Sign(claims interface{}, typ string) (string, error) {
+	k.mu.RLock()
+	key := k.current.jwk
+	k.mu.RUnlock()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: Algorithm, Key: key}, (&jose.SignerOptions{}).WithType(jose.ContentType(typ)))
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.Signed(signer).Claims(claims).CompactSerialize()
+}
+
+func (k *KeySet) Verify(token, typ string, claims ...interface{}) error {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return err
+	}
+	if len(parsed.Headers) != 1 {
+		return ErrUnexpectedHeader
+	}
+
+	header := parsed.Headers[0]
+	if header.Algorithm != string(Algorithm) || header.ExtraHeaders[jose.HeaderType] != typ {
+		return ErrUnexpectedHeader
+	}
+
+	key, ok := k.publicKey(header.KeyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	return parsed.Claims(key, claims...)
+}
+
+func (k *KeySet) PublicKeys() jose.JSONWebKeySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := []jose.JSONWebKey{k.current.jwk.Public(), k.next.jwk.Public()}
+	for _, key := range k.retired {
+		keys = append(keys, key.jwk.Public())
+	}
+
+	return jose.JSONWebKeySet{Keys: keys}
+}
+
+func (k *KeySet) publicKey(kid string) (interface{}, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range append([]*signingKey{k.current}, k.retired...) {
+		if key.jwk.KeyID == kid {
+			return key.jwk.Public().Key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (k *KeySet) currentKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.current.jwk.KeyID
+}
+
+func (k *KeySet) load() (bool, error) {
+	data, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var stored storedKeys
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidKeyFile, err)
+	}
+
+	keys := append([]storedKey{stored.Current, stored.Next}, stored.Retired...)
+	for _, key := range keys {
+		if _, ok := key.Key.Key.(*rsa.PrivateKey); !ok || key.Key.KeyID == "" {
+			return false, fmt.Errorf("%w: every key needs a kid and an RSA private key", ErrInvalidKeyFile)
+		}
+	}
+
+	k.current = &signingKey{jwk: stored.Current.Key}
+	k.next = &signingKey{jwk: stored.Next.Key}
+	for _, key := range stored.Retired {
+		k.retired = append(k.retired, &signingKey{jwk: key.Key, retiredAt: key.RetiredAt})
+	}
+
+	return true, nil
+}
+
+func (k *KeySet) save(current, next *signingKey, retired []*signingKey) error {
+	if k.path == "" {
+		return nil
+	}
+
+	stored := storedKeys{
+		Current: storedKey{Key: current.jwk},
+		Next:    storedKey{Key: next.jwk},
+	}
+	for _, key := range retired {
+		stored.Retired = append(stored.Retired, storedKey{Key: key.jwk, RetiredAt: key.retiredAt})
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), filepath.Base(k.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), k.path)
+}
+
+func newSigningKey() (*signingKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		jwk: jose.JSONWebKey{
+			Key:       key,
+			KeyID:     uuid.New().String(),
+			Algorithm: string(Algorithm),
+			Use:       "sig",
+		},
+	}, nil
+}
@@ -0,0 +1,141 @@
+package oidc
+
+import (
+	"errors"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"userCRUD/internal/common/deps"
+)
+
+func newTestKeySet(t *testing.T) *KeySet {
+	ks, err := NewKeySet(&deps.MockLogger{}, 0, 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create key set: %v", err)
+	}
+
+	return ks
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ks := newTestKeySet(t)
+
+	token, err := ks.Sign(&jwt.Claims{Subject: "alice"}, "JWT")
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	var claims jwt.Claims
+	if err := ks.Verify(token, "JWT", &claims); err != nil || claims.Subject != "alice" {
+		t.Errorf("Expected token to verify, got %v %+v", err, claims)
+	}
+	if err := ks.Verify(token, "at+jwt", &claims); !errors.Is(err, ErrUnexpectedHeader) {
+		t.Errorf("Expected ErrUnexpectedHeader for wrong type, got %v", err)
+	}
+
+	other := newTestKeySet(t)
+	if err := other.Verify(token, "JWT", &claims); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestRotatePublishesNextKeyAndRetiresOld(t *testing.T) {
+	ks := newTestKeySet(t)
+
+	published := ks.PublicKeys()
+	if len(published.Keys) != 2 {
+		t.Fatalf("Expected current and next keys to be published, got %d", len(published.Keys))
+	}
+	next := published.Keys[1].KeyID
+
+	token, err := ks.Sign(&jwt.Claims{Subject: "alice"}, "JWT")
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	if err := ks.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	if ks.currentKeyID() != next {
+		t.Errorf("Expected the pre-published key %s to become current, got %s", next, ks.currentKeyID())
+	}
+	if len(ks.PublicKeys().Keys) != 3 {
+		t.Errorf("Expected retired key to stay published, got %d keys", len(ks.PublicKeys().Keys))
+	}
+	if err := ks.Verify(token, "JWT", &jwt.Claims{}); err != nil {
+		t.Errorf("Expected token signed before rotation to verify, got %v", err)
+	}
+
+	if err := ks.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	if len(ks.PublicKeys().Keys) != 3 {
+		t.Errorf("Expected keys past retention to be dropped, got %d keys", len(ks.PublicKeys().Keys))
+	}
+	if err := ks.Verify(token, "JWT", &jwt.Claims{}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey after retention, got %v", err)
+	}
+}
+
+func TestKeySetPersistsKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	ks, err := NewKeySet(&deps.MockLogger{}, 0, time.Hour, path)
+	if err != nil {
+		t.Fatalf("Failed to create key set: %v", err)
+	}
+	if err := ks.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	token, err := ks.Sign(&jwt.Claims{Subject: "alice"}, "JWT")
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	restarted, err := NewKeySet(&deps.MockLogger{}, 0, time.Hour, path)
+	if err != nil {
+		t.Fatalf("Failed to load key set: %v", err)
+	}
+	if restarted.currentKeyID() != ks.currentKeyID() || len(restarted.PublicKeys().Keys) != 3 {
+		t.Errorf("Expected the rotated keys to be loaded, got %d keys", len(restarted.PublicKeys().Keys))
+	}
+	if err := restarted.Verify(token, "JWT", &jwt.Claims{}); err != nil {
+		t.Errorf("Expected tokens to verify after a restart, got %v", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the key file to be private, got %v, %v", info, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"current":{}}`), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if _, err := NewKeySet(&deps.MockLogger{}, 0, time.Hour, path); !errors.Is(err, ErrInvalidKeyFile) {
+		t.Errorf("Expected ErrInvalidKeyFile, got %v", err)
+	}
+}
+
+func TestRotateKeepsKeysWhenSaveFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Failed to create key directory: %v", err)
+	}
+
+	ks, err := NewKeySet(&deps.MockLogger{}, 0, time.Hour, filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatalf("Failed to create key set: %v", err)
+	}
+	current, published := ks.currentKeyID(), len(ks.PublicKeys().Keys)
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("Failed to remove key directory: %v", err)
+	}
+	if err := ks.Rotate(); err == nil {
+		t.Fatal("Expected Rotate to fail when the key file cannot be written")
+	}
+	if ks.currentKeyID() != current || len(ks.PublicKeys().Keys) != published {
+		t.Errorf("Expected the persisted keys to stay in use, got current %s and %d keys", ks.currentKeyID(), len(ks.PublicKeys().Keys))
+	}
+}
@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"userCRUD/internal/user/domain/model"
+)
+
+var (
+	ErrIdentityNotFound    = errors.New("external identity not found")
+	ErrIdentityAlreadyUsed = errors.New("external identity is already linked to an account")
+)
+
+type IdentityRepository interface {
+	LinkIdentity(ctx context.Context, identity *model.ExternalIdentity) (*model.ExternalIdentity, error)
+	UnlinkIdentity(ctx context.Context, provider, subject string) error
+	GetIdentity(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error)
+	ListIdentities(ctx context.Context, userID string) ([]*model.ExternalIdentity, error)
+}
+
+type identityKey struct {
+	provider string
+	subject  string
+}
+
+type IdentityRepositoryMemory struct {
+	sync.RWMutex
+	identities map[identityKey]*model.ExternalIdentity
+}
+
+func NewIdentityRepositoryMemory() *IdentityRepositoryMemory {
+	return &IdentityRepositoryMemory{
+		identities: make(map[identityKey]*model.ExternalIdentity),
+	}
+}
+
+func (r *IdentityRepositoryMemory) LinkIdentity(ctx context.Context, identity *model.ExternalIdentity) (*model.ExternalIdentity, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	key := identityKey{provider: identity.Provider, subject: identity.Subject}
+	if _, exists := r.identities[key]; exists {
+		return nil, ErrIdentityAlreadyUsed
+	}
+	for _, i := range r.identities {
+		if i.UserID == identity.UserID && i.Provider == identity.Provider {
+			return nil, ErrIdentityAlreadyUsed
+		}
+	}
+	r.identities[key] = identity
+
+	return identity, nil
+}
+
+func (r *IdentityRepositoryMemory) UnlinkIdentity(ctx context.Context, provider, subject string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	key := identityKey{provider: provider, subject: subject}
+	if _, exists := r.identities[key]; !exists {
+		return ErrIdentityNotFound
+	}
+	delete(r.identities, key)
+
+	return nil
+}
+
+func (r *IdentityRepositoryMemory) GetIdentity(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	identity, ok := r.identities[identityKey{provider: provider, subject: subject}]
+	if !ok {
+		return nil, ErrIdentityNotFound
+	}
+
+	return identity, nil
+}
+
+func (r *IdentityRepositoryMemory) ListIdentities(ctx context.Context, userID string) ([]*model.ExternalIdentity, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	identities := make([]*model.ExternalIdentity, 0)
+	for _, i := range r.identities {
+		if i.UserID == userID {
+			identities = append(identities, i)
+		}
+	}
+	sort.Slice(identities, func(a, b int) bool {
+		return identities[a].Provider < identities[b].Provider
+	})
+
+	return identities, nil
+}
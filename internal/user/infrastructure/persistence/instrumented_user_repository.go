@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"context"
+	"time"
+	"userCRUD/internal/common"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+)
+
+type InstrumentedUserRepository struct {
+	ur UserRepository
+}
+
+func NewInstrumentedUserRepository(ur UserRepository) *InstrumentedUserRepository {
+	return &InstrumentedUserRepository{ur: ur}
+}
+
+func (i *InstrumentedUserRepository) CreateUser(ctx context.Context, user *model.User, audit AuditFunc, events ...event.Event) (created *model.User, err error) {
+	ctx, done := instrument(ctx, "CreateUser")
+	defer done(&err)
+
+	return i.ur.CreateUser(ctx, user, audit, events...)
+}
+
+func (i *InstrumentedUserRepository) CreateUsers(ctx context.Context, users []*model.User, audit AuditFunc, events ...event.Event) (errs []error, err error) {
+	ctx, done := instrument(ctx, "CreateUsers")
+	defer done(&err)
+
+	return i.ur.CreateUsers(ctx, users, audit, events...)
+}
+
+func (i *InstrumentedUserRepository) UpdateUser(ctx context.Context, user *model.UpdateUser, audit AuditFunc, events ...event.Event) (updated *model.User, err error) {
+	ctx, done := instrument(ctx, "UpdateUser")
+	defer done(&err)
+
+	return i.ur.UpdateUser(ctx, user, audit, events...)
+}
+
+func (i *InstrumentedUserRepository) DeleteUser(ctx context.Context, id string, audit AuditFunc, events ...event.Event) (err error) {
+	ctx, done := instrument(ctx, "DeleteUser")
+	defer done(&err)
+
+	return i.ur.DeleteUser(ctx, id, audit, events...)
+}
+
+func (i *InstrumentedUserRepository) GetUsers(ctx context.Context, pagination *common.Pagination) (users []*model.User, err error) {
+	ctx, done := instrument(ctx, "GetUsers")
+	defer done(&err)
+
+	return i.ur.GetUsers(ctx, pagination)
+}
+
+func (i *InstrumentedUserRepository) Snapshot(ctx context.Context) (users []*model.User, revision uint64, err error) {
+	ctx, done := instrument(ctx, "Snapshot")
+	defer done(&err)
+
+	return i.ur.Snapshot(ctx)
+}
+
+func (i *InstrumentedUserRepository) GetUserByID(ctx context.Context, id string) (user *model.User, err error) {
+	ctx, done := instrument(ctx, "GetUserByID")
+	defer done(&err)
+
+	return i.ur.GetUserByID(ctx, id)
+}
+
+func (i *InstrumentedUserRepository) GetUserByUsername(ctx context.Context, username string) (user *model.User, err error) {
+	ctx, done := instrument(ctx, "GetUserByUsername")
+	defer done(&err)
+
+	return i.ur.GetUserByUsername(ctx, username)
+}
+
+func (i *InstrumentedUserRepository) GetUserByEmail(ctx context.Context, email string) (user *model.User, err error) {
+	ctx, done := instrument(ctx, "GetUserByEmail")
+	defer done(&err)
+
+	return i.ur.GetUserByEmail(ctx, email)
+}
+
+func (i *InstrumentedUserRepository) GetUsersByIDs(ctx context.Context, ids []string) (users []*model.User, err error) {
+	ctx, done := instrument(ctx, "GetUsersByIDs")
+	defer done(&err)
+
+	return i.ur.GetUsersByIDs(ctx, ids)
+}
+
+func (i *InstrumentedUserRepository) GetUsersByUsernames(ctx context.Context, usernames []string) (users []*model.User, err error) {
+	ctx, done := instrument(ctx, "GetUsersByUsernames")
+	defer done(&err)
+
+	return i.ur.GetUsersByUsernames(ctx, usernames)
+}
+
+func (i *InstrumentedUserRepository) GetUserByUsernameAndPassword(ctx context.Context, username, rawPassword string) (user *model.User, err error) {
+	ctx, done := instrument(ctx, "GetUserByUsernameAndPassword")
+	defer done(&err)
+
+	return i.ur.GetUserByUsernameAndPassword(ctx, username, rawPassword)
+}
+
+func (i *InstrumentedUserRepository) GetPasswordHistory(ctx context.Context, id string) (history []string, err error) {
+	ctx, done := instrument(ctx, "GetPasswordHistory")
+	defer done(&err)
+
+	return i.ur.GetPasswordHistory(ctx, id)
+}
+
+func (i *InstrumentedUserRepository) ChangePassword(ctx context.Context, id, hashedPassword string, audit AuditFunc, events ...event.Event) (user *model.User, err error) {
+	ctx, done := instrument(ctx, "ChangePassword")
+	defer done(&err)
+
+	return i.ur.ChangePassword(ctx, id, hashedPassword, audit, events...)
+}
+
+func (i *InstrumentedUserRepository) CountUsers(ctx context.Context) (count int, err error) {
+	ctx, done := instrument(ctx, "CountUsers")
+	defer done(&err)
+
+	return i.ur.CountUsers(ctx)
+}
+
+func (i *InstrumentedUserRepository) Ping(ctx context.Context) (err error) {
+	ctx, done := instrument(ctx, "Ping")
+	defer done(&err)
+
+	return i.ur.Ping(ctx)
+}
+
+func instrument(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "UserRepository."+operation)
+
+	return ctx, func(err *error) {
+		result := metrics.ResultOK
+		if *err != nil {
+			result = metrics.ResultError
+		}
+
+		metrics.RepositoryDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+		tracing.End(span, *err)
+	}
+}
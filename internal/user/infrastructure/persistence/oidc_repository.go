@@ -0,0 +1,111 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"userCRUD/internal/user/domain/model"
+)
+
+var (
+	ErrOIDCClientNotFound        = errors.New("oidc client with the ID not found")
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found")
+)
+
+type OIDCRepository interface {
+	CreateClient(ctx context.Context, client *model.OIDCClient) (*model.OIDCClient, error)
+	GetClient(ctx context.Context, id string) (*model.OIDCClient, error)
+	ListClients(ctx context.Context) ([]*model.OIDCClient, error)
+	DeleteClient(ctx context.Context, id string) error
+	SaveCode(ctx context.Context, code *model.AuthorizationCode) error
+	TakeCode(ctx context.Context, code string) (*model.AuthorizationCode, error)
+}
+
+type OIDCRepositoryMemory struct {
+	sync.RWMutex
+	clients []*model.OIDCClient
+	codes   map[string]*model.AuthorizationCode
+}
+
+func NewOIDCRepositoryMemory() *OIDCRepositoryMemory {
+	return &OIDCRepositoryMemory{
+		codes: make(map[string]*model.AuthorizationCode),
+	}
+}
+
+func (r *OIDCRepositoryMemory) CreateClient(ctx context.Context, client *model.OIDCClient) (*model.OIDCClient, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.clients = append(r.clients, client)
+
+	return client, nil
+}
+
+func (r *OIDCRepositoryMemory) GetClient(ctx context.Context, id string) (*model.OIDCClient, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, c := range r.clients {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+
+	return nil, ErrOIDCClientNotFound
+}
+
+func (r *OIDCRepositoryMemory) ListClients(ctx context.Context) ([]*model.OIDCClient, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return append([]*model.OIDCClient(nil), r.clients...), nil
+}
+
+func (r *OIDCRepositoryMemory) DeleteClient(ctx context.Context, id string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for i, c := range r.clients {
+		if c.ID == id {
+			r.clients = append(r.clients[:i], r.clients[i+1:]...)
+			for value, code := range r.codes {
+				if code.ClientID == id {
+					delete(r.codes, value)
+				}
+			}
+			return nil
+		}
+	}
+
+	return ErrOIDCClientNotFound
+}
+
+func (r *OIDCRepositoryMemory) SaveCode(ctx context.Context, code *model.AuthorizationCode) error {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	for value, c := range r.codes {
+		if now.After(c.ExpiresAt) {
+			delete(r.codes, value)
+		}
+	}
+	r.codes[code.Code] = code
+
+	return nil
+}
+
+func (r *OIDCRepositoryMemory) TakeCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	c, ok := r.codes[code]
+	if !ok {
+		return nil, ErrAuthorizationCodeNotFound
+	}
+	delete(r.codes, code)
+
+	return c, nil
+}
@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"context"
+	"userCRUD/internal/user/domain/event"
+)
+
+type Outbox interface {
+	Records(ctx context.Context, afterOffset uint64, limit int) ([]event.Record, error)
+	Offset(ctx context.Context, consumer string) (uint64, error)
+	Commit(ctx context.Context, consumer string, offset uint64) error
+	Revision(ctx context.Context) (uint64, error)
+	Register(ctx context.Context, consumer string) error
+	Changed() <-chan struct{}
+}
+
+func (r *UserRepositoryMemory) Records(ctx context.Context, afterOffset uint64, limit int) ([]event.Record, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if afterOffset < r.outboxBase {
+		return nil, ErrRevisionCompacted
+	}
+	if afterOffset >= r.revision() {
+		return nil, nil
+	}
+
+	records := r.outbox[afterOffset-r.outboxBase:]
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	return append([]event.Record(nil), records...), nil
+}
+
+func (r *UserRepositoryMemory) Offset(ctx context.Context, consumer string) (uint64, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.offsets[consumer], nil
+}
+
+func (r *UserRepositoryMemory) Commit(ctx context.Context, consumer string, offset uint64) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if offset > r.offsets[consumer] {
+		r.offsets[consumer] = offset
+	}
+	r.compact()
+
+	return nil
+}
+
+func (r *UserRepositoryMemory) Register(ctx context.Context, consumer string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.offsets[consumer]; !ok {
+		r.offsets[consumer] = r.outboxBase
+	}
+
+	return nil
+}
+
+func (r *UserRepositoryMemory) Revision(ctx context.Context) (uint64, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.revision(), nil
+}
+
+func (r *UserRepositoryMemory) Changed() <-chan struct{} {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.changed
+}
+
+func (r *UserRepositoryMemory) appendEvents(events []event.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	for _, e := range events {
+		r.outbox = append(r.outbox, event.Record{
+			Offset: r.revision() + 1,
+			Event:  e,
+		})
+	}
+	r.compact()
+
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *UserRepositoryMemory) revision() uint64 {
+	return r.outboxBase + uint64(len(r.outbox))
+}
+
+func (r *UserRepositoryMemory) compact() {
+	keep := r.revision()
+	if retained := uint64(r.retention); keep > retained {
+		keep -= retained
+	} else {
+		keep = 0
+	}
+	for _, offset := range r.offsets {
+		if offset < keep {
+			keep = offset
+		}
+	}
+
+	if keep <= r.outboxBase {
+		return
+	}
+	trimmed := keep - r.outboxBase
+	if trimmed < uint64(len(r.outbox))/2 {
+		return
+	}
+
+	r.outbox = append(make([]event.Record, 0, len(r.outbox)-int(trimmed)), r.outbox[trimmed:]...)
+	r.outboxBase = keep
+}
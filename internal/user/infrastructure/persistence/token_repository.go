@@ -0,0 +1,73 @@
+package persistence
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"userCRUD/internal/user/domain/model"
+)
+
+var (
+	ErrTokenNotFound = errors.New("token not found or expired")
+)
+
+type TokenRepository interface {
+	CreateToken(ctx context.Context, token *model.Token) error
+	GetToken(ctx context.Context, value string) (*model.Token, error)
+}
+
+type TokenRepositoryMemory struct {
+	sync.Mutex
+	tokensByHash map[string]*model.Token
+}
+
+func NewTokenRepositoryMemory() *TokenRepositoryMemory {
+	return &TokenRepositoryMemory{
+		tokensByHash: make(map[string]*model.Token),
+	}
+}
+
+func (r *TokenRepositoryMemory) CreateToken(ctx context.Context, token *model.Token) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for hash, t := range r.tokensByHash {
+		if t.Expired() {
+			delete(r.tokensByHash, hash)
+		}
+	}
+
+	stored := *token
+	stored.Value = ""
+	r.tokensByHash[hashToken(token.Value)] = &stored
+
+	return nil
+}
+
+func (r *TokenRepositoryMemory) GetToken(ctx context.Context, value string) (*model.Token, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	hash := hashToken(value)
+	token, ok := r.tokensByHash[hash]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	if token.Expired() {
+		delete(r.tokensByHash, hash)
+		return nil, ErrTokenNotFound
+	}
+
+	found := *token
+	found.Value = value
+
+	return &found, nil
+}
+
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
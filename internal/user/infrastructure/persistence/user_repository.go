@@ -5,10 +5,12 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"sync"
+	"time"
 	"userCRUD/internal/common"
 	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/user/domain/event"
 	"userCRUD/internal/user/domain/model"
-	"userCRUD/pkg/common/password"
 )
 
 var (
@@ -17,51 +19,101 @@ var (
 	ErrUsernameTaken     = errors.New("username is already taken")
 	ErrEmailTaken        = errors.New("email is already taken")
 	ErrPageOutOfRange    = errors.New("page out of range")
+	ErrBatchRejected     = errors.New("batch rejected, no users were created")
+	ErrStorageBusy       = errors.New("user storage did not respond in time")
+	ErrRevisionCompacted = errors.New("revision has been compacted, take a new snapshot")
 )
 
+type AuditFunc func(before, after *model.User) error
+
 type UserRepository interface {
-	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
-	UpdateUser(ctx context.Context, user *model.UpdateUser) (*model.User, error)
-	DeleteUser(ctx context.Context, id string) error
+	CreateUser(ctx context.Context, user *model.User, audit AuditFunc, events ...event.Event) (*model.User, error)
+	CreateUsers(ctx context.Context, users []*model.User, audit AuditFunc, events ...event.Event) ([]error, error)
+	UpdateUser(ctx context.Context, user *model.UpdateUser, audit AuditFunc, events ...event.Event) (*model.User, error)
+	DeleteUser(ctx context.Context, id string, audit AuditFunc, events ...event.Event) error
 	GetUsers(ctx context.Context, pagination *common.Pagination) ([]*model.User, error)
+	Snapshot(ctx context.Context) ([]*model.User, uint64, error)
 	GetUserByID(ctx context.Context, id string) (*model.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUsersByIDs(ctx context.Context, ids []string) ([]*model.User, error)
+	GetUsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error)
 	GetUserByUsernameAndPassword(ctx context.Context, username, rawPassword string) (*model.User, error)
+	GetPasswordHistory(ctx context.Context, id string) ([]string, error)
+	ChangePassword(ctx context.Context, id, hashedPassword string, audit AuditFunc, events ...event.Event) (*model.User, error)
+	CountUsers(ctx context.Context) (int, error)
+	Ping(ctx context.Context) error
 }
 
-type UserRepositoryMemory struct {
+const (
+	passwordHistoryLimit = 24
+	pingRetryInterval    = time.Millisecond
+)
+
+type timedRWMutex struct {
 	sync.RWMutex
+}
+
+func (m *timedRWMutex) Lock() {
+	start := time.Now()
+	m.RWMutex.Lock()
+	metrics.RepositoryLockWait.WithLabelValues("write").Observe(time.Since(start).Seconds())
+}
+
+func (m *timedRWMutex) RLock() {
+	start := time.Now()
+	m.RWMutex.RLock()
+	metrics.RepositoryLockWait.WithLabelValues("read").Observe(time.Since(start).Seconds())
+}
+
+type UserRepositoryMemory struct {
+	timedRWMutex
 	l              deps.Logger
+	h              deps.PasswordHasher
 	orderedUserIDs []string
 	usersByID      map[string]*model.User
 	userByUsername map[string]*model.User
 	userByEmail    map[string]*model.User
+	passwordsByID  map[string][]string
+	outbox         []event.Record
+	outboxBase     uint64
+	retention      int
+	offsets        map[string]uint64
+	changed        chan struct{}
 }
 
-func NewUserRepositoryMemory(l deps.Logger) *UserRepositoryMemory {
+func NewUserRepositoryMemory(l deps.Logger, h deps.PasswordHasher, retention int) *UserRepositoryMemory {
 
 	ur := &UserRepositoryMemory{
 		l:              l,
+		h:              h,
 		orderedUserIDs: make([]string, 0, 16),
 		usersByID:      make(map[string]*model.User),
 		userByUsername: make(map[string]*model.User),
 		userByEmail:    make(map[string]*model.User),
+		passwordsByID:  make(map[string][]string),
+		outbox:         make([]event.Record, 0, 64),
+		retention:      retention,
+		offsets:        make(map[string]uint64),
+		changed:        make(chan struct{}),
 	}
 
-	adminPassHashed, _ := password.HashPassword("admin")
+	adminPassHashed, _ := h.Hash(context.Background(), "admin")
 
 	ur.CreateUser(context.Background(), &model.User{
-		ID:       uuid.New().String(),
-		Email:    "admin@gmail.com",
-		Username: "admin",
-		Password: adminPassHashed,
-		Admin:    true,
-	})
+		ID:                uuid.New().String(),
+		Email:             "admin@gmail.com",
+		Username:          "admin",
+		Password:          adminPassHashed,
+		Admin:             true,
+		Permissions:       []model.Permission{model.PermissionImpersonate},
+		PasswordChangedAt: time.Now(),
+	}, nil)
 
 	return ur
 }
 
-func (r *UserRepositoryMemory) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+func (r *UserRepositoryMemory) CreateUser(ctx context.Context, user *model.User, audit AuditFunc, events ...event.Event) (*model.User, error) {
 	r.Lock()
 	defer r.Unlock()
 
@@ -77,17 +129,75 @@ func (r *UserRepositoryMemory) CreateUser(ctx context.Context, user *model.User)
 		return nil, ErrUsernameTaken
 	}
 
+	if err := runAudit(audit, nil, user); err != nil {
+		return nil, err
+	}
+
 	r.usersByID[user.ID] = user
 	r.userByUsername[user.Username] = user
 	r.userByEmail[user.Email] = user
 	r.orderedUserIDs = append(r.orderedUserIDs, user.ID)
+	r.appendEvents(events)
 
 	r.l.Info(ctx, "User created", "id", user.ID)
 
 	return user, nil
 }
 
-func (r *UserRepositoryMemory) UpdateUser(ctx context.Context, userU *model.UpdateUser) (*model.User, error) {
+func (r *UserRepositoryMemory) CreateUsers(ctx context.Context, users []*model.User, audit AuditFunc, events ...event.Event) ([]error, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	errs := make([]error, len(users))
+	failed := false
+	usernames := make(map[string]struct{}, len(users))
+	emails := make(map[string]struct{}, len(users))
+
+	for i, user := range users {
+		_, idExists := r.usersByID[user.ID]
+		_, emailExists := r.userByEmail[user.Email]
+		_, usernameExists := r.userByUsername[user.Username]
+		_, emailInBatch := emails[user.Email]
+		_, usernameInBatch := usernames[user.Username]
+
+		switch {
+		case idExists:
+			errs[i] = ErrUserAlreadyExists
+		case emailExists || emailInBatch:
+			errs[i] = ErrEmailTaken
+		case usernameExists || usernameInBatch:
+			errs[i] = ErrUsernameTaken
+		}
+
+		failed = failed || errs[i] != nil
+		emails[user.Email] = struct{}{}
+		usernames[user.Username] = struct{}{}
+	}
+
+	if failed {
+		return errs, ErrBatchRejected
+	}
+
+	for _, user := range users {
+		if err := runAudit(audit, nil, user); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, user := range users {
+		r.usersByID[user.ID] = user
+		r.userByUsername[user.Username] = user
+		r.userByEmail[user.Email] = user
+		r.orderedUserIDs = append(r.orderedUserIDs, user.ID)
+	}
+	r.appendEvents(events)
+
+	r.l.Info(ctx, "Users created", "count", len(users))
+
+	return errs, nil
+}
+
+func (r *UserRepositoryMemory) UpdateUser(ctx context.Context, userU *model.UpdateUser, audit AuditFunc, events ...event.Event) (*model.User, error) {
 	r.Lock()
 	defer r.Unlock()
 
@@ -109,26 +219,42 @@ func (r *UserRepositoryMemory) UpdateUser(ctx context.Context, userU *model.Upda
 	}
 
 	user := &model.User{
-		ID:       userU.ID,
-		Email:    userU.Email,
-		Username: userU.Username,
-		Password: userU.Password,
-		Admin:    userU.Admin,
+		ID:                 userU.ID,
+		Email:              userU.Email,
+		Username:           userU.Username,
+		Password:           exUser.Password,
+		Admin:              userU.Admin,
+		Permissions:        userU.Permissions,
+		MustChangePassword: userU.MustChangePassword,
+		PasswordChangedAt:  exUser.PasswordChangedAt,
+	}
+
+	if userU.Password != "" {
+		user.Password = userU.Password
+	}
+
+	if err := runAudit(audit, exUser, user); err != nil {
+		return nil, err
+	}
+
+	if userU.Password != "" {
+		r.rotatePassword(exUser, user, userU.Password)
 	}
 
 	delete(r.userByUsername, exUser.Username)
 	delete(r.userByEmail, exUser.Email)
 
-	exUser = user
+	r.usersByID[user.ID] = user
 	r.userByUsername[user.Username] = user
 	r.userByEmail[user.Email] = user
+	r.appendEvents(events)
 
 	r.l.Info(ctx, "User updated", "id", user.ID)
 
 	return user, nil
 }
 
-func (r *UserRepositoryMemory) DeleteUser(ctx context.Context, id string) error {
+func (r *UserRepositoryMemory) DeleteUser(ctx context.Context, id string, audit AuditFunc, events ...event.Event) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -137,6 +263,10 @@ func (r *UserRepositoryMemory) DeleteUser(ctx context.Context, id string) error
 		return ErrUserNotFound
 	}
 
+	if err := runAudit(audit, exUser, nil); err != nil {
+		return err
+	}
+
 	for i, userID := range r.orderedUserIDs {
 		if userID == id {
 			r.orderedUserIDs = append(r.orderedUserIDs[:i], r.orderedUserIDs[i+1:]...)
@@ -147,6 +277,8 @@ func (r *UserRepositoryMemory) DeleteUser(ctx context.Context, id string) error
 	delete(r.userByUsername, exUser.Username)
 	delete(r.userByEmail, exUser.Email)
 	delete(r.usersByID, id)
+	delete(r.passwordsByID, id)
+	r.appendEvents(events)
 
 	r.l.Info(ctx, "User deleted", "id", id)
 
@@ -177,6 +309,42 @@ func (r *UserRepositoryMemory) GetUserByUsername(ctx context.Context, username s
 	return user, nil
 }
 
+func (r *UserRepositoryMemory) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	user, ok := r.userByEmail[email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (r *UserRepositoryMemory) GetUsersByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	users := make([]*model.User, len(ids))
+	for i, id := range ids {
+		users[i] = r.usersByID[id]
+	}
+
+	return users, nil
+}
+
+func (r *UserRepositoryMemory) GetUsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	users := make([]*model.User, len(usernames))
+	for i, username := range usernames {
+		users[i] = r.userByUsername[username]
+	}
+
+	return users, nil
+}
+
 func (r *UserRepositoryMemory) GetUsers(ctx context.Context, pagination *common.Pagination) ([]*model.User, error) {
 	r.RLock()
 	defer r.RUnlock()
@@ -200,18 +368,114 @@ func (r *UserRepositoryMemory) GetUsers(ctx context.Context, pagination *common.
 	return users, nil
 }
 
-func (r *UserRepositoryMemory) GetUserByUsernameAndPassword(ctx context.Context, username, rawPassword string) (*model.User, error) {
+func (r *UserRepositoryMemory) Snapshot(ctx context.Context) ([]*model.User, uint64, error) {
 	r.RLock()
 	defer r.RUnlock()
 
+	users := make([]*model.User, 0, len(r.orderedUserIDs))
+	for _, userID := range r.orderedUserIDs {
+		users = append(users, r.usersByID[userID])
+	}
+
+	return users, r.revision(), nil
+}
+
+func (r *UserRepositoryMemory) GetUserByUsernameAndPassword(ctx context.Context, username, rawPassword string) (*model.User, error) {
 	user, err := r.GetUserByUsername(ctx, username)
-	if user == nil && err != nil {
+	if err != nil || user == nil {
 		return nil, ErrUserNotFound
 	}
 
-	if !password.CheckPasswordHash(rawPassword, user.Password) {
+	match, err := r.h.Compare(ctx, rawPassword, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
 		return nil, ErrUserNotFound
 	}
 
 	return user, nil
 }
+
+func (r *UserRepositoryMemory) GetPasswordHistory(ctx context.Context, id string) ([]string, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if _, ok := r.usersByID[id]; !ok {
+		return nil, ErrUserNotFound
+	}
+
+	history := make([]string, len(r.passwordsByID[id]))
+	copy(history, r.passwordsByID[id])
+
+	return history, nil
+}
+
+func (r *UserRepositoryMemory) ChangePassword(ctx context.Context, id, hashedPassword string, audit AuditFunc, events ...event.Event) (*model.User, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	exUser, found := r.usersByID[id]
+	if !found {
+		return nil, ErrUserNotFound
+	}
+
+	user := *exUser
+	user.MustChangePassword = false
+	user.Password = hashedPassword
+	if err := runAudit(audit, exUser, &user); err != nil {
+		return nil, err
+	}
+	r.rotatePassword(exUser, &user, hashedPassword)
+
+	r.usersByID[user.ID] = &user
+	r.userByUsername[user.Username] = &user
+	r.userByEmail[user.Email] = &user
+	r.appendEvents(events)
+
+	r.l.Info(ctx, "User password changed", "id", user.ID)
+
+	return &user, nil
+}
+
+func runAudit(audit AuditFunc, before, after *model.User) error {
+	if audit == nil {
+		return nil
+	}
+
+	return audit(before, after)
+}
+
+func (r *UserRepositoryMemory) rotatePassword(exUser, user *model.User, hashedPassword string) {
+	history := append([]string{exUser.Password}, r.passwordsByID[exUser.ID]...)
+	if len(history) > passwordHistoryLimit {
+		history = history[:passwordHistoryLimit]
+	}
+	r.passwordsByID[exUser.ID] = history
+
+	user.Password = hashedPassword
+	user.PasswordChangedAt = time.Now()
+}
+
+func (r *UserRepositoryMemory) CountUsers(ctx context.Context) (int, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return len(r.usersByID), nil
+}
+
+func (r *UserRepositoryMemory) Ping(ctx context.Context) error {
+	ticker := time.NewTicker(pingRetryInterval)
+	defer ticker.Stop()
+
+	for !r.TryRLock() {
+		select {
+		case <-ctx.Done():
+			return ErrStorageBusy
+		case <-ticker.C:
+		}
+	}
+	r.RUnlock()
+
+	return nil
+}
@@ -3,70 +3,205 @@ package persistence
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/crypto/bcrypt"
 	"testing"
+	"time"
 	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/user/domain/event"
 	"userCRUD/internal/user/domain/model"
+	"userCRUD/pkg/common/password"
 )
 
 var ctx = context.TODO()
 var logger = &deps.MockLogger{}
+var hasher = password.NewPool(bcrypt.MinCost, 4, 16)
 
 func TestCreateAndUpdateUser(t *testing.T) {
-	ur := NewUserRepositoryMemory(&deps.MockLogger{})
+	ur := NewUserRepositoryMemory(&deps.MockLogger{}, hasher, 1000)
 
 	userFirst := &model.User{ID: uuid.New().String(), Username: "userFirst", Email: "userFirst@example.com"}
 	userSecond := &model.User{ID: uuid.New().String(), Username: "userSecond", Email: "userSecond@example.com"}
-	user, err := ur.CreateUser(ctx, userFirst)
+	user, err := ur.CreateUser(ctx, userFirst, nil)
 	if err != nil || user == nil {
 		t.Errorf("Failed to add user: %v", err)
 	}
 
-	user, err = ur.CreateUser(ctx, userSecond)
+	user, err = ur.CreateUser(ctx, userSecond, nil)
 	if err != nil || user == nil {
 		t.Errorf("Failed to create user: %v", err)
 	}
 
-	user, err = ur.CreateUser(ctx, userFirst)
+	user, err = ur.CreateUser(ctx, userFirst, nil)
 	if !errors.Is(err, ErrUserAlreadyExists) {
 		t.Errorf("Expected ErrUserAlreadyExists, got %v", err)
 	}
 
 	userSecondUpdate := &model.UpdateUser{ID: userSecond.ID, Username: userFirst.Username, Email: "newtest@example.com"}
-	user, err = ur.UpdateUser(ctx, userSecondUpdate)
+	user, err = ur.UpdateUser(ctx, userSecondUpdate, nil)
 	if !errors.Is(err, ErrUsernameTaken) {
 		t.Errorf("Expected ErrUsernameTaken, got %v", err)
 	}
 
 	userSecondUpdate = &model.UpdateUser{ID: userSecond.ID, Username: "newuser", Email: "userFirst@example.com"}
-	user, err = ur.UpdateUser(ctx, userSecondUpdate)
+	user, err = ur.UpdateUser(ctx, userSecondUpdate, nil)
 	if !errors.Is(err, ErrEmailTaken) {
 		t.Errorf("Expected ErrEmailTaken, got %v", err)
 	}
 
 	userSecondUpdate = &model.UpdateUser{ID: userSecond.ID, Username: "newuser", Email: "newtest@example.com"}
-	user, err = ur.UpdateUser(ctx, userSecondUpdate)
+	user, err = ur.UpdateUser(ctx, userSecondUpdate, nil)
 	if err != nil || user == nil {
 		t.Errorf("Failed to update user: %v", err)
 	}
 }
 
 func TestDeleteUser(t *testing.T) {
-	ur := NewUserRepositoryMemory(logger)
+	ur := NewUserRepositoryMemory(logger, hasher, 1000)
 
 	userFirst := &model.User{ID: uuid.New().String(), Username: "userFirst", Email: "userFirst@example.com"}
-	user, err := ur.CreateUser(ctx, userFirst)
+	user, err := ur.CreateUser(ctx, userFirst, nil)
 	if err != nil || user == nil {
 		t.Errorf("Failed to create user: %v", err)
 	}
 
-	err = ur.DeleteUser(ctx, uuid.New().String())
+	err = ur.DeleteUser(ctx, uuid.New().String(), nil)
 	if !errors.Is(err, ErrUserNotFound) {
 		t.Errorf("Expected ErrUserNotFound, got %v", err)
 	}
 
-	err = ur.DeleteUser(ctx, userFirst.ID)
+	err = ur.DeleteUser(ctx, userFirst.ID, nil)
 	if err != nil {
 		t.Errorf("Failed to delete user: %v", err)
 	}
 }
+
+func TestPing(t *testing.T) {
+	ur := NewUserRepositoryMemory(logger, hasher, 1000)
+
+	if err := ur.Ping(ctx); err != nil {
+		t.Errorf("Expected ping to succeed, got %v", err)
+	}
+
+	ur.Lock()
+	defer ur.Unlock()
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := ur.Ping(timeout); !errors.Is(err, ErrStorageBusy) {
+		t.Errorf("Expected ErrStorageBusy while storage is locked, got %v", err)
+	}
+}
+
+func TestInstrumentedUserRepository(t *testing.T) {
+	memory := NewUserRepositoryMemory(logger, hasher, 1000)
+	ur := NewInstrumentedUserRepository(memory)
+
+	ok := metrics.RepositoryDuration.WithLabelValues("GetUserByUsername", metrics.ResultOK).(prometheus.Histogram)
+	failed := metrics.RepositoryDuration.WithLabelValues("GetUserByUsername", metrics.ResultError).(prometheus.Histogram)
+	okBefore, failedBefore := sampleCount(t, ok), sampleCount(t, failed)
+	readWaitsBefore := sampleCount(t, metrics.RepositoryLockWait.WithLabelValues("read").(prometheus.Histogram))
+
+	if _, err := ur.GetUserByUsername(ctx, "admin"); err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+	if _, err := ur.GetUserByUsername(ctx, "missing"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if count, err := ur.CountUsers(ctx); err != nil || count != 1 {
+		t.Errorf("Expected one user, got %d %v", count, err)
+	}
+
+	if got := sampleCount(t, ok) - okBefore; got != 1 {
+		t.Errorf("Expected 1 successful lookup observed, got %d", got)
+	}
+	if got := sampleCount(t, failed) - failedBefore; got != 1 {
+		t.Errorf("Expected 1 failed lookup observed, got %d", got)
+	}
+	if got := sampleCount(t, metrics.RepositoryLockWait.WithLabelValues("read").(prometheus.Histogram)) - readWaitsBefore; got < 3 {
+		t.Errorf("Expected read lock waits to be observed, got %d", got)
+	}
+}
+
+func TestFailedAuditAbortsMutation(t *testing.T) {
+	ur := NewUserRepositoryMemory(&deps.MockLogger{}, hasher, 1000)
+	errAudit := errors.New("audit log unavailable")
+	failAudit := func(before, after *model.User) error { return errAudit }
+	revision, _ := ur.Revision(ctx)
+
+	user := &model.User{ID: uuid.New().String(), Username: "audited", Email: "audited@example.com"}
+	if _, err := ur.CreateUser(ctx, user, failAudit, event.Event{Type: event.UserCreated}); !errors.Is(err, errAudit) {
+		t.Fatalf("Expected the audit error, got %v", err)
+	}
+	if _, err := ur.GetUserByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected the user not to be created, got %v", err)
+	}
+	if got, _ := ur.Revision(ctx); got != revision {
+		t.Errorf("Expected no outbox records, got revision %d", got)
+	}
+
+	var audited []*model.User
+	recordAudit := func(before, after *model.User) error {
+		audited = append(audited, before, after)
+		return nil
+	}
+	if _, err := ur.CreateUser(ctx, user, recordAudit); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := ur.UpdateUser(ctx, &model.UpdateUser{ID: user.ID, Username: "renamed", Email: user.Email}, failAudit); !errors.Is(err, errAudit) {
+		t.Fatalf("Expected the audit error, got %v", err)
+	}
+	if found, _ := ur.GetUserByID(ctx, user.ID); found.Username != "audited" {
+		t.Errorf("Expected the update to be rolled back, got %s", found.Username)
+	}
+	if err := ur.DeleteUser(ctx, user.ID, recordAudit); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if len(audited) != 4 || audited[0] != nil || audited[1].ID != user.ID || audited[2].ID != user.ID || audited[3] != nil {
+		t.Errorf("Expected create and delete to be audited with before and after, got %v", audited)
+	}
+}
+
+func TestOutboxCompaction(t *testing.T) {
+	ur := NewUserRepositoryMemory(&deps.MockLogger{}, hasher, 2)
+	if err := ur.Register(ctx, "relay"); err != nil {
+		t.Fatalf("Failed to register consumer: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		user := &model.User{ID: uuid.New().String(), Username: fmt.Sprintf("compacted%d", i), Email: fmt.Sprintf("compacted%d@example.com", i)}
+		if _, err := ur.CreateUser(ctx, user, nil, event.Event{Type: event.UserCreated}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if records, err := ur.Records(ctx, 0, 0); err != nil || len(records) != 6 {
+		t.Fatalf("Expected uncommitted records to be kept, got %d, %v", len(records), err)
+	}
+
+	if err := ur.Commit(ctx, "relay", 6); err != nil {
+		t.Fatalf("Failed to commit offset: %v", err)
+	}
+	if _, err := ur.Records(ctx, 0, 0); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("Expected ErrRevisionCompacted below the retained window, got %v", err)
+	}
+	records, err := ur.Records(ctx, 4, 0)
+	if err != nil || len(records) != 2 || records[0].Offset != 5 {
+		t.Errorf("Expected the retained window to start at offset 5, got %v, %v", records, err)
+	}
+	if revision, _ := ur.Revision(ctx); revision != 6 {
+		t.Errorf("Expected revision 6 after compaction, got %d", revision)
+	}
+}
+
+func sampleCount(t *testing.T, h prometheus.Histogram) uint64 {
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatalf("Failed to read histogram: %v", err)
+	}
+
+	return m.GetHistogram().GetSampleCount()
+}
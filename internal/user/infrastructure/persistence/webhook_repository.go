@@ -0,0 +1,226 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"userCRUD/internal/user/domain/model"
+)
+
+var (
+	ErrWebhookNotFound    = errors.New("webhook with the ID not found")
+	ErrDeadLetterNotFound = errors.New("dead letter with the ID not found")
+	ErrDeliveryNotFound   = errors.New("webhook delivery with the ID not found")
+)
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	AddDeadLetter(ctx context.Context, deadLetter *model.DeadLetter) error
+	ListDeadLetters(ctx context.Context, webhookID string) ([]*model.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id string) (*model.DeadLetter, error)
+	UpdateDeadLetter(ctx context.Context, deadLetter *model.DeadLetter) error
+	DeleteDeadLetter(ctx context.Context, id string) error
+	AddDeliveries(ctx context.Context, deliveries []*model.Delivery) error
+	ListDeliveries(ctx context.Context) ([]*model.Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery *model.Delivery) error
+	DeleteDelivery(ctx context.Context, id string) error
+}
+
+type WebhookRepositoryMemory struct {
+	sync.RWMutex
+	webhooks    []*model.Webhook
+	deadLetters []*model.DeadLetter
+	deliveries  []*model.Delivery
+}
+
+func NewWebhookRepositoryMemory() *WebhookRepositoryMemory {
+	return &WebhookRepositoryMemory{}
+}
+
+func (r *WebhookRepositoryMemory) CreateWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.webhooks = append(r.webhooks, webhook)
+
+	return webhook, nil
+}
+
+func (r *WebhookRepositoryMemory) GetWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, w := range r.webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+
+	return nil, ErrWebhookNotFound
+}
+
+func (r *WebhookRepositoryMemory) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return append([]*model.Webhook(nil), r.webhooks...), nil
+}
+
+func (r *WebhookRepositoryMemory) DeleteWebhook(ctx context.Context, id string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for i, w := range r.webhooks {
+		if w.ID == id {
+			r.webhooks = append(r.webhooks[:i], r.webhooks[i+1:]...)
+
+			deliveries := r.deliveries[:0]
+			for _, d := range r.deliveries {
+				if d.WebhookID != id {
+					deliveries = append(deliveries, d)
+				}
+			}
+			r.deliveries = deliveries
+
+			return nil
+		}
+	}
+
+	return ErrWebhookNotFound
+}
+
+func (r *WebhookRepositoryMemory) AddDeadLetter(ctx context.Context, deadLetter *model.DeadLetter) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.deadLetters = append(r.deadLetters, deadLetter)
+
+	return nil
+}
+
+func (r *WebhookRepositoryMemory) ListDeadLetters(ctx context.Context, webhookID string) ([]*model.DeadLetter, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	deadLetters := make([]*model.DeadLetter, 0)
+	for _, d := range r.deadLetters {
+		if webhookID == "" || d.WebhookID == webhookID {
+			deadLetters = append(deadLetters, d)
+		}
+	}
+
+	return deadLetters, nil
+}
+
+func (r *WebhookRepositoryMemory) GetDeadLetter(ctx context.Context, id string) (*model.DeadLetter, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, d := range r.deadLetters {
+		if d.ID == id {
+			deadLetter := *d
+			return &deadLetter, nil
+		}
+	}
+
+	return nil, ErrDeadLetterNotFound
+}
+
+func (r *WebhookRepositoryMemory) UpdateDeadLetter(ctx context.Context, deadLetter *model.DeadLetter) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for i, d := range r.deadLetters {
+		if d.ID == deadLetter.ID {
+			r.deadLetters[i] = deadLetter
+			return nil
+		}
+	}
+
+	return ErrDeadLetterNotFound
+}
+
+func (r *WebhookRepositoryMemory) DeleteDeadLetter(ctx context.Context, id string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for i, d := range r.deadLetters {
+		if d.ID == id {
+			r.deadLetters = append(r.deadLetters[:i], r.deadLetters[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrDeadLetterNotFound
+}
+
+func (r *WebhookRepositoryMemory) AddDeliveries(ctx context.Context, deliveries []*model.Delivery) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, delivery := range deliveries {
+		if r.hasDelivery(delivery.WebhookID, delivery.EventID) {
+			continue
+		}
+
+		stored := *delivery
+		r.deliveries = append(r.deliveries, &stored)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepositoryMemory) ListDeliveries(ctx context.Context) ([]*model.Delivery, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	deliveries := make([]*model.Delivery, len(r.deliveries))
+	for i, d := range r.deliveries {
+		delivery := *d
+		deliveries[i] = &delivery
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepositoryMemory) UpdateDelivery(ctx context.Context, delivery *model.Delivery) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for i, d := range r.deliveries {
+		if d.ID == delivery.ID {
+			stored := *delivery
+			r.deliveries[i] = &stored
+			return nil
+		}
+	}
+
+	return ErrDeliveryNotFound
+}
+
+func (r *WebhookRepositoryMemory) DeleteDelivery(ctx context.Context, id string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for i, d := range r.deliveries {
+		if d.ID == id {
+			r.deliveries = append(r.deliveries[:i], r.deliveries[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrDeliveryNotFound
+}
+
+func (r *WebhookRepositoryMemory) hasDelivery(webhookID, eventID string) bool {
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID && d.EventID == eventID {
+			return true
+		}
+	}
+
+	return false
+}
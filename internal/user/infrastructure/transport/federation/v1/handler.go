@@ -0,0 +1,316 @@
+package v1
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strings"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/federation"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const (
+	Prefix = "/federation"
+
+	loginAction    = "login"
+	callbackAction = "callback"
+
+	stateCookie = "federation_state"
+	loginTTL    = 10 * time.Minute
+)
+
+const (
+	ErrorInvalidRequest         = "invalid_request"
+	ErrorAccessDenied           = "access_denied"
+	ErrorAccountConflict        = "account_conflict"
+	ErrorInvalidProfile         = "invalid_profile"
+	ErrorServerError            = "server_error"
+	ErrorTemporarilyUnavailable = "temporarily_unavailable"
+)
+
+type Handler struct {
+	fc        *command.Federation
+	providers map[string]*federation.Provider
+	l         deps.Logger
+	secure    bool
+	aead      cipher.AEAD
+}
+
+type pendingLogin struct {
+	Provider  string    `json:"provider"`
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type sessionResponse struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      sessionUser `json:"user"`
+}
+
+type sessionUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Admin    bool   `json:"admin"`
+}
+
+type federationError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func NewHandler(fc *command.Federation, providers []*federation.Provider, l deps.Logger, baseURL string) (*Handler, error) {
+	byName := make(map[string]*federation.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		fc:        fc,
+		providers: byName,
+		l:         l,
+		secure:    strings.HasPrefix(baseURL, "https://"),
+		aead:      aead,
+	}, nil
+}
+
+func CallbackURL(baseURL, provider string) string {
+	return strings.TrimSuffix(baseURL, "/") + Prefix + "/" + provider + "/" + callbackAction
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, Prefix+"/"), "/")
+	provider, ok := h.providers[name]
+	if !ok || (action != loginAction && action != callbackAction) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+		return
+	}
+
+	if action == loginAction {
+		h.login(w, r, provider)
+		return
+	}
+	h.callback(w, r, provider)
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request, provider *federation.Provider) {
+	ctx := requestContext(r)
+
+	state, err := newRandomValue()
+	if err != nil {
+		h.l.Error(ctx, "Federated login failed", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+		return
+	}
+	nonce, err := newRandomValue()
+	if err != nil {
+		h.l.Error(ctx, "Federated login failed", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		h.l.Error(ctx, "Federated login failed", "provider", provider.Name(), "error", err)
+		writeError(w, http.StatusBadGateway, ErrorTemporarilyUnavailable, "identity provider is unavailable")
+		return
+	}
+
+	sealed, err := h.seal(&pendingLogin{
+		Provider:  provider.Name(),
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: time.Now().Add(loginTTL),
+	})
+	if err != nil {
+		h.l.Error(ctx, "Federated login failed", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+		return
+	}
+
+	http.SetCookie(w, h.stateCookie(provider.Name(), sealed, int(loginTTL/time.Second)))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *Handler) callback(w http.ResponseWriter, r *http.Request, provider *federation.Provider) {
+	ctx := requestContext(r)
+	query := r.URL.Query()
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(stateCookie)
+	if state == "" || err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "login state is missing or does not match")
+		return
+	}
+	http.SetCookie(w, h.stateCookie(provider.Name(), "", -1))
+
+	pending := h.open(cookie.Value, provider.Name())
+	if pending == nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "login request expired, start over")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(pending.State), []byte(state)) != 1 {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "login state is missing or does not match")
+		return
+	}
+
+	if upstream := query.Get("error"); upstream != "" {
+		writeError(w, http.StatusUnauthorized, ErrorAccessDenied, strings.TrimSpace(upstream+" "+query.Get("error_description")))
+		return
+	}
+
+	login, err := provider.Exchange(ctx, query.Get("code"), pending.Verifier, pending.Nonce)
+	if err != nil {
+		h.l.Info(ctx, "Federated login rejected", "provider", provider.Name(), "error", err)
+		if errors.Is(err, federation.ErrProviderUnavailable) {
+			writeError(w, http.StatusBadGateway, ErrorTemporarilyUnavailable, "identity provider is unavailable")
+			return
+		}
+		writeError(w, http.StatusUnauthorized, ErrorAccessDenied, "identity provider response could not be verified")
+		return
+	}
+
+	user, token, err := h.fc.SignIn(ctx, login)
+	if err != nil {
+		h.writeSignInError(ctx, w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &sessionResponse{
+		Token:     token.Value,
+		ExpiresAt: token.ExpiresAt,
+		User: sessionUser{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Admin:    user.Admin,
+		},
+	})
+}
+
+func (h *Handler) writeSignInError(ctx context.Context, w http.ResponseWriter, err error) {
+	var invalid validator.ValidationErrors
+
+	switch {
+	case errors.Is(err, command.ErrFederatedUserUnknown):
+		writeError(w, http.StatusForbidden, ErrorAccessDenied, err.Error())
+	case errors.Is(err, persistence.ErrUsernameTaken), errors.Is(err, persistence.ErrEmailTaken),
+		errors.Is(err, persistence.ErrIdentityAlreadyUsed):
+		writeError(w, http.StatusConflict, ErrorAccountConflict, err.Error())
+	case errors.As(err, &invalid):
+		writeError(w, http.StatusUnprocessableEntity, ErrorInvalidProfile, err.Error())
+	case errors.Is(err, password.ErrHashingOverloaded):
+		writeError(w, http.StatusServiceUnavailable, ErrorTemporarilyUnavailable, err.Error())
+	default:
+		h.l.Error(ctx, "Federated sign-in failed", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+	}
+}
+
+func (h *Handler) seal(login *pendingLogin) (string, error) {
+	plaintext, err := json.Marshal(login)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, h.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h.aead.Seal(nonce, nonce, plaintext, []byte(login.Provider))), nil
+}
+
+func (h *Handler) open(value, provider string) *pendingLogin {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < h.aead.NonceSize() {
+		return nil
+	}
+
+	nonce, ciphertext := sealed[:h.aead.NonceSize()], sealed[h.aead.NonceSize():]
+	plaintext, err := h.aead.Open(nil, nonce, ciphertext, []byte(provider))
+	if err != nil {
+		return nil
+	}
+
+	login := &pendingLogin{}
+	if err := json.Unmarshal(plaintext, login); err != nil || login.Provider != provider || time.Now().After(login.ExpiresAt) {
+		return nil
+	}
+
+	return login
+}
+
+func (h *Handler) stateCookie(provider, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     Prefix + "/" + provider + "/",
+		MaxAge:   maxAge,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func newRandomValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func requestContext(r *http.Request) context.Context {
+	return tracing.WithTraceID(r.Context())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, &federationError{Code: code, Description: description})
+}
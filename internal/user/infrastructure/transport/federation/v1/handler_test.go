@@ -0,0 +1,395 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/federation"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const (
+	testClientID     = "usercrud"
+	testClientSecret = "s3cret"
+)
+
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu             sync.Mutex
+	subject        string
+	claims         map[string]interface{}
+	signingKey     *rsa.PrivateKey
+	audience       string
+	nonce          string
+	authorizeError string
+	codes          map[string]url.Values
+}
+
+type federationTest struct {
+	server *httptest.Server
+	idp    *fakeIdP
+	ur     persistence.UserRepository
+	tr     persistence.TokenRepository
+	uc     *command.User
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	idp := &fakeIdP{key: key, codes: make(map[string]url.Values)}
+	idp.reset()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (i *fakeIdP) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.subject = "corp-1"
+	i.claims = map[string]interface{}{
+		"preferred_username": "corpUser",
+		"email":              "corp.user@example.com",
+		"email_verified":     true,
+		"groups":             []string{"staff", "user-admins"},
+	}
+	i.signingKey = i.key
+	i.audience = testClientID
+	i.nonce = ""
+	i.authorizeError = ""
+}
+
+func (i *fakeIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                i.server.URL,
+		"authorization_endpoint":                i.server.URL + "/authorize",
+		"token_endpoint":                        i.server.URL + "/token",
+		"jwks_uri":                              i.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *fakeIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := url.Values{"state": {query.Get("state")}}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	switch {
+	case i.authorizeError != "":
+		values.Set("error", i.authorizeError)
+	case query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256":
+		values.Set("error", "invalid_request")
+	default:
+		code := base64.RawURLEncoding.EncodeToString([]byte(time.Now().String()))
+		i.codes[code] = query
+		values.Set("code", code)
+	}
+
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (i *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != testClientID || secret != testClientSecret || r.ParseForm() != nil {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	request, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != request.Get("code_challenge") {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	nonce := request.Get("nonce")
+	if i.nonce != "" {
+		nonce = i.nonce
+	}
+	claims := map[string]interface{}{
+		"iss":   i.server.URL,
+		"sub":   i.subject,
+		"aud":   i.audience,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": nonce,
+	}
+	for k, v := range i.claims {
+		claims[k] = v
+	}
+
+	signer, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: i.signingKey, KeyID: "k1"}}, nil)
+	idToken, _ := jwt.Signed(signer).Claims(claims).CompactSerialize()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "upstream-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func (i *fakeIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &i.key.PublicKey, KeyID: "k1", Algorithm: "RS256", Use: "sig"},
+	}})
+}
+
+func newFederationTest(t *testing.T) *federationTest {
+	l := &deps.MockLogger{}
+	v := deps.NewGoPlaygroundValidator()
+	h := password.NewPool(bcrypt.MinCost, 4, 64)
+	ur := persistence.NewUserRepositoryMemory(l, h, 1000)
+	tr := persistence.NewTokenRepositoryMemory()
+	al := auditpersistence.NewAuditLogMemory()
+	idp := newFakeIdP(t)
+
+	configs := []*federation.ProviderConfig{
+		{
+			Name:          "corp",
+			Issuer:        idp.server.URL,
+			ClientID:      testClientID,
+			ClientSecret:  testClientSecret,
+			LinkByEmail:   true,
+			AutoProvision: true,
+			Claims:        federation.ClaimMapping{Admin: "groups", AdminValues: []string{"user-admins"}},
+		},
+		{
+			Name:         "partner",
+			Issuer:       idp.server.URL,
+			ClientID:     testClientID,
+			ClientSecret: testClientSecret,
+		},
+	}
+
+	server := httptest.NewServer(nil)
+	t.Cleanup(server.Close)
+
+	providers := make([]*federation.Provider, len(configs))
+	for i, c := range configs {
+		providers[i] = federation.NewProvider(c, CallbackURL(server.URL, c.Name))
+	}
+	uc := command.NewUserCommand(ur, v, h, command.NewPasswordPolicy(5, 0), al)
+	fc := command.NewFederationCommand(persistence.NewIdentityRepositoryMemory(), ur, tr, al, v, uc, l, federation.Policies(configs), time.Hour)
+	handler, err := NewHandler(fc, providers, l, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	server.Config.Handler = handler
+
+	return &federationTest{
+		server: server,
+		idp:    idp,
+		ur:     ur,
+		tr:     tr,
+		uc:     uc,
+	}
+}
+
+func (ft *federationTest) login(t *testing.T, provider string) (int, map[string]interface{}) {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(ft.server.URL + Prefix + "/" + provider + "/login")
+	if err != nil {
+		t.Fatalf("Login request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	return resp.StatusCode, body
+}
+
+func TestFederatedLoginProvisionsAndLinks(t *testing.T) {
+	ft := newFederationTest(t)
+
+	status, body := ft.login(t, "corp")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %v", status, body)
+	}
+
+	user, _ := body["user"].(map[string]interface{})
+	if user["username"] != "corpUser" || user["email"] != "corp.user@example.com" || user["admin"] != true {
+		t.Errorf("Unexpected provisioned user: %v", user)
+	}
+	token, err := ft.tr.GetToken(context.Background(), body["token"].(string))
+	if err != nil || token.UserID != user["id"] {
+		t.Errorf("Expected a session token for the provisioned user, got %+v %v", token, err)
+	}
+
+	ft.idp.mu.Lock()
+	ft.idp.claims["preferred_username"] = "renamedUser"
+	ft.idp.mu.Unlock()
+	if _, again := ft.login(t, "corp"); again["user"].(map[string]interface{})["id"] != user["id"] {
+		t.Errorf("Expected the linked account on repeated login, got %v", again)
+	}
+
+	admin, _ := ft.ur.GetUserByUsername(context.Background(), "admin")
+	local, err := ft.uc.CreateUser(context.WithValue(context.Background(), constants.UserContextKey, admin), &model.User{
+		Username: "localUser",
+		Email:    "local.user@example.com",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	ft.idp.mu.Lock()
+	ft.idp.subject = "corp-2"
+	ft.idp.claims = map[string]interface{}{"email": "local.user@example.com", "email_verified": "true"}
+	ft.idp.mu.Unlock()
+	status, body = ft.login(t, "corp")
+	if status != http.StatusOK || body["user"].(map[string]interface{})["id"] != local.ID {
+		t.Errorf("Expected verified email to link the local account, got %d %v", status, body)
+	}
+}
+
+func TestFederatedLoginRejections(t *testing.T) {
+	ft := newFederationTest(t)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name     string
+		provider string
+		setup    func(i *fakeIdP)
+		status   int
+		code     string
+	}{
+		{"upstream error", "corp", func(i *fakeIdP) { i.authorizeError = "access_denied" }, http.StatusUnauthorized, ErrorAccessDenied},
+		{"nonce mismatch", "corp", func(i *fakeIdP) { i.nonce = "replayed" }, http.StatusUnauthorized, ErrorAccessDenied},
+		{"unknown signing key", "corp", func(i *fakeIdP) { i.signingKey = otherKey }, http.StatusUnauthorized, ErrorAccessDenied},
+		{"wrong audience", "corp", func(i *fakeIdP) { i.audience = "someone-else" }, http.StatusUnauthorized, ErrorAccessDenied},
+		{"no linked account", "partner", func(i *fakeIdP) {}, http.StatusForbidden, ErrorAccessDenied},
+		{"invalid profile", "corp", func(i *fakeIdP) { i.claims = map[string]interface{}{"preferred_username": "x"} }, http.StatusUnprocessableEntity, ErrorInvalidProfile},
+		{"username conflict", "corp", func(i *fakeIdP) {
+			i.claims["preferred_username"] = "admin1"
+			i.claims["email"] = "admin@gmail.com"
+			i.claims["email_verified"] = false
+		}, http.StatusConflict, ErrorAccountConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft.idp.reset()
+			ft.idp.mu.Lock()
+			ft.idp.subject = tt.name
+			tt.setup(ft.idp)
+			ft.idp.mu.Unlock()
+
+			status, body := ft.login(t, tt.provider)
+			if status != tt.status || body["error"] != tt.code {
+				t.Errorf("Expected %d %s, got %d %v", tt.status, tt.code, status, body)
+			}
+		})
+	}
+}
+
+func TestFederatedCallbackState(t *testing.T) {
+	ft := newFederationTest(t)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Host == ft.server.Listener.Addr().String() {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+
+	resp, err := client.Get(ft.server.URL + Prefix + "/corp/login")
+	if err != nil {
+		t.Fatalf("Login request failed: %v", err)
+	}
+	resp.Body.Close()
+	callback := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusFound || callback == "" {
+		t.Fatalf("Expected a redirect to the callback, got %d", resp.StatusCode)
+	}
+
+	u, _ := url.Parse(callback)
+	var sealed *http.Cookie
+	for _, c := range jar.Cookies(u) {
+		if c.Name == stateCookie {
+			sealed = c
+		}
+	}
+	if sealed == nil || strings.Contains(sealed.Value, u.Query().Get("state")) {
+		t.Fatalf("Expected the login state to be sealed in the cookie, got %v", sealed)
+	}
+
+	if resp, _ := http.Get(callback); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without the state cookie, got %d", resp.StatusCode)
+	}
+	forged, _ := http.NewRequest(http.MethodGet, callback, nil)
+	forged.AddCookie(&http.Cookie{Name: stateCookie, Value: u.Query().Get("state")})
+	if resp, _ := http.DefaultClient.Do(forged); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a forged state cookie, got %d", resp.StatusCode)
+	}
+	otherProvider, _ := http.NewRequest(http.MethodGet, strings.Replace(callback, "/corp/", "/partner/", 1), nil)
+	otherProvider.AddCookie(sealed)
+	if resp, _ := http.DefaultClient.Do(otherProvider); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a state cookie issued for another provider, got %d", resp.StatusCode)
+	}
+	if resp, _ := client.Get(callback); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with the state cookie, got %d", resp.StatusCode)
+	}
+
+	replay, _ := http.NewRequest(http.MethodGet, callback, nil)
+	replay.AddCookie(sealed)
+	if resp, _ := http.DefaultClient.Do(replay); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the replayed authorization code to be rejected, got %d", resp.StatusCode)
+	}
+
+	if resp, _ := http.Get(ft.server.URL + Prefix + "/unknown/login"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown provider, got %d", resp.StatusCode)
+	}
+	if resp, _ := http.Post(ft.server.URL+Prefix+"/corp/login", "text/plain", nil); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", resp.StatusCode)
+	}
+}
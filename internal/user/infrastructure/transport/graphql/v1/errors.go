@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const (
+	CodeInvalidArgument    = "INVALID_ARGUMENT"
+	CodeNotFound           = "NOT_FOUND"
+	CodePermissionDenied   = "PERMISSION_DENIED"
+	CodeFailedPrecondition = "FAILED_PRECONDITION"
+	CodeResourceExhausted  = "RESOURCE_EXHAUSTED"
+	CodeCanceled           = "CANCELED"
+	CodeDeadlineExceeded   = "DEADLINE_EXCEEDED"
+)
+
+type resolverError struct {
+	err  error
+	code string
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.code,
+	}
+}
+
+func handleGraphQLError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &resolverError{err: err, code: errorCode(err)}
+}
+
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	case errors.Is(err, password.ErrHashingOverloaded), errors.Is(err, ratelimit.ErrLimited):
+		return CodeResourceExhausted
+	case errors.Is(err, persistence.ErrUserNotFound):
+		return CodeNotFound
+	case errors.Is(err, command.ErrPasswordRotation):
+		return CodeFailedPrecondition
+	case errors.Is(err, command.ErrNotEnoughPermissions), errors.Is(err, command.ErrAuthFailed):
+		return CodePermissionDenied
+	default:
+		return CodeInvalidArgument
+	}
+}
+
+func toQueryErrors(err error) []*gqlerrors.QueryError {
+	return []*gqlerrors.QueryError{{
+		Message:       err.Error(),
+		ResolverError: err,
+		Extensions:    map[string]interface{}{"code": errorCode(err)},
+	}}
+}
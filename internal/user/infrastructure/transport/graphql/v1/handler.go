@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"github.com/graph-gophers/graphql-go"
+	"net/http"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/auth"
+)
+
+const (
+	Path                = "/graphql"
+	maxQueryDepth       = 10
+	maxRequestBodyBytes = 1 << 20
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Handler struct {
+	schema *graphql.Schema
+	uc     *command.User
+	a      *auth.Authenticator
+	l      deps.Logger
+}
+
+func NewHandler(uc *command.User, a *auth.Authenticator, l deps.Logger) (*Handler, error) {
+	schema, err := graphql.ParseSchema(schemaSource, &resolver{uc: uc}, graphql.MaxDepth(maxQueryDepth))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		schema: schema,
+		uc:     uc,
+		a:      a,
+		l:      l,
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, err := h.authenticate(r)
+	if err != nil {
+		writeJSON(w, &graphql.Response{Errors: toQueryErrors(err)})
+		return
+	}
+
+	writeJSON(w, h.schema.Exec(withLoaders(ctx, h.uc), req.Query, req.OperationName, req.Variables))
+}
+
+func (h *Handler) authenticate(r *http.Request) (context.Context, error) {
+	ctx := tracing.WithTraceID(r.Context())
+	ctx, err := h.a.Authenticate(ctx, r.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.a.CheckPasswordRotation(ctx); err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+func writeJSON(w http.ResponseWriter, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
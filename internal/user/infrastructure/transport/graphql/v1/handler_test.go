@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const adminAuth = "Basic YWRtaW46YWRtaW4="
+
+type countingRepository struct {
+	persistence.UserRepository
+	byIDCalls int32
+}
+
+func (r *countingRepository) GetUsersByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	atomic.AddInt32(&r.byIDCalls, 1)
+	return r.UserRepository.GetUsersByIDs(ctx, ids)
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestHandler(t *testing.T) (*Handler, *countingRepository) {
+	l := &deps.MockLogger{}
+	h := password.NewPool(bcrypt.MinCost, 4, 64)
+	ur := &countingRepository{UserRepository: persistence.NewUserRepositoryMemory(l, h, 1000)}
+	pp := command.NewPasswordPolicy(5, 0)
+	uc := command.NewUserCommand(ur, deps.NewGoPlaygroundValidator(), h, pp, auditpersistence.NewAuditLogMemory())
+
+	handler, err := NewHandler(uc, auth.NewAuthenticator(ur, persistence.NewTokenRepositoryMemory(), pp), l)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	return handler, ur
+}
+
+func execute(t *testing.T, h *Handler, authHeader, query string, variables map[string]interface{}) *graphQLResponse {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(string(body)))
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp graphQLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	return &resp
+}
+
+func TestGraphQLMutationsAndQueries(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := execute(t, h, adminAuth, `mutation($input: NewUserInput!) { createUser(input: $input) { id username } }`,
+		map[string]interface{}{"input": map[string]interface{}{"email": "alice@example.com", "username": "alice", "password": "secret"}})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", resp.Errors)
+	}
+
+	var created struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	json.Unmarshal(resp.Data["createUser"], &created)
+	if created.ID == "" || created.Username != "alice" {
+		t.Fatalf("Unexpected created user: %+v", created)
+	}
+
+	resp = execute(t, h, adminAuth, `query($id: ID!) { user(id: $id) { email } userByUsername(username: "alice") { id } users(pageSize: 50) { username } }`,
+		map[string]interface{}{"id": created.ID})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", resp.Errors)
+	}
+	if got := string(resp.Data["user"]); got != `{"email":"alice@example.com"}` {
+		t.Errorf("Unexpected user: %s", got)
+	}
+	if got := string(resp.Data["userByUsername"]); !strings.Contains(got, created.ID) {
+		t.Errorf("Unexpected userByUsername: %s", got)
+	}
+	if got := string(resp.Data["users"]); !strings.Contains(got, "alice") || !strings.Contains(got, "admin") {
+		t.Errorf("Unexpected users: %s", got)
+	}
+
+	resp = execute(t, h, adminAuth, `mutation($id: ID!) { deleteUser(id: $id) }`, map[string]interface{}{"id": created.ID})
+	if len(resp.Errors) != 0 || string(resp.Data["deleteUser"]) != "true" {
+		t.Fatalf("Unexpected delete result: %v %s", resp.Errors, resp.Data["deleteUser"])
+	}
+
+	resp = execute(t, h, adminAuth, `query($id: ID!) { user(id: $id) { id } }`, map[string]interface{}{"id": created.ID})
+	if len(resp.Errors) != 0 || string(resp.Data["user"]) != "null" {
+		t.Errorf("Expected deleted user to resolve to null, got %v %s", resp.Errors, resp.Data["user"])
+	}
+}
+
+func TestGraphQLAuthorizationAndValidation(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := execute(t, h, "", `mutation { createUser(input: {email: "bob@example.com", username: "bobby", password: "secret"}) { id } }`, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != CodePermissionDenied {
+		t.Errorf("Expected PERMISSION_DENIED for anonymous mutation, got %+v", resp.Errors)
+	}
+
+	resp = execute(t, h, adminAuth, `mutation { createUser(input: {email: "not-an-email", username: "bobby", password: "secret"}) { id } }`, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != CodeInvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for invalid email, got %+v", resp.Errors)
+	}
+
+	resp = execute(t, h, adminAuth, `{ user(id: "not-a-uuid") { id } }`, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != CodeInvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for malformed id, got %+v", resp.Errors)
+	}
+}
+
+func TestGraphQLBatchesLookups(t *testing.T) {
+	h, ur := newTestHandler(t)
+
+	admin, err := ur.GetUserByUsername(context.Background(), "admin")
+	if err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+
+	resp := execute(t, h, adminAuth, `query($id: ID!, $missing: ID!) {
+		a: user(id: $id) { username }
+		b: user(id: $id) { email }
+		c: user(id: $missing) { username }
+	}`, map[string]interface{}{"id": admin.ID, "missing": "1b4e28ba-2fa1-41d2-883f-0016d3cca427"})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", resp.Errors)
+	}
+	if string(resp.Data["a"]) != `{"username":"admin"}` || string(resp.Data["c"]) != "null" {
+		t.Errorf("Unexpected data: %s %s", resp.Data["a"], resp.Data["c"])
+	}
+
+	if calls := atomic.LoadInt32(&ur.byIDCalls); calls != 1 {
+		t.Errorf("Expected lookups to be batched into 1 repository call, got %d", calls)
+	}
+}
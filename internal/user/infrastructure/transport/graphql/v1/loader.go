@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"context"
+	"github.com/graph-gophers/dataloader/v7"
+	"time"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	loadersContextKey = "graphqlLoaders"
+	loaderWait        = 2 * time.Millisecond
+	loaderBatchSize   = 100
+)
+
+type loaders struct {
+	byID       *dataloader.Loader[string, *model.User]
+	byUsername *dataloader.Loader[string, *model.User]
+}
+
+func newLoaders(uc *command.User) *loaders {
+	return &loaders{
+		byID:       newUserLoader(uc.GetUsersByIDs),
+		byUsername: newUserLoader(uc.GetUsersByUsernames),
+	}
+}
+
+func newUserLoader(fetch func(context.Context, []string) ([]*model.User, []error)) *dataloader.Loader[string, *model.User] {
+	batch := func(ctx context.Context, keys []string) []*dataloader.Result[*model.User] {
+		users, errs := fetch(ctx, keys)
+
+		results := make([]*dataloader.Result[*model.User], len(keys))
+		for i := range keys {
+			results[i] = &dataloader.Result[*model.User]{Data: users[i], Error: errs[i]}
+		}
+
+		return results
+	}
+
+	return dataloader.NewBatchedLoader(batch,
+		dataloader.WithWait[string, *model.User](loaderWait),
+		dataloader.WithBatchCapacity[string, *model.User](loaderBatchSize),
+	)
+}
+
+func withLoaders(ctx context.Context, uc *command.User) context.Context {
+	return context.WithValue(ctx, loadersContextKey, newLoaders(uc))
+}
+
+func loadersFromContext(ctx context.Context) *loaders {
+	return ctx.Value(loadersContextKey).(*loaders)
+}
@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"userCRUD/internal/common"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+type resolver struct {
+	uc *command.User
+}
+
+type userResolver struct {
+	user *model.User
+}
+
+type newUserInput struct {
+	Email              string
+	Username           string
+	Password           string
+	Admin              bool
+	Permissions        []string
+	MustChangePassword bool
+}
+
+type updateUserInput struct {
+	ID                 graphql.ID
+	Email              string
+	Username           string
+	Password           *string
+	Admin              bool
+	Permissions        []string
+	MustChangePassword bool
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	user, err := loadersFromContext(ctx).byID.Load(ctx, string(args.ID))()
+	return toUserResolver(user, err)
+}
+
+func (r *resolver) UserByUsername(ctx context.Context, args struct{ Username string }) (*userResolver, error) {
+	user, err := loadersFromContext(ctx).byUsername.Load(ctx, args.Username)()
+	return toUserResolver(user, err)
+}
+
+func (r *resolver) Users(ctx context.Context, args struct {
+	Page     int32
+	PageSize int32
+}) ([]*userResolver, error) {
+	if args.Page < 0 || args.PageSize < 0 {
+		return nil, handleGraphQLError(persistence.ErrPageOutOfRange)
+	}
+
+	users, err := r.uc.GetUsers(ctx, &common.Pagination{
+		Page:     uint32(args.Page),
+		PageSize: uint32(args.PageSize),
+	})
+	if errors.Is(err, persistence.ErrPageOutOfRange) {
+		return []*userResolver{}, nil
+	}
+	if err != nil {
+		return nil, handleGraphQLError(err)
+	}
+
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		resolvers[i] = &userResolver{user: user}
+	}
+
+	return resolvers, nil
+}
+
+func (r *resolver) CreateUser(ctx context.Context, args struct{ Input newUserInput }) (*userResolver, error) {
+	user, err := r.uc.CreateUser(ctx, &model.User{
+		Email:              args.Input.Email,
+		Username:           args.Input.Username,
+		Password:           args.Input.Password,
+		Admin:              args.Input.Admin,
+		Permissions:        toPermissions(args.Input.Permissions),
+		MustChangePassword: args.Input.MustChangePassword,
+	})
+	if err != nil {
+		return nil, handleGraphQLError(err)
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+func (r *resolver) UpdateUser(ctx context.Context, args struct{ Input updateUserInput }) (*userResolver, error) {
+	update := &model.UpdateUser{
+		ID:                 string(args.Input.ID),
+		Email:              args.Input.Email,
+		Username:           args.Input.Username,
+		Admin:              args.Input.Admin,
+		Permissions:        toPermissions(args.Input.Permissions),
+		MustChangePassword: args.Input.MustChangePassword,
+	}
+	if args.Input.Password != nil {
+		update.Password = *args.Input.Password
+	}
+
+	user, err := r.uc.UpdateUser(ctx, update)
+	if err != nil {
+		return nil, handleGraphQLError(err)
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+func (r *resolver) DeleteUser(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := r.uc.DeleteUser(ctx, &model.UserByID{ID: string(args.ID)}); err != nil {
+		return false, handleGraphQLError(err)
+	}
+
+	return true, nil
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(u.user.ID)
+}
+
+func (u *userResolver) Email() string {
+	return u.user.Email
+}
+
+func (u *userResolver) Username() string {
+	return u.user.Username
+}
+
+func (u *userResolver) Admin() bool {
+	return u.user.Admin
+}
+
+func (u *userResolver) Permissions() []string {
+	permissions := make([]string, len(u.user.Permissions))
+	for i, p := range u.user.Permissions {
+		permissions[i] = string(p)
+	}
+
+	return permissions
+}
+
+func (u *userResolver) MustChangePassword() bool {
+	return u.user.MustChangePassword
+}
+
+func (u *userResolver) PasswordChangedAt() *graphql.Time {
+	if u.user.PasswordChangedAt.IsZero() {
+		return nil
+	}
+
+	return &graphql.Time{Time: u.user.PasswordChangedAt}
+}
+
+func toUserResolver(user *model.User, err error) (*userResolver, error) {
+	if errors.Is(err, persistence.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, handleGraphQLError(err)
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+func toPermissions(permissions []string) []model.Permission {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	result := make([]model.Permission, len(permissions))
+	for i, p := range permissions {
+		result[i] = model.Permission(p)
+	}
+
+	return result
+}
@@ -0,0 +1,271 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"userCRUD/api/openapi"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/requestid"
+	grpcv1 "userCRUD/internal/user/infrastructure/transport/proto/v1"
+)
+
+const (
+	usersPath          = "/v1/users"
+	userPathPrefix     = usersPath + "/"
+	byUsernamePrefix   = usersPath + ":byUsername/"
+	changePasswordPath = usersPath + ":changePassword"
+
+	maxRequestBodyBytes = 1 << 20
+)
+
+type Gateway struct {
+	client pb.UserServiceClient
+}
+
+func NewGateway(client pb.UserServiceClient) *Gateway {
+	return &Gateway{
+		client: client,
+	}
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	switch {
+	case path == OpenAPIPath:
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapi.Spec)
+	case path == usersPath:
+		switch r.Method {
+		case http.MethodGet:
+			g.getUsers(w, r)
+		case http.MethodPost:
+			g.newUser(w, r)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case path == changePasswordPath:
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		g.changePassword(w, r)
+	case strings.HasPrefix(path, byUsernamePrefix):
+		username := strings.TrimPrefix(path, byUsernamePrefix)
+		if username == "" || strings.Contains(username, "/") {
+			writeNotFound(w)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		g.getUserByUsername(w, r, username)
+	case strings.HasPrefix(path, userPathPrefix):
+		id := strings.TrimPrefix(path, userPathPrefix)
+		if id == "" || strings.Contains(id, "/") {
+			writeNotFound(w)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			g.getUserByID(w, r, id)
+		case http.MethodPut:
+			g.updateUser(w, r, id)
+		case http.MethodDelete:
+			g.deleteUser(w, r, id)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+		}
+	default:
+		writeNotFound(w)
+	}
+}
+
+func (g *Gateway) newUser(w http.ResponseWriter, r *http.Request) {
+	req := &pb.NewUserRequest{}
+	if err := readBody(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.NewUser(outgoingContext(r), req)
+	writeResponse(w, successStatus(pb.UserService_NewUser_FullMethodName), resp, err)
+}
+
+func (g *Gateway) getUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := queryUint32(r, "page", 1)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	pageSize, err := queryUint32(r, "page_size", 10)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.GetUsers(outgoingContext(r), &pb.GetUsersRequest{
+		Page:     page,
+		PageSize: pageSize,
+	})
+	writeResponse(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) getUserByID(w http.ResponseWriter, r *http.Request, id string) {
+	resp, err := g.client.GetUserByID(outgoingContext(r), &pb.GetUserByIDRequest{
+		Id: id,
+	})
+	writeResponse(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) getUserByUsername(w http.ResponseWriter, r *http.Request, username string) {
+	resp, err := g.client.GetUserByUsername(outgoingContext(r), &pb.GetUserByUsernameRequest{
+		Username: username,
+	})
+	writeResponse(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) updateUser(w http.ResponseWriter, r *http.Request, id string) {
+	req := &pb.UpdateUserRequest{}
+	if err := readBody(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Id != "" && req.Id != id {
+		writeError(w, status.Errorf(codes.InvalidArgument, "id in body does not match the path"))
+		return
+	}
+	req.Id = id
+
+	resp, err := g.client.UpdateUser(outgoingContext(r), req)
+	writeResponse(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) deleteUser(w http.ResponseWriter, r *http.Request, id string) {
+	resp, err := g.client.DeleteUser(outgoingContext(r), &pb.DeleteUserRequest{
+		Id: id,
+	})
+	writeResponse(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) changePassword(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ChangePasswordRequest{}
+	if err := readBody(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.ChangePassword(outgoingContext(r), req)
+	writeResponse(w, http.StatusOK, resp, err)
+}
+
+func outgoingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		md.Set(grpcv1.AuthHeader, auth)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		md.Set(grpcv1.ForwardedForHeader, host)
+	}
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		md.Set(grpcv1.IdempotencyKeyHeader, key)
+	}
+	if id := requestid.FromContext(r.Context()); id != "" {
+		md.Set(requestid.Header, id)
+	}
+
+	return metadata.NewOutgoingContext(r.Context(), md)
+}
+
+func readBody(r *http.Request, m proto.Message) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodyBytes+1))
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err)
+	}
+	if len(body) > maxRequestBodyBytes {
+		return status.Errorf(codes.InvalidArgument, "request body is too large")
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	if err := protojson.Unmarshal(body, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid JSON body: %v", err)
+	}
+
+	return nil
+}
+
+func queryUint32(r *http.Request, key string, fallback uint32) (uint32, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s: %q", key, value)
+	}
+
+	return uint32(parsed), nil
+}
+
+func writeResponse(w http.ResponseWriter, code int, resp proto.Message, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, code, resp)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			seconds := math.Max(1, math.Ceil(info.GetRetryDelay().AsDuration().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+		}
+	}
+
+	writeJSON(w, HTTPStatusFromCode(s.Code()), s.Proto())
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, status.Errorf(codes.NotFound, "no route for the requested path"))
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	s := status.New(codes.Unimplemented, "method not allowed")
+	writeJSON(w, http.StatusMethodNotAllowed, s.Proto())
+}
+
+func writeJSON(w http.ResponseWriter, code int, m proto.Message) {
+	body, err := protojson.Marshal(m)
+	if err != nil {
+		code = http.StatusInternalServerError
+		body = []byte(`{"code":13,"message":"failed to encode response"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
@@ -0,0 +1,220 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+)
+
+type stubUserService struct {
+	pb.UnimplementedUserServiceServer
+
+	md      metadata.MD
+	lastReq interface{}
+}
+
+func (s *stubUserService) NewUser(ctx context.Context, req *pb.NewUserRequest) (*pb.UserResponse, error) {
+	s.record(ctx, req)
+	return &pb.UserResponse{Id: "new-id", Username: req.Username}, nil
+}
+
+func (s *stubUserService) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
+	s.record(ctx, req)
+	return &pb.GetUsersResponse{}, nil
+}
+
+func (s *stubUserService) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*pb.UserResponse, error) {
+	s.record(ctx, req)
+	if req.Id == "missing" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &pb.UserResponse{Id: req.Id}, nil
+}
+
+func (s *stubUserService) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernameRequest) (*pb.UserResponse, error) {
+	s.record(ctx, req)
+	if req.Username == "throttled" {
+		st, _ := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)})
+		return nil, st.Err()
+	}
+	return &pb.UserResponse{Username: req.Username}, nil
+}
+
+func (s *stubUserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
+	s.record(ctx, req)
+	return &pb.UserResponse{Id: req.Id, Username: req.Username}, nil
+}
+
+func (s *stubUserService) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	s.record(ctx, req)
+	return nil, status.Error(codes.PermissionDenied, "permission denied")
+}
+
+func (s *stubUserService) record(ctx context.Context, req interface{}) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	s.lastReq = req
+}
+
+func newTestGateway(t *testing.T) (*Gateway, *stubUserService) {
+	stub := &stubUserService{}
+	server := grpc.NewServer()
+	pb.RegisterUserServiceServer(server, stub)
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewGateway(pb.NewUserServiceClient(conn)), stub
+}
+
+func serve(g *Gateway, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Basic YWRtaW46YWRtaW4=")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGatewayRoutes(t *testing.T) {
+	g, stub := newTestGateway(t)
+
+	rec := serve(g, http.MethodPost, "/v1/users", `{"username":"alice","password":"secret"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created["id"] != "new-id" || created["username"] != "alice" {
+		t.Errorf("Unexpected response body: %v", created)
+	}
+	if got := stub.md.Get("authorization"); len(got) != 1 || got[0] != "Basic YWRtaW46YWRtaW4=" {
+		t.Errorf("Expected authorization to be forwarded, got %v", got)
+	}
+	if got := stub.md.Get("x-forwarded-for"); len(got) != 1 || got[0] != "192.0.2.1" {
+		t.Errorf("Expected x-forwarded-for to carry the client address, got %v", got)
+	}
+
+	rec = serve(g, http.MethodGet, "/v1/users?page=2&page_size=5", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if req := stub.lastReq.(*pb.GetUsersRequest); req.Page != 2 || req.PageSize != 5 {
+		t.Errorf("Unexpected paging: %v", req)
+	}
+
+	rec = serve(g, http.MethodGet, "/v1/users:byUsername/bob", "")
+	if rec.Code != http.StatusOK || stub.lastReq.(*pb.GetUserByUsernameRequest).Username != "bob" {
+		t.Errorf("Unexpected byUsername result: %d %v", rec.Code, stub.lastReq)
+	}
+
+	rec = serve(g, http.MethodPut, "/v1/users/42", `{"username":"carol"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if req := stub.lastReq.(*pb.UpdateUserRequest); req.Id != "42" || req.Username != "carol" {
+		t.Errorf("Expected id from path, got %v", req)
+	}
+
+	rec = serve(g, http.MethodPatch, "/v1/users/42", `{"username":"carol"}`)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, PUT, DELETE" {
+		t.Errorf("Expected PATCH to be rejected as a partial update is not supported, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	rec = serve(g, http.MethodPut, "/v1/users/42", `{"id":"43"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for mismatched id, got %d", rec.Code)
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	g, _ := newTestGateway(t)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		status int
+		code   codes.Code
+	}{
+		{http.MethodGet, "/v1/users/missing", "", http.StatusNotFound, codes.NotFound},
+		{http.MethodDelete, "/v1/users/42", "", http.StatusForbidden, codes.PermissionDenied},
+		{http.MethodPost, "/v1/users", "{", http.StatusBadRequest, codes.InvalidArgument},
+		{http.MethodGet, "/v1/users?page=abc", "", http.StatusBadRequest, codes.InvalidArgument},
+		{http.MethodPost, "/v1/users:changePassword", `{}`, http.StatusNotImplemented, codes.Unimplemented},
+		{http.MethodGet, "/v1/unknown", "", http.StatusNotFound, codes.NotFound},
+		{http.MethodPost, "/v1/users/42", "", http.StatusMethodNotAllowed, codes.Unimplemented},
+	}
+
+	for _, tt := range tests {
+		rec := serve(g, tt.method, tt.target, tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.target, tt.status, rec.Code)
+		}
+
+		var body struct {
+			Code    codes.Code `json:"code"`
+			Message string     `json:"message"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s: failed to decode error body: %v", tt.method, tt.target, err)
+			continue
+		}
+		if body.Code != tt.code || body.Message == "" {
+			t.Errorf("%s %s: unexpected error body %s", tt.method, tt.target, rec.Body.String())
+		}
+	}
+}
+
+func TestGatewayRetryAfter(t *testing.T) {
+	g, _ := newTestGateway(t)
+
+	rec := serve(g, http.MethodGet, "/v1/users:byUsername/throttled", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Expected Retry-After 2, got %q", got)
+	}
+
+	rec = serve(g, http.MethodGet, "/v1/users/missing", "")
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Expected no Retry-After without RetryInfo, got %q", got)
+	}
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	if got := HTTPStatusFromCode(codes.ResourceExhausted); got != http.StatusTooManyRequests {
+		t.Errorf("Expected 429, got %d", got)
+	}
+	if got := HTTPStatusFromCode(codes.FailedPrecondition); got != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", got)
+	}
+	if got := HTTPStatusFromCode(codes.Code(42)); got != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for unknown code, got %d", got)
+	}
+}
@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"userCRUD/internal/common/constants"
+	grpcv1 "userCRUD/internal/user/infrastructure/transport/proto/v1"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+
+	return s.ResponseWriter.Write(b)
+}
+
+func Guarded(g *grpcv1.Guard, method string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ctx = context.WithValue(ctx, constants.PeerAddr, host)
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		read := r.Method == http.MethodGet || r.Method == http.MethodHead
+		err := g.Run(ctx, "/http/"+method, read, func(ctx context.Context) error {
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			return errorFromHTTPStatus(rec.status)
+		})
+		if err != nil && rec.status == 0 {
+			writeError(w, err)
+		}
+	})
+}
+
+func errorFromHTTPStatus(code int) error {
+	if code < http.StatusBadRequest {
+		return nil
+	}
+
+	return status.Error(codeFromHTTPStatus(code), http.StatusText(code))
+}
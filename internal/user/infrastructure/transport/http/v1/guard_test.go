@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/ratelimit"
+	grpcv1 "userCRUD/internal/user/infrastructure/transport/proto/v1"
+)
+
+func TestGuarded(t *testing.T) {
+	limits, err := ratelimit.ParseLimits("SCIM.anonymous=1/m")
+	if err != nil {
+		t.Fatalf("Failed to parse limits: %v", err)
+	}
+	l := &deps.MockLogger{}
+	g := grpcv1.NewGuard(
+		grpcv1.NewAccessLog(l, false, nil),
+		grpcv1.NewRecovery(l, ""),
+		grpcv1.NewLoadShedder(loadshed.NewLimiter(4, 1, 4, time.Minute, time.Minute, nil)),
+		grpcv1.NewRateLimiter(limits, ratelimit.NewMemoryStore(), l),
+	)
+
+	h := Guarded(g, "SCIM", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	serveFrom := func(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/scim/v2/Users", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serveFrom(h, "10.0.0.1:5000"); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the first request to pass, got %d", rec.Code)
+	}
+	rec := serveFrom(h, "10.0.0.1:5001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After for the same peer, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := serveFrom(h, "10.0.0.2:5000"); rec.Code != http.StatusCreated {
+		t.Errorf("Expected peers to be limited independently, got %d", rec.Code)
+	}
+
+	panicking := Guarded(g, "GraphQL", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	if rec := serveFrom(panicking, "10.0.0.3:5000"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a panicking handler, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,349 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	pb "userCRUD/api/proto"
+)
+
+const (
+	OpenAPIPath    = "/openapi.json"
+	openAPIVersion = "3.0.3"
+	statusSchema   = "Status"
+	anySchema      = "Any"
+	timestampName  = "google.protobuf.Timestamp"
+)
+
+var (
+	ErrUnsupportedHTTPRule = errors.New("unsupported google.api.http rule")
+
+	pathParamPattern = regexp.MustCompile(`\{([^}=]+)\}`)
+
+	successStatuses = map[string]int{
+		pb.UserService_NewUser_FullMethodName: http.StatusCreated,
+	}
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       openAPIInfo                      `json:"info"`
+	Security   []map[string][]string            `json:"security"`
+	Paths      map[string]map[string]*openAPIOp `json:"paths"`
+	Components openAPIComponents                `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type openAPIOp struct {
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}               `json:"additionalProperties,omitempty"`
+}
+
+type openAPIGenerator struct {
+	doc *openAPIDocument
+}
+
+func GenerateOpenAPI(fd protoreflect.FileDescriptor) ([]byte, error) {
+	g := &openAPIGenerator{
+		doc: &openAPIDocument{
+			OpenAPI:  openAPIVersion,
+			Info:     openAPIInfo{Title: string(fd.Package()), Version: "v1"},
+			Security: []map[string][]string{{"basicAuth": {}}, {"bearerAuth": {}}},
+			Paths:    map[string]map[string]*openAPIOp{},
+			Components: openAPIComponents{
+				Schemas: map[string]*openAPISchema{},
+				SecuritySchemes: map[string]*openAPISecurityScheme{
+					"basicAuth":  {Type: "http", Scheme: "basic"},
+					"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "opaque"},
+				},
+			},
+		},
+	}
+	g.addStatusSchemas()
+
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			if err := g.addMethod(methods.Get(j)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(g.doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (g *openAPIGenerator) addMethod(md protoreflect.MethodDescriptor) error {
+	rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return fmt.Errorf("%w: %s is streaming", ErrUnsupportedHTTPRule, md.FullName())
+	}
+
+	rules := append([]*annotations.HttpRule{rule}, rule.AdditionalBindings...)
+	for i, r := range rules {
+		method, path, err := httpPattern(r)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, md.FullName())
+		}
+
+		operationID := fmt.Sprintf("%s_%s", md.Parent().Name(), md.Name())
+		if i > 0 {
+			operationID = fmt.Sprintf("%s%d", operationID, i+1)
+		}
+
+		op, err := g.operation(md, r, path, operationID)
+		if err != nil {
+			return err
+		}
+
+		if g.doc.Paths[path] == nil {
+			g.doc.Paths[path] = map[string]*openAPIOp{}
+		}
+		g.doc.Paths[path][strings.ToLower(method)] = op
+	}
+
+	return nil
+}
+
+func (g *openAPIGenerator) operation(md protoreflect.MethodDescriptor, rule *annotations.HttpRule, path, operationID string) (*openAPIOp, error) {
+	input := md.Input()
+	op := &openAPIOp{
+		OperationID: operationID,
+		Tags:        []string{string(md.Parent().Name())},
+		Responses: map[string]*openAPIResponse{
+			strconv.Itoa(successStatus(fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name()))): {
+				Description: "A successful response.",
+				Content:     jsonContent(g.messageRef(md.Output())),
+			},
+			"default": {
+				Description: "An error response.",
+				Content:     jsonContent(&openAPISchema{Ref: schemaRef(statusSchema)}),
+			},
+		},
+	}
+
+	pathFields := map[string]bool{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		field := input.Fields().ByName(protoreflect.Name(match[1]))
+		if field == nil {
+			return nil, fmt.Errorf("%w: unknown path field %q in %s", ErrUnsupportedHTTPRule, match[1], md.FullName())
+		}
+		pathFields[match[1]] = true
+		op.Parameters = append(op.Parameters, &openAPIParameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   g.fieldSchema(field),
+		})
+	}
+
+	switch rule.Body {
+	case "*":
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  jsonContent(g.messageRef(input)),
+		}
+	case "":
+		fields := input.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if pathFields[string(field.Name())] || field.Kind() == protoreflect.MessageKind {
+				continue
+			}
+			op.Parameters = append(op.Parameters, &openAPIParameter{
+				Name:   string(field.Name()),
+				In:     "query",
+				Schema: g.fieldSchema(field),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("%w: body %q in %s", ErrUnsupportedHTTPRule, rule.Body, md.FullName())
+	}
+
+	return op, nil
+}
+
+func (g *openAPIGenerator) messageRef(md protoreflect.MessageDescriptor) *openAPISchema {
+	if md.FullName() == timestampName {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+
+	name := string(md.Name())
+	if _, ok := g.doc.Components.Schemas[name]; !ok {
+		schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+		g.doc.Components.Schemas[name] = schema
+
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			schema.Properties[field.JSONName()] = g.fieldSchema(field)
+		}
+	}
+
+	return &openAPISchema{Ref: schemaRef(name)}
+}
+
+func (g *openAPIGenerator) fieldSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	if fd.IsMap() {
+		return &openAPISchema{Type: "object", AdditionalProperties: g.singularSchema(fd.MapValue())}
+	}
+	if fd.IsList() {
+		return &openAPISchema{Type: "array", Items: g.singularSchema(fd)}
+	}
+
+	return g.singularSchema(fd)
+}
+
+func (g *openAPIGenerator) singularSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return &openAPISchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &openAPISchema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &openAPISchema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &openAPISchema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		enum := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			enum = append(enum, string(values.Get(i).Name()))
+		}
+		return &openAPISchema{Type: "string", Enum: enum}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.messageRef(fd.Message())
+	default:
+		return &openAPISchema{Type: "string"}
+	}
+}
+
+func (g *openAPIGenerator) addStatusSchemas() {
+	g.doc.Components.Schemas[anySchema] = &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"@type": {Type: "string"},
+		},
+		AdditionalProperties: true,
+	}
+	g.doc.Components.Schemas[statusSchema] = &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"code":    {Type: "integer", Format: "int32"},
+			"message": {Type: "string"},
+			"details": {Type: "array", Items: &openAPISchema{Ref: schemaRef(anySchema)}},
+		},
+	}
+}
+
+func httpPattern(rule *annotations.HttpRule) (string, string, error) {
+	switch pattern := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, pattern.Get, nil
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, pattern.Put, nil
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, pattern.Post, nil
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, pattern.Delete, nil
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, pattern.Patch, nil
+	default:
+		return "", "", ErrUnsupportedHTTPRule
+	}
+}
+
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+func jsonContent(schema *openAPISchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{
+		"application/json": {Schema: schema},
+	}
+}
+
+func successStatus(method string) int {
+	if code, ok := successStatuses[method]; ok {
+		return code
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"userCRUD/api/openapi"
+	pb "userCRUD/api/proto"
+)
+
+func TestOpenAPISpecUpToDate(t *testing.T) {
+	spec, err := GenerateOpenAPI(pb.File_api_proto_user_proto)
+	if err != nil {
+		t.Fatalf("Failed to generate OpenAPI document: %v", err)
+	}
+
+	if !bytes.Equal(spec, openapi.Spec) {
+		t.Errorf("api/openapi/user.openapi.json is out of date with api/proto/user.proto, run `make openapi`")
+	}
+}
+
+func TestOpenAPISpecRoutesServed(t *testing.T) {
+	g, _ := newTestGateway(t)
+
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &doc); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+	if len(doc.Paths) == 0 {
+		t.Fatal("Expected OpenAPI document to contain paths")
+	}
+
+	for path, ops := range doc.Paths {
+		target := pathParamPattern.ReplaceAllString(path, "x")
+		for method := range ops {
+			rec := serve(g, strings.ToUpper(method), target, "{}")
+			if rec.Code == http.StatusMethodNotAllowed || strings.Contains(rec.Body.String(), "no route") {
+				t.Errorf("%s %s is documented but not routed by the gateway: %d", method, path, rec.Code)
+			}
+		}
+	}
+}
+
+func TestGatewayServesOpenAPI(t *testing.T) {
+	g, _ := newTestGateway(t)
+
+	rec := serve(g, http.MethodGet, OpenAPIPath, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), openapi.Spec) {
+		t.Errorf("Expected the embedded OpenAPI document to be served")
+	}
+}
+
+func TestOpenAPISpecDocumentsCreatedStatus(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &doc); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+
+	responses := doc.Paths["/v1/users"]["post"].Responses
+	if _, ok := responses["201"]; !ok {
+		t.Errorf("Expected POST /v1/users to document 201, got %v", responses)
+	}
+	if _, ok := responses["200"]; ok {
+		t.Errorf("Expected POST /v1/users not to document 200")
+	}
+}
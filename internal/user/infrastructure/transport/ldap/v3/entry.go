@@ -0,0 +1,156 @@
+package v3
+
+import (
+	"github.com/go-ldap/ldap/v3"
+	"strings"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	attributeAll            = "*"
+	attributeAllOperational = "+"
+	attributeNone           = "1.1"
+
+	vendorName = "userCRUD"
+)
+
+var attributeNames = map[string]string{
+	"objectclass":            "objectClass",
+	"uid":                    "uid",
+	"userid":                 "uid",
+	"cn":                     "cn",
+	"commonname":             "cn",
+	"sn":                     "sn",
+	"surname":                "sn",
+	"displayname":            "displayName",
+	"mail":                   "mail",
+	"rfc822mailbox":          "mail",
+	"ou":                     "ou",
+	"organizationalunitname": "ou",
+	"entryuuid":              "entryUUID",
+	"namingcontexts":         "namingContexts",
+	"supportedldapversion":   "supportedLDAPVersion",
+	"vendorname":             "vendorName",
+}
+
+type entry struct {
+	dn          *ldap.DN
+	user        []string
+	operational []string
+	attributes  map[string][]string
+}
+
+func newUserEntry(base *ldap.DN, user *model.User) *entry {
+	return &entry{
+		dn:          userDN(base, user.Username),
+		user:        []string{"objectClass", "uid", "cn", "sn", "displayName", "mail"},
+		operational: []string{"entryUUID"},
+		attributes: map[string][]string{
+			"objectClass": {"top", "person", "organizationalPerson", "inetOrgPerson"},
+			"uid":         {user.Username},
+			"cn":          {user.Username},
+			"sn":          {user.Username},
+			"displayName": {user.Username},
+			"mail":        {user.Email},
+			"entryUUID":   {user.ID},
+		},
+	}
+}
+
+func newBaseEntry(base *ldap.DN) *entry {
+	e := &entry{
+		dn:   base,
+		user: []string{"objectClass"},
+		attributes: map[string][]string{
+			"objectClass": {"top", "organizationalUnit"},
+		},
+	}
+
+	if len(base.RDNs) > 0 {
+		for _, a := range base.RDNs[0].Attributes {
+			if name := canonicalAttribute(a.Type); name != "" {
+				e.user = append(e.user, name)
+				e.attributes[name] = append(e.attributes[name], a.Value)
+			}
+		}
+	}
+
+	return e
+}
+
+func newRootDSE(base *ldap.DN) *entry {
+	return &entry{
+		dn:   &ldap.DN{},
+		user: []string{"objectClass", "namingContexts", "supportedLDAPVersion", "vendorName"},
+		attributes: map[string][]string{
+			"objectClass":          {"top"},
+			"namingContexts":       {base.String()},
+			"supportedLDAPVersion": {"3"},
+			"vendorName":           {vendorName},
+		},
+	}
+}
+
+func (e *entry) selectAttributes(requested []string) []string {
+	if len(requested) == 0 {
+		return e.user
+	}
+
+	seen := make(map[string]bool)
+	var selected []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if _, ok := e.attributes[name]; ok && !seen[name] {
+				seen[name] = true
+				selected = append(selected, name)
+			}
+		}
+	}
+
+	for _, r := range requested {
+		switch r {
+		case attributeAll:
+			add(e.user...)
+		case attributeAllOperational:
+			add(e.operational...)
+		case attributeNone:
+		default:
+			add(canonicalAttribute(r))
+		}
+	}
+
+	return selected
+}
+
+func userDN(base *ldap.DN, username string) *ldap.DN {
+	rdn := &ldap.RelativeDN{Attributes: []*ldap.AttributeTypeAndValue{{Type: "uid", Value: username}}}
+
+	return &ldap.DN{RDNs: append([]*ldap.RelativeDN{rdn}, base.RDNs...)}
+}
+
+func usernameFromDN(base *ldap.DN, name string) string {
+	if !strings.Contains(name, "=") {
+		return name
+	}
+
+	dn, err := ldap.ParseDN(name)
+	if err != nil || len(dn.RDNs) != len(base.RDNs)+1 || !base.AncestorOfFold(dn) {
+		return ""
+	}
+
+	rdn := dn.RDNs[0]
+	if len(rdn.Attributes) != 1 {
+		return ""
+	}
+	if name := canonicalAttribute(rdn.Attributes[0].Type); name != "uid" && name != "cn" {
+		return ""
+	}
+
+	return rdn.Attributes[0].Value
+}
+
+func canonicalAttribute(description string) string {
+	name, _, _ := strings.Cut(description, ";")
+
+	return attributeNames[strings.ToLower(name)]
+}
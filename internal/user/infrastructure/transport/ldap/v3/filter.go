@@ -0,0 +1,173 @@
+package v3
+
+import (
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"strings"
+)
+
+type matchResult int
+
+const (
+	matchFalse matchResult = iota
+	matchTrue
+	matchUndefined
+)
+
+func evaluateFilter(f *ber.Packet, e *entry) (matchResult, error) {
+	if f.ClassType != ber.ClassContext {
+		return matchUndefined, ErrMalformedMessage
+	}
+
+	switch f.Tag {
+	case ldap.FilterAnd:
+		result := matchTrue
+		for _, child := range f.Children {
+			r, err := evaluateFilter(child, e)
+			if err != nil {
+				return matchUndefined, err
+			}
+			if r == matchFalse {
+				return matchFalse, nil
+			}
+			if r == matchUndefined {
+				result = matchUndefined
+			}
+		}
+
+		return result, nil
+	case ldap.FilterOr:
+		result := matchFalse
+		for _, child := range f.Children {
+			r, err := evaluateFilter(child, e)
+			if err != nil {
+				return matchUndefined, err
+			}
+			if r == matchTrue {
+				return matchTrue, nil
+			}
+			if r == matchUndefined {
+				result = matchUndefined
+			}
+		}
+
+		return result, nil
+	case ldap.FilterNot:
+		if len(f.Children) != 1 {
+			return matchUndefined, ErrMalformedMessage
+		}
+
+		r, err := evaluateFilter(f.Children[0], e)
+		switch {
+		case err != nil:
+			return matchUndefined, err
+		case r == matchTrue:
+			return matchFalse, nil
+		case r == matchFalse:
+			return matchTrue, nil
+		}
+
+		return matchUndefined, nil
+	case ldap.FilterPresent:
+		if _, ok := e.attributes[canonicalAttribute(packetString(f))]; ok {
+			return matchTrue, nil
+		}
+
+		return matchFalse, nil
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(f.Children) != 2 {
+			return matchUndefined, ErrMalformedMessage
+		}
+
+		values, ok := attributeValues(e, packetString(f.Children[0]))
+		if !ok {
+			return matchUndefined, nil
+		}
+
+		assertion := strings.ToLower(packetString(f.Children[1]))
+		for _, value := range values {
+			if compareValue(f.Tag, strings.ToLower(value), assertion) {
+				return matchTrue, nil
+			}
+		}
+
+		return matchFalse, nil
+	case ldap.FilterSubstrings:
+		if len(f.Children) != 2 || len(f.Children[1].Children) == 0 {
+			return matchUndefined, ErrMalformedMessage
+		}
+
+		values, ok := attributeValues(e, packetString(f.Children[0]))
+		if !ok {
+			return matchUndefined, nil
+		}
+
+		for _, value := range values {
+			if matchSubstrings(strings.ToLower(value), f.Children[1].Children) {
+				return matchTrue, nil
+			}
+		}
+
+		return matchFalse, nil
+	case ldap.FilterExtensibleMatch:
+		return matchUndefined, nil
+	}
+
+	return matchUndefined, ErrMalformedMessage
+}
+
+func attributeValues(e *entry, description string) ([]string, bool) {
+	name := canonicalAttribute(description)
+	if name == "" {
+		return nil, false
+	}
+
+	return e.attributes[name], true
+}
+
+func compareValue(tag ber.Tag, value, assertion string) bool {
+	switch tag {
+	case ldap.FilterGreaterOrEqual:
+		return value >= assertion
+	case ldap.FilterLessOrEqual:
+		return value <= assertion
+	}
+
+	return value == assertion
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for i, part := range parts {
+		s := strings.ToLower(packetString(part))
+
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if i != 0 || !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case ldap.FilterSubstringsAny:
+			idx := strings.Index(value, s)
+			if idx < 0 {
+				return false
+			}
+			value = value[idx+len(s):]
+		case ldap.FilterSubstringsFinal:
+			if i != len(parts)-1 || !strings.HasSuffix(value, s) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func packetString(p *ber.Packet) string {
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+
+	return p.Data.String()
+}
@@ -0,0 +1,174 @@
+package v3
+
+import (
+	"bufio"
+	"errors"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"io"
+)
+
+const (
+	maxMessageBytes = 1 << 20
+	sequenceTag     = 0x30
+)
+
+var (
+	ErrMalformedMessage = errors.New("malformed ldap message")
+	ErrMessageTooLarge  = errors.New("ldap message exceeds the allowed size")
+)
+
+type message struct {
+	id int64
+	op *ber.Packet
+}
+
+func readMessage(r *bufio.Reader) (*message, error) {
+	header, err := r.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != sequenceTag {
+		return nil, ErrMalformedMessage
+	}
+
+	headerLen, length := 2, int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return nil, ErrMalformedMessage
+		}
+		if header, err = r.Peek(2 + n); err != nil {
+			return nil, err
+		}
+
+		length = 0
+		for _, b := range header[2:] {
+			length = length<<8 | int(b)
+		}
+		headerLen += n
+	}
+	if length > maxMessageBytes {
+		return nil, ErrMessageTooLarge
+	}
+
+	data := make([]byte, headerLen+length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if err := checkLengths(data[headerLen:]); err != nil {
+		return nil, err
+	}
+
+	packet, err := ber.DecodePacketErr(data)
+	if err != nil {
+		return nil, ErrMalformedMessage
+	}
+	if len(packet.Children) < 2 {
+		return nil, ErrMalformedMessage
+	}
+
+	id, ok := packet.Children[0].Value.(int64)
+	if !ok || packet.Children[1].ClassType != ber.ClassApplication {
+		return nil, ErrMalformedMessage
+	}
+
+	return &message{id: id, op: packet.Children[1]}, nil
+}
+
+func checkLengths(data []byte) error {
+	for len(data) > 0 {
+		constructed, headerLen, length, err := parseHeader(data)
+		if err != nil {
+			return err
+		}
+		if constructed {
+			if err := checkLengths(data[headerLen : headerLen+length]); err != nil {
+				return err
+			}
+		}
+		data = data[headerLen+length:]
+	}
+
+	return nil
+}
+
+func parseHeader(data []byte) (bool, int, int, error) {
+	if len(data) < 2 {
+		return false, 0, 0, ErrMalformedMessage
+	}
+
+	constructed := data[0]&0x20 != 0
+	i := 1
+	if data[0]&0x1f == 0x1f {
+		for i < len(data) && data[i]&0x80 != 0 {
+			i++
+		}
+		i++
+	}
+	if i >= len(data) {
+		return false, 0, 0, ErrMalformedMessage
+	}
+
+	length := int(data[i])
+	i++
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || i+n > len(data) {
+			return false, 0, 0, ErrMalformedMessage
+		}
+
+		length = 0
+		for _, b := range data[i : i+n] {
+			length = length<<8 | int(b)
+		}
+		i += n
+	}
+	if length < 0 || length > len(data)-i {
+		return false, 0, 0, ErrMalformedMessage
+	}
+
+	return constructed, i, length, nil
+}
+
+func writeMessage(w io.Writer, id int64, op *ber.Packet) error {
+	packet := ber.NewSequence("LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	packet.AppendChild(op)
+
+	_, err := w.Write(packet.Bytes())
+
+	return err
+}
+
+func newResult(tag ber.Tag, code uint16, matchedDN, diagnostic string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, ldap.ApplicationMap[uint8(tag)])
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, uint64(code), "resultCode"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, matchedDN, "matchedDN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, diagnostic, "diagnosticMessage"))
+
+	return packet
+}
+
+func newEntry(e *entry, attributes []string, typesOnly bool) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn.String(), "objectName"))
+
+	list := ber.NewSequence("attributes")
+	for _, name := range attributes {
+		attribute := ber.NewSequence("attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		if !typesOnly {
+			for _, value := range e.attributes[name] {
+				values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+			}
+		}
+		attribute.AppendChild(values)
+		list.AppendChild(attribute)
+	}
+	packet.AppendChild(list)
+
+	return packet
+}
@@ -0,0 +1,412 @@
+package v3
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"sync"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/common/requestid"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const (
+	protocolVersion = 3
+	maxResults      = 1000
+)
+
+var (
+	ErrServerClosed = errors.New("ldap server closed")
+)
+
+var responseTags = map[ber.Tag]ber.Tag{
+	ldap.ApplicationModifyRequest:   ldap.ApplicationModifyResponse,
+	ldap.ApplicationAddRequest:      ldap.ApplicationAddResponse,
+	ldap.ApplicationDelRequest:      ldap.ApplicationDelResponse,
+	ldap.ApplicationModifyDNRequest: ldap.ApplicationModifyDNResponse,
+	ldap.ApplicationCompareRequest:  ldap.ApplicationCompareResponse,
+	ldap.ApplicationExtendedRequest: ldap.ApplicationExtendedResponse,
+}
+
+type Guard interface {
+	Run(ctx context.Context, method string, read bool, fn func(ctx context.Context) error) error
+}
+
+type Server struct {
+	ur    persistence.UserRepository
+	a     *auth.Authenticator
+	guard Guard
+	l     deps.Logger
+	base  *ldap.DN
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+type session struct {
+	conn net.Conn
+	ctx  context.Context
+	user *model.User
+}
+
+func NewServer(ur persistence.UserRepository, a *auth.Authenticator, g Guard, l deps.Logger, baseDN string) (*Server, error) {
+	base, err := ldap.ParseDN(baseDN)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		ur:        ur,
+		a:         a,
+		guard:     g,
+		l:         l,
+		base:      base,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}, nil
+}
+
+func (s *Server) Serve(lis net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[lis] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, lis)
+			s.mu.Unlock()
+
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+
+		go func() {
+			defer s.untrack(conn)
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for lis := range s.listeners {
+		lis.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+
+	s.wg.Done()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	ctx := context.Background()
+	if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		ctx = context.WithValue(ctx, constants.PeerAddr, host)
+	}
+
+	sess := &session{conn: conn, ctx: ctx}
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			if errors.Is(err, ErrMalformedMessage) || errors.Is(err, ErrMessageTooLarge) {
+				s.l.Info(ctx, "LDAP connection closed", "error", err)
+			}
+			return
+		}
+
+		if !s.handle(sess, msg) {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(sess *session, msg *message) bool {
+	ctx, span := tracing.Start(sess.ctx, "LDAP "+ldap.ApplicationMap[uint8(msg.op.Tag)])
+	defer span.End()
+
+	ctx = requestid.WithRequestID(tracing.WithTraceID(ctx), "")
+	if sess.user != nil {
+		ctx = auth.WithIdentity(ctx, sess.user, nil)
+	}
+
+	var err error
+	switch msg.op.Tag {
+	case ldap.ApplicationBindRequest:
+		err = s.guarded(ctx, sess, msg, "Bind", false, ldap.ApplicationBindResponse, s.bind)
+	case ldap.ApplicationSearchRequest:
+		err = s.guarded(ctx, sess, msg, "Search", true, ldap.ApplicationSearchResultDone, s.search)
+	case ldap.ApplicationUnbindRequest:
+		return false
+	case ldap.ApplicationAbandonRequest:
+		return true
+	default:
+		tag, ok := responseTags[msg.op.Tag]
+		if !ok {
+			s.l.Info(ctx, "LDAP unknown operation", "tag", msg.op.Tag)
+			return false
+		}
+		err = writeMessage(sess.conn, msg.id, newResult(tag, ldap.LDAPResultUnwillingToPerform, "", "directory is read-only"))
+	}
+
+	if err != nil {
+		s.l.Info(ctx, "LDAP operation failed", "error", err)
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) guarded(ctx context.Context, sess *session, msg *message, method string, read bool, tag ber.Tag, op func(context.Context, *session, *message) error) error {
+	if s.guard == nil {
+		return op(ctx, sess, msg)
+	}
+
+	var opErr error
+	err := s.guard.Run(ctx, "/ldap/"+method, read, func(ctx context.Context) error {
+		opErr = op(ctx, sess, msg)
+		return opErr
+	})
+	if err == nil || opErr != nil {
+		return err
+	}
+
+	code := uint16(ldap.LDAPResultOther)
+	if c := status.Code(err); c == codes.ResourceExhausted || c == codes.Unavailable {
+		code = ldap.LDAPResultBusy
+	}
+
+	return writeMessage(sess.conn, msg.id, newResult(tag, code, "", status.Convert(err).Message()))
+}
+
+func (s *Server) bind(ctx context.Context, sess *session, msg *message) error {
+	reply := func(code uint16, diagnostic string) error {
+		return writeMessage(sess.conn, msg.id, newResult(ldap.ApplicationBindResponse, code, "", diagnostic))
+	}
+
+	sess.user = nil
+
+	op := msg.op.Children
+	if len(op) != 3 {
+		return reply(ldap.LDAPResultProtocolError, "malformed bind request")
+	}
+	if version, ok := op[0].Value.(int64); !ok || version != protocolVersion {
+		return reply(ldap.LDAPResultProtocolError, "only LDAPv3 is supported")
+	}
+	if op[2].ClassType != ber.ClassContext || op[2].Tag != 0 {
+		return reply(ldap.LDAPResultAuthMethodNotSupported, "only simple bind is supported")
+	}
+
+	name, rawPassword := packetString(op[1]), packetString(op[2])
+	if name == "" && rawPassword == "" {
+		return reply(ldap.LDAPResultSuccess, "")
+	}
+	if rawPassword == "" {
+		return reply(ldap.LDAPResultUnwillingToPerform, "unauthenticated bind is not allowed")
+	}
+
+	username := usernameFromDN(s.base, name)
+	if username == "" {
+		return reply(ldap.LDAPResultInvalidCredentials, "")
+	}
+
+	ctx, err := s.a.AuthenticatePassword(ctx, username, rawPassword)
+	if err != nil {
+		return reply(resultCode(err), err.Error())
+	}
+
+	user, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		s.l.Info(ctx, "LDAP bind failed", "dn", name)
+		return reply(ldap.LDAPResultInvalidCredentials, "")
+	}
+	if err := s.a.CheckPasswordRotation(ctx); err != nil {
+		return reply(ldap.LDAPResultInvalidCredentials, err.Error())
+	}
+
+	sess.user = user
+	s.l.Info(ctx, "LDAP bind succeeded", "dn", name)
+
+	return reply(ldap.LDAPResultSuccess, "")
+}
+
+func (s *Server) search(ctx context.Context, sess *session, msg *message) error {
+	reply := func(code uint16, matchedDN, diagnostic string) error {
+		return writeMessage(sess.conn, msg.id, newResult(ldap.ApplicationSearchResultDone, code, matchedDN, diagnostic))
+	}
+
+	op := msg.op.Children
+	if len(op) != 8 {
+		return reply(ldap.LDAPResultProtocolError, "", "malformed search request")
+	}
+
+	base, err := ldap.ParseDN(packetString(op[0]))
+	if err != nil {
+		return reply(ldap.LDAPResultInvalidDNSyntax, "", err.Error())
+	}
+	scope, _ := op[1].Value.(int64)
+	sizeLimit, _ := op[3].Value.(int64)
+	typesOnly, _ := op[5].Value.(bool)
+	filter := op[6]
+
+	var requested []string
+	for _, attribute := range op[7].Children {
+		requested = append(requested, packetString(attribute))
+	}
+
+	if len(base.RDNs) == 0 && scope == ldap.ScopeBaseObject {
+		return s.sendEntries(sess, msg, []*entry{newRootDSE(s.base)}, filter, requested, typesOnly, sizeLimit)
+	}
+
+	if sess.user == nil {
+		return reply(ldap.LDAPResultInsufficientAccessRights, "", "bind required")
+	}
+
+	candidates, err := s.entries(ctx)
+	if err != nil {
+		return reply(resultCode(err), "", err.Error())
+	}
+
+	var inScope []*entry
+	found := base.AncestorOfFold(s.base)
+	for _, e := range candidates {
+		if base.EqualFold(e.dn) {
+			found = true
+		}
+		if withinScope(base, e.dn, scope) {
+			inScope = append(inScope, e)
+		}
+	}
+	if !found {
+		matchedDN := ""
+		if s.base.AncestorOfFold(base) {
+			matchedDN = s.base.String()
+		}
+
+		return reply(ldap.LDAPResultNoSuchObject, matchedDN, "")
+	}
+
+	return s.sendEntries(sess, msg, inScope, filter, requested, typesOnly, sizeLimit)
+}
+
+func (s *Server) sendEntries(sess *session, msg *message, entries []*entry, filter *ber.Packet, requested []string, typesOnly bool, sizeLimit int64) error {
+	if sizeLimit <= 0 || sizeLimit > maxResults {
+		sizeLimit = maxResults
+	}
+
+	sent := int64(0)
+	for _, e := range entries {
+		match, err := evaluateFilter(filter, e)
+		if err != nil {
+			return writeMessage(sess.conn, msg.id, newResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "", "malformed filter"))
+		}
+		if match != matchTrue {
+			continue
+		}
+
+		if sent == sizeLimit {
+			return writeMessage(sess.conn, msg.id, newResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded, "", ""))
+		}
+		if err := writeMessage(sess.conn, msg.id, newEntry(e, e.selectAttributes(requested), typesOnly)); err != nil {
+			return err
+		}
+		sent++
+	}
+
+	return writeMessage(sess.conn, msg.id, newResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, "", ""))
+}
+
+func (s *Server) entries(ctx context.Context) ([]*entry, error) {
+	users, _, err := s.ur.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*entry, 0, len(users)+1)
+	entries = append(entries, newBaseEntry(s.base))
+	for _, user := range users {
+		entries = append(entries, newUserEntry(s.base, user))
+	}
+
+	return entries, nil
+}
+
+func withinScope(base, dn *ldap.DN, scope int64) bool {
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return base.EqualFold(dn)
+	case ldap.ScopeSingleLevel:
+		return len(dn.RDNs) == len(base.RDNs)+1 && base.AncestorOfFold(dn)
+	case ldap.ScopeWholeSubtree:
+		return base.EqualFold(dn) || base.AncestorOfFold(dn)
+	}
+
+	return false
+}
+
+func resultCode(err error) uint16 {
+	switch {
+	case errors.Is(err, password.ErrHashingOverloaded), errors.Is(err, ratelimit.ErrLimited):
+		return ldap.LDAPResultBusy
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ldap.LDAPResultUnavailable
+	}
+
+	return ldap.LDAPResultOther
+}
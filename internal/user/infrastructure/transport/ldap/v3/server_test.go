@@ -0,0 +1,385 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"testing"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const testBaseDN = "ou=users,dc=usercrud,dc=local"
+
+type ldapTest struct {
+	addr string
+	uc   *command.User
+	ctx  context.Context
+}
+
+type rejectingGuard struct {
+	method string
+}
+
+func (g *rejectingGuard) Run(ctx context.Context, method string, read bool, fn func(ctx context.Context) error) error {
+	if method == g.method {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return fn(ctx)
+}
+
+type limitingGuard struct{}
+
+func (g *limitingGuard) Run(ctx context.Context, method string, read bool, fn func(ctx context.Context) error) error {
+	return fn(ratelimit.WithCheck(ctx, func(ctx context.Context, class, id string) error {
+		return ratelimit.ErrLimited
+	}))
+}
+
+func newLDAPTest(t *testing.T) *ldapTest {
+	return newGuardedLDAPTest(t, nil)
+}
+
+func newGuardedLDAPTest(t *testing.T, g Guard) *ldapTest {
+	l := &deps.MockLogger{}
+	h := password.NewPool(bcrypt.MinCost, 4, 64)
+	ur := persistence.NewUserRepositoryMemory(l, h, 1000)
+	pp := command.NewPasswordPolicy(5, 0)
+	uc := command.NewUserCommand(ur, deps.NewGoPlaygroundValidator(), h, pp, auditpersistence.NewAuditLogMemory())
+
+	admin, err := ur.GetUserByUsername(context.Background(), "admin")
+	if err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	for _, u := range []*model.User{
+		{Username: "bjensen", Email: "bjensen@example.com", Password: "password"},
+		{Username: "jsmith", Email: "jsmith@example.com", Password: "password"},
+	} {
+		if _, err := uc.CreateUser(ctx, u); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	s, err := NewServer(ur, auth.NewAuthenticator(ur, persistence.NewTokenRepositoryMemory(), pp), g, l, testBaseDN)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(func() { s.Close() })
+
+	return &ldapTest{addr: lis.Addr().String(), uc: uc, ctx: ctx}
+}
+
+func (lt *ldapTest) dial(t *testing.T) *ldap.Conn {
+	conn, err := ldap.DialURL("ldap://" + lt.addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func (lt *ldapTest) bound(t *testing.T) *ldap.Conn {
+	conn := lt.dial(t)
+	if err := conn.Bind("uid=bjensen,"+testBaseDN, "password"); err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+
+	return conn
+}
+
+func search(conn *ldap.Conn, base string, scope int, filter string, attributes ...string) (*ldap.SearchResult, error) {
+	return conn.Search(ldap.NewSearchRequest(base, scope, ldap.NeverDerefAliases, 0, 0, false, filter, attributes, nil))
+}
+
+func usernames(result *ldap.SearchResult) map[string]bool {
+	names := make(map[string]bool)
+	for _, e := range result.Entries {
+		names[e.GetAttributeValue("uid")] = true
+	}
+
+	return names
+}
+
+func TestBind(t *testing.T) {
+	lt := newLDAPTest(t)
+
+	tests := []struct {
+		name     string
+		dn       string
+		password string
+		code     uint16
+	}{
+		{"full dn", "uid=bjensen," + testBaseDN, "password", ldap.LDAPResultSuccess},
+		{"case insensitive dn", "UID=bjensen,OU=Users,DC=usercrud,DC=local", "password", ldap.LDAPResultSuccess},
+		{"cn rdn", "cn=bjensen," + testBaseDN, "password", ldap.LDAPResultSuccess},
+		{"bare username", "bjensen", "password", ldap.LDAPResultSuccess},
+		{"wrong password", "uid=bjensen," + testBaseDN, "wrong", ldap.LDAPResultInvalidCredentials},
+		{"unknown user", "uid=nobody," + testBaseDN, "password", ldap.LDAPResultInvalidCredentials},
+		{"foreign base", "uid=bjensen,dc=example,dc=com", "password", ldap.LDAPResultInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		err := lt.dial(t).Bind(tt.dn, tt.password)
+		if tt.code == ldap.LDAPResultSuccess {
+			if err != nil {
+				t.Errorf("%s: expected success, got %v", tt.name, err)
+			}
+			continue
+		}
+		if !ldap.IsErrorWithCode(err, tt.code) {
+			t.Errorf("%s: expected code %d, got %v", tt.name, tt.code, err)
+		}
+	}
+
+	if err := lt.dial(t).UnauthenticatedBind("uid=bjensen," + testBaseDN); !ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform) {
+		t.Errorf("Expected unauthenticated bind to be rejected, got %v", err)
+	}
+}
+
+func TestBindRequiresPasswordRotation(t *testing.T) {
+	lt := newLDAPTest(t)
+
+	user, err := lt.uc.CreateUser(lt.ctx, &model.User{Username: "rotated", Email: "rotated@example.com", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := lt.uc.UpdateUser(lt.ctx, &model.UpdateUser{ID: user.ID, Username: user.Username, Email: user.Email, MustChangePassword: true}); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	if err := lt.dial(t).Bind("uid=rotated,"+testBaseDN, "password"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Errorf("Expected invalid credentials, got %v", err)
+	}
+}
+
+func TestSearchRequiresBind(t *testing.T) {
+	lt := newLDAPTest(t)
+	conn := lt.dial(t)
+
+	result, err := search(conn, "", ldap.ScopeBaseObject, "(objectClass=*)")
+	if err != nil || len(result.Entries) != 1 {
+		t.Fatalf("Expected root DSE, got %v %v", result, err)
+	}
+	if got := result.Entries[0].GetAttributeValue("namingContexts"); got != testBaseDN {
+		t.Errorf("Unexpected namingContexts %q", got)
+	}
+
+	if _, err := search(conn, testBaseDN, ldap.ScopeWholeSubtree, "(uid=bjensen)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights) {
+		t.Errorf("Expected insufficient access rights, got %v", err)
+	}
+
+	if err := conn.Bind("uid=bjensen,"+testBaseDN, "wrong"); err == nil {
+		t.Fatal("Expected bind to fail")
+	}
+	if _, err := search(conn, testBaseDN, ldap.ScopeWholeSubtree, "(uid=bjensen)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights) {
+		t.Errorf("Expected failed bind to leave the connection anonymous, got %v", err)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	lt := newLDAPTest(t)
+	conn := lt.bound(t)
+
+	result, err := search(conn, testBaseDN, ldap.ScopeWholeSubtree, "(&(objectClass=inetOrgPerson)(uid=BJensen))")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(result.Entries))
+	}
+
+	e := result.Entries[0]
+	if e.DN != "uid=bjensen,"+testBaseDN {
+		t.Errorf("Unexpected DN %q", e.DN)
+	}
+	if e.GetAttributeValue("mail") != "bjensen@example.com" || e.GetAttributeValue("cn") != "bjensen" || e.GetAttributeValue("sn") != "bjensen" {
+		t.Errorf("Unexpected attributes %+v", e.Attributes)
+	}
+	if classes := e.GetAttributeValues("objectClass"); len(classes) != 4 || classes[3] != "inetOrgPerson" {
+		t.Errorf("Unexpected objectClass %v", classes)
+	}
+	if e.GetAttributeValue("entryUUID") != "" || e.GetAttributeValue("userPassword") != "" {
+		t.Error("Expected only user attributes to be returned by default")
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"(mail=*@example.com)", []string{"bjensen", "jsmith"}},
+		{"(mail=b*n@EXAMPLE*)", []string{"bjensen"}},
+		{"(|(uid=admin)(uid=jsmith))", []string{"admin", "jsmith"}},
+		{"(&(objectClass=person)(!(uid=admin)))", []string{"bjensen", "jsmith"}},
+		{"(userid=jsmith)", []string{"jsmith"}},
+		{"(uid>=j)", []string{"jsmith"}},
+		{"(uid~=ADMIN)", []string{"admin"}},
+		{"(unknownAttribute=x)", nil},
+		{"(!(unknownAttribute=x))", nil},
+		{"(telephoneNumber=*)", nil},
+	}
+
+	for _, tt := range tests {
+		result, err := search(conn, testBaseDN, ldap.ScopeSingleLevel, tt.filter)
+		if err != nil {
+			t.Errorf("%s: search failed: %v", tt.filter, err)
+			continue
+		}
+
+		got := usernames(result)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.filter, tt.want, got)
+			continue
+		}
+		for _, name := range tt.want {
+			if !got[name] {
+				t.Errorf("%s: expected %v, got %v", tt.filter, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestSearchScopes(t *testing.T) {
+	lt := newLDAPTest(t)
+	conn := lt.bound(t)
+
+	result, err := search(conn, "dc=usercrud,dc=local", ldap.ScopeWholeSubtree, "(objectClass=*)")
+	if err != nil || len(result.Entries) != 4 {
+		t.Fatalf("Expected container and 3 users, got %v %v", result, err)
+	}
+
+	result, err = search(conn, testBaseDN, ldap.ScopeBaseObject, "(objectClass=*)")
+	if err != nil || len(result.Entries) != 1 || result.Entries[0].GetAttributeValue("ou") != "users" {
+		t.Fatalf("Expected the base entry, got %v %v", result, err)
+	}
+
+	result, err = search(conn, "uid=jsmith,"+testBaseDN, ldap.ScopeBaseObject, "(objectClass=*)")
+	if err != nil || len(result.Entries) != 1 || result.Entries[0].GetAttributeValue("uid") != "jsmith" {
+		t.Fatalf("Expected jsmith, got %v %v", result, err)
+	}
+
+	_, err = search(conn, "uid=nobody,"+testBaseDN, ldap.ScopeBaseObject, "(objectClass=*)")
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultNoSuchObject || ldapErr.MatchedDN != testBaseDN {
+		t.Errorf("Expected noSuchObject with matched DN, got %v", err)
+	}
+
+	if _, err := search(conn, "dc=example,dc=com", ldap.ScopeWholeSubtree, "(objectClass=*)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("Expected noSuchObject, got %v", err)
+	}
+}
+
+func TestSearchAttributesAndLimits(t *testing.T) {
+	lt := newLDAPTest(t)
+	conn := lt.bound(t)
+
+	result, err := search(conn, testBaseDN, ldap.ScopeSingleLevel, "(uid=bjensen)", "MAIL", "+")
+	if err != nil || len(result.Entries) != 1 {
+		t.Fatalf("Search failed: %v %v", result, err)
+	}
+	e := result.Entries[0]
+	if len(e.Attributes) != 2 || e.GetAttributeValue("mail") != "bjensen@example.com" || e.GetAttributeValue("entryUUID") == "" {
+		t.Errorf("Unexpected attributes %+v", e.Attributes)
+	}
+
+	result, err = search(conn, testBaseDN, ldap.ScopeSingleLevel, "(uid=bjensen)", "1.1")
+	if err != nil || len(result.Entries) != 1 || len(result.Entries[0].Attributes) != 0 {
+		t.Errorf("Expected no attributes, got %v %v", result, err)
+	}
+
+	result, err = conn.Search(ldap.NewSearchRequest(testBaseDN, ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 2, 0, false, "(objectClass=person)", nil, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || result == nil || len(result.Entries) != 2 {
+		t.Errorf("Expected size limit exceeded after 2 entries, got %v", err)
+	}
+}
+
+func TestDirectoryIsReadOnly(t *testing.T) {
+	lt := newLDAPTest(t)
+	conn := lt.bound(t)
+
+	modify := ldap.NewModifyRequest("uid=bjensen,"+testBaseDN, nil)
+	modify.Replace("mail", []string{"other@example.com"})
+	if err := conn.Modify(modify); !ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform) {
+		t.Errorf("Expected unwillingToPerform for modify, got %v", err)
+	}
+
+	if err := conn.Del(ldap.NewDelRequest("uid=bjensen,"+testBaseDN, nil)); !ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform) {
+		t.Errorf("Expected unwillingToPerform for delete, got %v", err)
+	}
+
+	if _, err := search(conn, testBaseDN, ldap.ScopeSingleLevel, "(uid=bjensen)"); err != nil {
+		t.Errorf("Expected connection to stay usable, got %v", err)
+	}
+}
+
+func TestMalformedMessageClosesConnection(t *testing.T) {
+	lt := newLDAPTest(t)
+
+	for _, payload := range [][]byte{
+		{0x04, 0x03, 'a', 'b', 'c'},
+		{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff},
+		{0x30, 0x07, 0x02, 0x01, 0x01, 0x04, 0x84, 0x7f, 0xff},
+	} {
+		conn, err := net.Dial("tcp", lt.addr)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+
+		conn.Write(payload)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("Expected connection to be closed for %x, got %v", payload, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestGuardRejectsBind(t *testing.T) {
+	lt := newGuardedLDAPTest(t, &rejectingGuard{method: "/ldap/Bind"})
+	conn := lt.dial(t)
+
+	err := conn.Bind("uid=bjensen,"+testBaseDN, "password")
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultBusy) {
+		t.Fatalf("Expected busy for a rejected bind, got %v", err)
+	}
+
+	if _, err := search(conn, "", ldap.ScopeBaseObject, "(objectClass=*)"); err != nil {
+		t.Errorf("Expected the connection to stay usable, got %v", err)
+	}
+}
+
+func TestGuardLimitsBoundUser(t *testing.T) {
+	lt := newGuardedLDAPTest(t, &limitingGuard{})
+	conn := lt.dial(t)
+
+	err := conn.Bind("uid=bjensen,"+testBaseDN, "password")
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultBusy) {
+		t.Fatalf("Expected busy when the user is rate limited, got %v", err)
+	}
+
+	if _, err := search(conn, testBaseDN, ldap.ScopeWholeSubtree, "(uid=*)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights) {
+		t.Errorf("Expected the limited bind to leave the connection anonymous, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	codeChallengeMethodS256 = "S256"
+
+	messageInvalidCredentials = "Invalid username or password."
+	messagePasswordRotation   = "Your password has expired. Change it before signing in."
+	messageUnavailable        = "The service is busy. Please try again."
+)
+
+var authorizeParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"}
+
+type authorizeRequest struct {
+	client *model.OIDCClient
+	params url.Values
+	scopes []string
+}
+
+type loginPage struct {
+	Action     string
+	ClientName string
+	Params     map[string]string
+	Username   string
+	Error      string
+}
+
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	values := r.URL.Query()
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		values = r.PostForm
+	}
+
+	req, ok := h.parseAuthorizeRequest(ctx, w, r, values)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.renderLogin(w, req, http.StatusOK, "", "")
+		return
+	}
+
+	username := values.Get("username")
+	ctx, err := h.a.AuthenticatePassword(ctx, username, values.Get("password"))
+	if errors.Is(err, ratelimit.ErrLimited) {
+		h.renderLogin(w, req, http.StatusTooManyRequests, username, messageUnavailable)
+		return
+	}
+	if err != nil {
+		h.l.Error(ctx, "OIDC login failed", "error", err)
+		h.renderLogin(w, req, http.StatusServiceUnavailable, username, messageUnavailable)
+		return
+	}
+	if _, ok := ctx.Value(constants.UserContextKey).(*model.User); !ok {
+		h.renderLogin(w, req, http.StatusUnauthorized, username, messageInvalidCredentials)
+		return
+	}
+	if err := h.a.CheckPasswordRotation(ctx); err != nil {
+		h.renderLogin(w, req, http.StatusForbidden, username, messagePasswordRotation)
+		return
+	}
+
+	code, err := h.oc.IssueCode(ctx, &model.AuthorizationCode{
+		ClientID:      req.client.ID,
+		RedirectURI:   req.params.Get("redirect_uri"),
+		Scopes:        req.scopes,
+		Nonce:         req.params.Get("nonce"),
+		CodeChallenge: req.params.Get("code_challenge"),
+	})
+	if err != nil {
+		h.l.Error(ctx, "failed to issue authorization code", "error", err)
+		redirectError(w, r, req.params.Get("redirect_uri"), req.params.Get("state"), ErrorServerError, "failed to issue authorization code")
+		return
+	}
+
+	h.l.Info(ctx, "OIDC authorization code issued", "clientID", req.client.ID)
+
+	query := url.Values{"code": {code.Code}}
+	if state := req.params.Get("state"); state != "" {
+		query.Set("state", state)
+	}
+	http.Redirect(w, r, withQuery(code.RedirectURI, query), http.StatusFound)
+}
+
+func (h *Handler) parseAuthorizeRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, values url.Values) (*authorizeRequest, bool) {
+	params := url.Values{}
+	for _, name := range authorizeParams {
+		if value := values.Get(name); value != "" {
+			params.Set(name, value)
+		}
+	}
+
+	client, err := h.oc.GetClient(ctx, params.Get("client_id"))
+	if err != nil {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	redirectURI, state := params.Get("redirect_uri"), params.Get("state")
+	if !client.AllowsRedirectURI(redirectURI) {
+		http.Error(w, "redirect_uri is not registered for the client", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if params.Get("response_type") != "code" {
+		redirectError(w, r, redirectURI, state, ErrorUnsupportedResponseType, "only the authorization code flow is supported")
+		return nil, false
+	}
+
+	var scopes []string
+	for _, scope := range strings.Fields(params.Get("scope")) {
+		for _, supported := range supportedScopes {
+			if scope == supported {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	req := &authorizeRequest{client: client, params: params, scopes: scopes}
+	if !req.hasScope(model.ScopeOpenID) {
+		redirectError(w, r, redirectURI, state, ErrorInvalidScope, "the openid scope is required")
+		return nil, false
+	}
+
+	if params.Get("code_challenge") == "" || params.Get("code_challenge_method") != codeChallengeMethodS256 {
+		redirectError(w, r, redirectURI, state, ErrorInvalidRequest, "PKCE with the S256 method is required")
+		return nil, false
+	}
+
+	return req, true
+}
+
+func (h *Handler) renderLogin(w http.ResponseWriter, req *authorizeRequest, status int, username, message string) {
+	params := make(map[string]string, len(req.params))
+	for name := range req.params {
+		params[name] = req.params.Get(name)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+
+	h.login.Execute(w, &loginPage{
+		Action:     AuthorizePath,
+		ClientName: req.client.Name,
+		Params:     params,
+		Username:   username,
+		Error:      message,
+	})
+}
+
+func (r *authorizeRequest) hasScope(scope string) bool {
+	for _, s := range r.scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorInvalidToken            = "invalid_token"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorServerError             = "server_error"
+	ErrorTemporarilyUnavailable  = "temporarily_unavailable"
+)
+
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, &oauthError{Code: code, Description: description})
+}
+
+func writeBearerError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="`+code+`"`)
+	writeError(w, http.StatusUnauthorized, code, description)
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, code, description string) {
+	query := url.Values{"error": {code}, "error_description": {description}}
+	if state != "" {
+		query.Set("state", state)
+	}
+
+	http.Redirect(w, r, withQuery(redirectURI, query), http.StatusFound)
+}
+
+func withQuery(uri string, query url.Values) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	values := u.Query()
+	for key, v := range query {
+		values[key] = v
+	}
+	u.RawQuery = values.Encode()
+
+	return u.String()
+}
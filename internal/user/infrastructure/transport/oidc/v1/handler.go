@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/oidc"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+const (
+	DiscoveryPath = "/.well-known/openid-configuration"
+	Prefix        = "/oauth2"
+	AuthorizePath = Prefix + "/authorize"
+	TokenPath     = Prefix + "/token"
+	UserinfoPath  = Prefix + "/userinfo"
+	JWKSPath      = Prefix + "/jwks"
+
+	accessTokenType = "at+jwt"
+	idTokenType     = "JWT"
+
+	maxFormBytes = 1 << 16
+)
+
+var supportedScopes = []string{model.ScopeOpenID, model.ScopeProfile, model.ScopeEmail}
+
+//go:embed templates
+var templates embed.FS
+
+type Handler struct {
+	oc       *command.OIDC
+	ur       persistence.UserRepository
+	a        *auth.Authenticator
+	ks       *oidc.KeySet
+	l        deps.Logger
+	issuer   string
+	tokenTTL time.Duration
+	login    *template.Template
+}
+
+type discoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func NewHandler(oc *command.OIDC, ur persistence.UserRepository, a *auth.Authenticator, ks *oidc.KeySet, l deps.Logger, issuer string, tokenTTL time.Duration) *Handler {
+	return &Handler{
+		oc:       oc,
+		ur:       ur,
+		a:        a,
+		ks:       ks,
+		l:        l,
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		tokenTTL: tokenTTL,
+		login:    template.Must(template.ParseFS(templates, "templates/login.html")),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case DiscoveryPath:
+		h.allow(w, r, h.discovery, http.MethodGet)
+	case JWKSPath:
+		h.allow(w, r, h.jwks, http.MethodGet)
+	case AuthorizePath:
+		h.allow(w, r, h.authorize, http.MethodGet, http.MethodPost)
+	case TokenPath:
+		h.allow(w, r, h.token, http.MethodPost)
+	case UserinfoPath:
+		h.allow(w, r, h.userinfo, http.MethodGet, http.MethodPost)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) allow(w http.ResponseWriter, r *http.Request, handle http.HandlerFunc, methods ...string) {
+	for _, m := range methods {
+		if r.Method == m {
+			handle(w, r)
+			return
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+}
+
+func (h *Handler) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &discoveryDocument{
+		Issuer:                            h.issuer,
+		AuthorizationEndpoint:             h.issuer + AuthorizePath,
+		TokenEndpoint:                     h.issuer + TokenPath,
+		UserinfoEndpoint:                  h.issuer + UserinfoPath,
+		JWKSURI:                           h.issuer + JWKSPath,
+		ScopesSupported:                   supportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{string(oidc.Algorithm)},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "azp",
+			"preferred_username", "email", "admin", "permissions",
+		},
+	})
+}
+
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.ks.PublicKeys())
+}
+
+func requestContext(r *http.Request) context.Context {
+	return tracing.WithTraceID(r.Context())
+}
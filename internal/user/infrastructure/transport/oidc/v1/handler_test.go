@@ -0,0 +1,453 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	keyset "userCRUD/internal/user/infrastructure/oidc"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const testRedirectURI = "https://app.example.com/callback"
+
+type oidcTest struct {
+	server   *httptest.Server
+	ks       *keyset.KeySet
+	uc       *command.User
+	adminCtx context.Context
+	client   *model.OIDCClient
+	public   *model.OIDCClient
+	provider *oidc.Provider
+}
+
+type flow struct {
+	config   *oauth2.Config
+	verifier string
+	state    string
+	nonce    string
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	l := &deps.MockLogger{}
+	v := deps.NewGoPlaygroundValidator()
+	h := password.NewPool(bcrypt.MinCost, 4, 64)
+	ur := persistence.NewUserRepositoryMemory(l, h, 1000)
+	al := auditpersistence.NewAuditLogMemory()
+	pp := command.NewPasswordPolicy(5, 0)
+	uc := command.NewUserCommand(ur, v, h, pp, al)
+	oc := command.NewOIDCCommand(persistence.NewOIDCRepositoryMemory(), ur, al, v, time.Minute)
+
+	ks, err := keyset.NewKeySet(l, 0, time.Hour, "")
+	if err != nil {
+		t.Fatalf("Failed to create key set: %v", err)
+	}
+
+	admin, err := ur.GetUserByUsername(context.Background(), "admin")
+	if err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	if _, err := uc.CreateUser(adminCtx, &model.User{
+		Username:    "bjensen",
+		Email:       "bjensen@example.com",
+		Password:    "password",
+		Permissions: []model.Permission{model.PermissionImpersonate},
+	}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	client, err := oc.RegisterClient(adminCtx, &model.OIDCClient{Name: "Wiki", RedirectURIs: []string{testRedirectURI}})
+	if err != nil {
+		t.Fatalf("Failed to register client: %v", err)
+	}
+	public, err := oc.RegisterClient(adminCtx, &model.OIDCClient{Name: "CLI", RedirectURIs: []string{"http://127.0.0.1:9999/cb"}, Public: true})
+	if err != nil {
+		t.Fatalf("Failed to register client: %v", err)
+	}
+
+	server := httptest.NewServer(nil)
+	t.Cleanup(server.Close)
+	server.Config.Handler = NewHandler(oc, ur, auth.NewAuthenticator(ur, persistence.NewTokenRepositoryMemory(), pp), ks, l, server.URL, 10*time.Minute)
+
+	provider, err := oidc.NewProvider(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Failed to discover provider: %v", err)
+	}
+
+	return &oidcTest{
+		server:   server,
+		ks:       ks,
+		uc:       uc,
+		adminCtx: adminCtx,
+		client:   client,
+		public:   public,
+		provider: provider,
+	}
+}
+
+func (ot *oidcTest) newFlow(client *model.OIDCClient, scopes ...string) *flow {
+	return &flow{
+		config: &oauth2.Config{
+			ClientID:     client.ID,
+			ClientSecret: client.Secret,
+			Endpoint:     ot.provider.Endpoint(),
+			RedirectURL:  client.RedirectURIs[0],
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		verifier: oauth2.GenerateVerifier(),
+		state:    "state-123",
+		nonce:    "nonce-456",
+	}
+}
+
+func (f *flow) authURL() string {
+	return f.config.AuthCodeURL(f.state, oauth2.S256ChallengeOption(f.verifier), oidc.Nonce(f.nonce))
+}
+
+func noRedirect() *http.Client {
+	return &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+}
+
+func login(t *testing.T, authURL, username, password string) *http.Response {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Invalid auth URL: %v", err)
+	}
+
+	form := u.Query()
+	form.Set("username", username)
+	form.Set("password", password)
+
+	u.RawQuery = ""
+	resp, err := noRedirect().PostForm(u.String(), form)
+	if err != nil {
+		t.Fatalf("Login request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func (f *flow) authorize(t *testing.T, username, password string) string {
+	resp := login(t, f.authURL(), username, password)
+	if resp.StatusCode != http.StatusFound {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected redirect, got %d: %s", resp.StatusCode, body)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect: %v", err)
+	}
+	if location.Query().Get("state") != f.state {
+		t.Errorf("Expected state to round-trip, got %q", location.Query().Get("state"))
+	}
+
+	return location.Query().Get("code")
+}
+
+func redirectQuery(t *testing.T, resp *http.Response) url.Values {
+	t.Helper()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect, got %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect: %v", err)
+	}
+
+	return location.Query()
+}
+
+func oauthErrorCode(err error) string {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return retrieveErr.ErrorCode
+	}
+
+	return ""
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ot := newOIDCTest(t)
+	ctx := context.Background()
+	f := ot.newFlow(ot.client, model.ScopeProfile, model.ScopeEmail, oidc.ScopeOfflineAccess)
+
+	resp, err := http.Get(f.authURL())
+	if err != nil {
+		t.Fatalf("Failed to load login page: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Sign in to Wiki") || !strings.Contains(string(body), f.nonce) {
+		t.Fatalf("Unexpected login page %d: %s", resp.StatusCode, body)
+	}
+	if resp.Header.Get("X-Frame-Options") != "DENY" {
+		t.Error("Expected login page to forbid framing")
+	}
+
+	token, err := f.config.Exchange(ctx, f.authorize(t, "bjensen", "password"), oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+	if token.Extra("scope") != "openid profile email" {
+		t.Errorf("Expected unsupported scopes to be dropped, got %v", token.Extra("scope"))
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := ot.provider.Verifier(&oidc.Config{ClientID: ot.client.ID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		t.Fatalf("Failed to verify id token: %v", err)
+	}
+	if idToken.Nonce != f.nonce {
+		t.Errorf("Expected nonce %q, got %q", f.nonce, idToken.Nonce)
+	}
+	if err := idToken.VerifyAccessToken(token.AccessToken); err != nil {
+		t.Errorf("Expected at_hash to match: %v", err)
+	}
+
+	var claims struct {
+		PreferredUsername string   `json:"preferred_username"`
+		Email             string   `json:"email"`
+		Admin             bool     `json:"admin"`
+		Permissions       []string `json:"permissions"`
+		AuthTime          int64    `json:"auth_time"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		t.Fatalf("Failed to decode claims: %v", err)
+	}
+	if claims.PreferredUsername != "bjensen" || claims.Email != "bjensen@example.com" || claims.Admin ||
+		len(claims.Permissions) != 1 || claims.Permissions[0] != string(model.PermissionImpersonate) || claims.AuthTime == 0 {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+
+	userInfo, err := ot.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err != nil {
+		t.Fatalf("Failed to fetch userinfo: %v", err)
+	}
+	if userInfo.Subject != idToken.Subject || userInfo.Email != "bjensen@example.com" {
+		t.Errorf("Unexpected userinfo %+v", userInfo)
+	}
+}
+
+func TestScopesLimitClaims(t *testing.T) {
+	ot := newOIDCTest(t)
+	ctx := context.Background()
+	f := ot.newFlow(ot.client)
+
+	token, err := f.config.Exchange(ctx, f.authorize(t, "bjensen", "password"), oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+
+	idToken, err := ot.provider.Verifier(&oidc.Config{ClientID: ot.client.ID}).Verify(ctx, token.Extra("id_token").(string))
+	if err != nil {
+		t.Fatalf("Failed to verify id token: %v", err)
+	}
+
+	var claims map[string]interface{}
+	idToken.Claims(&claims)
+	for _, claim := range []string{"preferred_username", "email", "permissions"} {
+		if _, ok := claims[claim]; ok {
+			t.Errorf("Expected %s to require its scope", claim)
+		}
+	}
+}
+
+func TestTokenEndpointRejectsInvalidGrants(t *testing.T) {
+	ot := newOIDCTest(t)
+	ctx := context.Background()
+	f := ot.newFlow(ot.client)
+
+	code := f.authorize(t, "bjensen", "password")
+	if _, err := f.config.Exchange(ctx, code, oauth2.VerifierOption("wrong-verifier-wrong-verifier-wrong-verifier")); oauthErrorCode(err) != ErrorInvalidGrant {
+		t.Errorf("Expected invalid_grant for PKCE mismatch, got %v", err)
+	}
+	if _, err := f.config.Exchange(ctx, code, oauth2.VerifierOption(f.verifier)); oauthErrorCode(err) != ErrorInvalidGrant {
+		t.Errorf("Expected the code to be consumed by the failed attempt, got %v", err)
+	}
+
+	code = f.authorize(t, "bjensen", "password")
+	if _, err := f.config.Exchange(ctx, code, oauth2.VerifierOption(f.verifier)); err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+	if _, err := f.config.Exchange(ctx, code, oauth2.VerifierOption(f.verifier)); oauthErrorCode(err) != ErrorInvalidGrant {
+		t.Errorf("Expected code reuse to fail, got %v", err)
+	}
+
+	code = f.authorize(t, "bjensen", "password")
+	wrongSecret := *f.config
+	wrongSecret.ClientSecret = "wrong"
+	if _, err := wrongSecret.Exchange(ctx, code, oauth2.VerifierOption(f.verifier)); oauthErrorCode(err) != ErrorInvalidClient {
+		t.Errorf("Expected invalid_client, got %v", err)
+	}
+
+	other := ot.newFlow(ot.public)
+	other.config.RedirectURL = testRedirectURI
+	if _, err := other.config.Exchange(ctx, code, oauth2.VerifierOption(f.verifier)); oauthErrorCode(err) != ErrorInvalidGrant {
+		t.Errorf("Expected a code issued to another client to be rejected, got %v", err)
+	}
+}
+
+func TestPublicClient(t *testing.T) {
+	ot := newOIDCTest(t)
+	ctx := context.Background()
+	f := ot.newFlow(ot.public)
+
+	token, err := f.config.Exchange(ctx, f.authorize(t, "bjensen", "password"), oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+	if _, err := ot.provider.Verifier(&oidc.Config{ClientID: ot.public.ID}).Verify(ctx, token.Extra("id_token").(string)); err != nil {
+		t.Errorf("Failed to verify id token: %v", err)
+	}
+}
+
+func TestAuthorizeErrors(t *testing.T) {
+	ot := newOIDCTest(t)
+	f := ot.newFlow(ot.client)
+
+	for name, mutate := range map[string]func(url.Values){
+		"unknown client":        func(q url.Values) { q.Set("client_id", "unknown") },
+		"unregistered redirect": func(q url.Values) { q.Set("redirect_uri", "https://evil.example.com/callback") },
+	} {
+		u, _ := url.Parse(f.authURL())
+		q := u.Query()
+		mutate(q)
+		u.RawQuery = q.Encode()
+
+		resp, err := noRedirect().Get(u.String())
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 without redirect, got %d", name, resp.StatusCode)
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		mutate func(url.Values)
+		code   string
+	}{
+		"missing pkce":  {func(q url.Values) { q.Del("code_challenge") }, ErrorInvalidRequest},
+		"plain pkce":    {func(q url.Values) { q.Set("code_challenge_method", "plain") }, ErrorInvalidRequest},
+		"missing scope": {func(q url.Values) { q.Set("scope", "profile") }, ErrorInvalidScope},
+		"implicit flow": {func(q url.Values) { q.Set("response_type", "token") }, ErrorUnsupportedResponseType},
+	} {
+		u, _ := url.Parse(f.authURL())
+		q := u.Query()
+		tt.mutate(q)
+		u.RawQuery = q.Encode()
+
+		resp, err := noRedirect().Get(u.String())
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		resp.Body.Close()
+
+		query := redirectQuery(t, resp)
+		if query.Get("error") != tt.code || query.Get("state") != f.state {
+			t.Errorf("%s: expected error %s, got %v", name, tt.code, query)
+		}
+	}
+
+	if resp := login(t, f.authURL(), "bjensen", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the login form to be shown again, got %d", resp.StatusCode)
+	}
+}
+
+func TestPasswordRotationBlocksLogin(t *testing.T) {
+	ot := newOIDCTest(t)
+
+	user, err := ot.uc.CreateUser(ot.adminCtx, &model.User{Username: "rotated", Email: "rotated@example.com", Password: "password", MustChangePassword: true})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if !user.MustChangePassword {
+		t.Fatal("Expected rotation to be required")
+	}
+
+	if resp := login(t, ot.newFlow(ot.client).authURL(), "rotated", "password"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestUserinfoRejectsInvalidTokens(t *testing.T) {
+	ot := newOIDCTest(t)
+	ctx := context.Background()
+	f := ot.newFlow(ot.client, model.ScopeEmail)
+
+	token, err := f.config.Exchange(ctx, f.authorize(t, "bjensen", "password"), oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+
+	for name, bearer := range map[string]string{
+		"id token":  token.Extra("id_token").(string),
+		"tampered":  token.AccessToken[:len(token.AccessToken)-2] + "xx",
+		"malformed": "not-a-jwt",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, ot.server.URL+UserinfoPath, nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(resp.Header.Get("WWW-Authenticate"), ErrorInvalidToken) {
+			t.Errorf("%s: expected invalid_token, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ot := newOIDCTest(t)
+	ctx := context.Background()
+	verifier := ot.provider.Verifier(&oidc.Config{ClientID: ot.client.ID})
+	f := ot.newFlow(ot.client)
+
+	before, err := f.config.Exchange(ctx, f.authorize(t, "bjensen", "password"), oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, before.Extra("id_token").(string)); err != nil {
+		t.Fatalf("Failed to verify id token: %v", err)
+	}
+
+	if err := ot.ks.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate keys: %v", err)
+	}
+
+	after, err := f.config.Exchange(ctx, f.authorize(t, "bjensen", "password"), oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+	for name, token := range map[string]*oauth2.Token{"before rotation": before, "after rotation": after} {
+		if _, err := verifier.Verify(ctx, token.Extra("id_token").(string)); err != nil {
+			t.Errorf("%s: failed to verify id token: %v", name, err)
+		}
+		if _, err := ot.provider.UserInfo(ctx, oauth2.StaticTokenSource(token)); err != nil {
+			t.Errorf("%s: failed to fetch userinfo: %v", name, err)
+		}
+	}
+}
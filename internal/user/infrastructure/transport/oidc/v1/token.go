@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+)
+
+const grantTypeAuthorizationCode = "authorization_code"
+
+type userClaims struct {
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	Admin             bool     `json:"admin,omitempty"`
+	Permissions       []string `json:"permissions,omitempty"`
+}
+
+type idTokenClaims struct {
+	jwt.Claims
+	userClaims
+	AuthorizedParty string `json:"azp"`
+	AuthTime        int64  `json:"auth_time"`
+	Nonce           string `json:"nonce,omitempty"`
+	AccessTokenHash string `json:"at_hash"`
+}
+
+type accessTokenClaims struct {
+	jwt.Claims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+func (h *Handler) token(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "invalid form body")
+		return
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	client, err := h.oc.AuthenticateClient(ctx, clientID, secret)
+	if err != nil {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+		}
+		writeError(w, http.StatusUnauthorized, ErrorInvalidClient, err.Error())
+		return
+	}
+
+	if grantType := r.PostForm.Get("grant_type"); grantType != grantTypeAuthorizationCode {
+		writeError(w, http.StatusBadRequest, ErrorUnsupportedGrantType, "only the authorization_code grant is supported")
+		return
+	}
+
+	code, user, err := h.oc.RedeemCode(ctx, client, r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+	if err != nil {
+		if errors.Is(err, command.ErrInvalidGrant) {
+			writeError(w, http.StatusBadRequest, ErrorInvalidGrant, err.Error())
+			return
+		}
+		h.l.Error(ctx, "failed to redeem authorization code", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "failed to redeem authorization code")
+		return
+	}
+
+	now := time.Now()
+	scope := strings.Join(code.Scopes, " ")
+	accessToken, err := h.ks.Sign(&accessTokenClaims{
+		Claims:   h.registeredClaims(user.ID, h.issuer, now),
+		ClientID: client.ID,
+		Scope:    scope,
+	}, accessTokenType)
+	if err != nil {
+		h.l.Error(ctx, "failed to sign access token", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "failed to issue tokens")
+		return
+	}
+
+	idToken, err := h.ks.Sign(&idTokenClaims{
+		Claims:          h.registeredClaims(user.ID, client.ID, now),
+		userClaims:      claimsFor(user, code.Scopes),
+		AuthorizedParty: client.ID,
+		AuthTime:        code.AuthTime.Unix(),
+		Nonce:           code.Nonce,
+		AccessTokenHash: accessTokenHash(accessToken),
+	}, idTokenType)
+	if err != nil {
+		h.l.Error(ctx, "failed to sign id token", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "failed to issue tokens")
+		return
+	}
+
+	h.l.Info(ctx, "OIDC tokens issued", "clientID", client.ID, "userID", user.ID)
+
+	writeJSON(w, http.StatusOK, &tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(h.tokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       scope,
+	})
+}
+
+func (h *Handler) registeredClaims(subject, audience string, now time.Time) jwt.Claims {
+	return jwt.Claims{
+		ID:       uuid.New().String(),
+		Issuer:   h.issuer,
+		Subject:  subject,
+		Audience: jwt.Audience{audience},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(h.tokenTTL)),
+	}
+}
+
+func claimsFor(user *model.User, scopes []string) userClaims {
+	var claims userClaims
+
+	for _, scope := range scopes {
+		switch scope {
+		case model.ScopeProfile:
+			claims.PreferredUsername = user.Username
+			claims.Admin = user.Admin
+			for _, p := range user.Permissions {
+				claims.Permissions = append(claims.Permissions, string(p))
+			}
+		case model.ScopeEmail:
+			claims.Email = user.Email
+		}
+	}
+
+	return claims
+}
+
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"github.com/go-jose/go-jose/v3/jwt"
+	"net/http"
+	"strings"
+	"time"
+	"userCRUD/internal/user/infrastructure/auth"
+)
+
+type userinfoResponse struct {
+	Subject string `json:"sub"`
+	userClaims
+}
+
+func (h *Handler) userinfo(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, auth.BearerPrefix) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, ErrorInvalidRequest, "bearer access token required")
+		return
+	}
+
+	var claims accessTokenClaims
+	if err := h.ks.Verify(strings.TrimPrefix(header, auth.BearerPrefix), accessTokenType, &claims); err != nil {
+		writeBearerError(w, ErrorInvalidToken, "access token is invalid")
+		return
+	}
+	if err := claims.Validate(jwt.Expected{Issuer: h.issuer, Audience: jwt.Audience{h.issuer}, Time: time.Now()}); err != nil {
+		writeBearerError(w, ErrorInvalidToken, err.Error())
+		return
+	}
+
+	user, err := h.ur.GetUserByID(ctx, claims.Subject)
+	if err != nil {
+		writeBearerError(w, ErrorInvalidToken, "user no longer exists")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &userinfoResponse{
+		Subject:    user.ID,
+		userClaims: claimsFor(user, strings.Fields(claims.Scope)),
+	})
+}
@@ -0,0 +1,272 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	redactedValue  = "[REDACTED]"
+	defaultSampler = "*"
+)
+
+var (
+	ErrInvalidSampling = errors.New("access log sampling must be a comma-separated list of method=rate with rates in [0, 1]")
+)
+
+type accessLogKey struct{}
+
+type accessEntry struct {
+	caller *model.User
+	actor  *model.User
+}
+
+type AccessLog struct {
+	l        deps.Logger
+	payloads bool
+	sampling map[string]float64
+}
+
+type countingStream struct {
+	grpc.ServerStream
+	ctx           context.Context
+	received      int
+	sent          int
+	receivedBytes int
+	sentBytes     int
+}
+
+func (c *countingStream) Context() context.Context {
+	return c.ctx
+}
+
+func (c *countingStream) RecvMsg(m interface{}) error {
+	err := c.ServerStream.RecvMsg(m)
+	if err == nil {
+		c.received++
+		c.receivedBytes += messageSize(m)
+	}
+
+	return err
+}
+
+func (c *countingStream) SendMsg(m interface{}) error {
+	err := c.ServerStream.SendMsg(m)
+	if err == nil {
+		c.sent++
+		c.sentBytes += messageSize(m)
+	}
+
+	return err
+}
+
+func NewAccessLog(l deps.Logger, payloads bool, sampling map[string]float64) *AccessLog {
+	return &AccessLog{
+		l:        l,
+		payloads: payloads,
+		sampling: sampling,
+	}
+}
+
+func ParseSampling(spec string) (map[string]float64, error) {
+	sampling := make(map[string]float64)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		method, value, ok := strings.Cut(item, "=")
+		rate, err := strconv.ParseFloat(value, 64)
+		if !ok || method == "" || err != nil || rate < 0 || rate > 1 {
+			return nil, ErrInvalidSampling
+		}
+		if method != defaultSampler && !strings.HasPrefix(method, "/") {
+			method = "/" + pb.UserService_ServiceDesc.ServiceName + "/" + method
+		}
+		sampling[method] = rate
+	}
+
+	return sampling, nil
+}
+
+func (a *AccessLog) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	entry := &accessEntry{}
+	resp, err := handler(context.WithValue(ctx, accessLogKey{}, entry), req)
+
+	if !a.sampled(info.FullMethod, err) {
+		return resp, err
+	}
+
+	fields := a.fields(ctx, entry, info.FullMethod, start, err)
+	fields = append(fields, "request_bytes", messageSize(req), "response_bytes", messageSize(resp))
+	if a.payloads {
+		fields = append(fields, "request", redactedPayload(req))
+		if err == nil {
+			fields = append(fields, "response", redactedPayload(resp))
+		}
+	}
+	a.l.Info(ctx, "gRPC call", fields...)
+
+	return resp, err
+}
+
+func (a *AccessLog) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	entry := &accessEntry{}
+	stream := &countingStream{ServerStream: ss, ctx: context.WithValue(ss.Context(), accessLogKey{}, entry)}
+	err := handler(srv, stream)
+
+	if !a.sampled(info.FullMethod, err) {
+		return err
+	}
+
+	fields := a.fields(ss.Context(), entry, info.FullMethod, start, err)
+	fields = append(fields,
+		"request_messages", stream.received,
+		"request_bytes", stream.receivedBytes,
+		"response_messages", stream.sent,
+		"response_bytes", stream.sentBytes,
+	)
+	a.l.Info(ss.Context(), "gRPC stream", fields...)
+
+	return err
+}
+
+func (a *AccessLog) sampled(method string, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	rate, ok := a.sampling[method]
+	if !ok {
+		if rate, ok = a.sampling[defaultSampler]; !ok {
+			return true
+		}
+	}
+
+	return rate >= 1 || rand.Float64() < rate
+}
+
+func (a *AccessLog) fields(ctx context.Context, entry *accessEntry, method string, start time.Time, err error) []interface{} {
+	peer, _ := ctx.Value(constants.PeerAddr).(string)
+	fields := []interface{}{
+		"method", method,
+		"peer", peer,
+		"code", status.Code(err).String(),
+		"duration", time.Since(start),
+	}
+
+	if entry.caller == nil {
+		fields = append(fields, "user", "anonymous")
+	} else {
+		fields = append(fields, "user", entry.caller.Username, "user_id", entry.caller.ID)
+	}
+	if entry.actor != nil {
+		fields = append(fields, "actor", entry.actor.Username, "actor_id", entry.actor.ID)
+	}
+	if err != nil {
+		fields = append(fields, "error", status.Convert(err).Message())
+	}
+
+	return fields
+}
+
+func recordCaller(ctx context.Context) {
+	entry, ok := ctx.Value(accessLogKey{}).(*accessEntry)
+	if !ok {
+		return
+	}
+
+	entry.caller, _ = ctx.Value(constants.UserContextKey).(*model.User)
+	entry.actor, _ = ctx.Value(constants.ActorContextKey).(*model.User)
+}
+
+func messageSize(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+
+	return 0
+}
+
+func redactedPayload(m interface{}) interface{} {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	clone := proto.Clone(msg)
+	redact(clone.ProtoReflect())
+
+	var payload interface{}
+	body, err := protojson.Marshal(clone)
+	if err != nil || json.Unmarshal(body, &payload) != nil {
+		return nil
+	}
+
+	return payload
+}
+
+func redact(m protoreflect.Message) {
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case isSensitive(fd):
+			sensitive = append(sensitive, fd)
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redact(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+				redact(value.Message())
+				return true
+			})
+		case fd.Message() != nil && !fd.IsMap():
+			redact(v.Message())
+		}
+
+		return true
+	})
+
+	for _, fd := range sensitive {
+		switch {
+		case fd.IsList() || fd.IsMap():
+			m.Clear(fd)
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(redactedValue))
+		case fd.Kind() == protoreflect.BytesKind:
+			m.Set(fd, protoreflect.ValueOfBytes([]byte(redactedValue)))
+		default:
+			m.Clear(fd)
+		}
+	}
+}
+
+func isSensitive(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+
+	sensitive, _ := proto.GetExtension(opts, pb.E_Sensitive).(bool)
+	return sensitive
+}
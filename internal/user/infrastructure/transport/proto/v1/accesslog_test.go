@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+)
+
+type logRecord struct {
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordingLogger struct {
+	records []logRecord
+}
+
+func (l *recordingLogger) Info(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+
+func (l *recordingLogger) Debug(ctx context.Context, msg string, keysAndValues ...interface{}) {}
+
+func (l *recordingLogger) Error(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+
+func (l *recordingLogger) record(msg string, keysAndValues []interface{}) {
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.records = append(l.records, logRecord{msg: msg, fields: fields})
+}
+
+func payloadString(payload interface{}) string {
+	body, _ := json.Marshal(payload)
+	return string(body)
+}
+
+func TestAccessLogInterceptor(t *testing.T) {
+	l := &recordingLogger{}
+	al := NewAccessLog(l, true, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_NewUser_FullMethodName}
+	caller := &model.User{ID: "caller-id", Username: "root"}
+
+	req := &pb.NewUserRequest{Username: "alice", Email: "alice@example.com", Password: "s3cret-pass"}
+	ctx := context.WithValue(context.Background(), constants.PeerAddr, "10.0.0.7")
+	_, err := al.Interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		recordCaller(auth.WithIdentity(ctx, caller, nil))
+		return &pb.UserResponse{Id: "new-id", Username: "alice"}, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(l.records) != 1 {
+		t.Fatalf("Expected 1 access log record, got %d", len(l.records))
+	}
+	fields := l.records[0].fields
+	expected := map[string]interface{}{
+		"method":  pb.UserService_NewUser_FullMethodName,
+		"peer":    "10.0.0.7",
+		"code":    codes.OK.String(),
+		"user":    "root",
+		"user_id": "caller-id",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, fields[key])
+		}
+	}
+	if size, _ := fields["request_bytes"].(int); size == 0 {
+		t.Error("Expected a non-zero request size")
+	}
+
+	request := payloadString(fields["request"])
+	if strings.Contains(request, "s3cret-pass") || !strings.Contains(request, redactedValue) || !strings.Contains(request, "alice") {
+		t.Errorf("Expected the password to be redacted, got %s", request)
+	}
+	if req.Password != "s3cret-pass" {
+		t.Error("Expected the original request to be left untouched")
+	}
+	if response := payloadString(fields["response"]); !strings.Contains(response, "new-id") {
+		t.Errorf("Expected the response payload, got %s", response)
+	}
+}
+
+func TestRedactNested(t *testing.T) {
+	redacted := payloadString(redactedPayload(&pb.ExportUsersResponse{
+		Users: []*pb.ExportedUser{{Username: "alice", PasswordHash: "$2a$hash"}},
+	}))
+	if strings.Contains(redacted, "$2a$hash") || !strings.Contains(redacted, "alice") {
+		t.Errorf("Expected nested password hashes to be redacted, got %s", redacted)
+	}
+
+	redacted = payloadString(redactedPayload(&pb.ImportUsersRequest{
+		Users: []*pb.NewUserRequest{{Username: "bob", Password: "bobs-password"}},
+	}))
+	if strings.Contains(redacted, "bobs-password") {
+		t.Errorf("Expected imported passwords to be redacted, got %s", redacted)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	sampling, err := ParseSampling("GetUserByID=0, /grpc.health.v1.Health/Check=0.5")
+	if err != nil {
+		t.Fatalf("Failed to parse sampling: %v", err)
+	}
+	if sampling[pb.UserService_GetUserByID_FullMethodName] != 0 || sampling["/grpc.health.v1.Health/Check"] != 0.5 {
+		t.Errorf("Unexpected sampling: %v", sampling)
+	}
+	for _, spec := range []string{"GetUserByID", "GetUserByID=2", "=0.1", "GetUserByID=x"} {
+		if _, err := ParseSampling(spec); !errors.Is(err, ErrInvalidSampling) {
+			t.Errorf("Expected ErrInvalidSampling for %q, got %v", spec, err)
+		}
+	}
+
+	l := &recordingLogger{}
+	al := NewAccessLog(l, false, sampling)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_GetUserByID_FullMethodName}
+
+	al.Interceptor(context.Background(), &pb.GetUserByIDRequest{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.UserResponse{}, nil
+	})
+	if len(l.records) != 0 {
+		t.Fatalf("Expected successful calls to be sampled out, got %d records", len(l.records))
+	}
+
+	al.Interceptor(context.Background(), &pb.GetUserByIDRequest{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	if len(l.records) != 1 || l.records[0].fields["user"] != "anonymous" || l.records[0].fields["code"] != codes.NotFound.String() {
+		t.Fatalf("Expected failed calls to always be logged, got %v", l.records)
+	}
+	if _, ok := l.records[0].fields["request"]; ok {
+		t.Error("Expected no payloads when payload logging is disabled")
+	}
+}
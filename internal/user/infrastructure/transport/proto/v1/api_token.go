@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "userCRUD/api/proto"
+)
+
+func (s *Server) IssueAPIToken(ctx context.Context, req *pb.IssueAPITokenRequest) (*pb.IssueAPITokenResponse, error) {
+	token, err := s.tc.IssueAPIToken(ctx, req.Ttl.AsDuration())
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return &pb.IssueAPITokenResponse{
+		Token:     token.Value,
+		ExpiresAt: timestamppb.New(token.ExpiresAt),
+	}, nil
+}
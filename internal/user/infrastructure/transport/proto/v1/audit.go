@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "userCRUD/api/proto"
+	auditmodel "userCRUD/internal/audit/domain/model"
+)
+
+func (s *Server) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	filter := &auditmodel.Filter{
+		ActorID:       req.ActorId,
+		TargetID:      req.TargetId,
+		AfterSequence: req.AfterSequence,
+		Limit:         int(req.Limit),
+	}
+	if req.From != nil {
+		filter.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		filter.To = req.To.AsTime()
+	}
+
+	events, err := s.uc.ListAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	eventsResp := make([]*pb.AuditEvent, len(events))
+	for i, e := range events {
+		changes := make([]*pb.AuditChange, len(e.Changes))
+		for j, c := range e.Changes {
+			changes[j] = &pb.AuditChange{
+				Field:  c.Field,
+				Before: c.Before,
+				After:  c.After,
+			}
+		}
+
+		eventsResp[i] = &pb.AuditEvent{
+			Sequence:       e.Sequence,
+			Timestamp:      timestamppb.New(e.Timestamp),
+			ActorId:        e.ActorID,
+			ImpersonatorId: e.ImpersonatorID,
+			Action:         e.Action,
+			TargetId:       e.TargetID,
+			Changes:        changes,
+			TraceId:        e.TraceID,
+			PeerAddr:       e.PeerAddr,
+			PrevHash:       e.PrevHash,
+			Hash:           e.Hash,
+		}
+	}
+
+	return &pb.ListAuditEventsResponse{
+		Events: eventsResp,
+	}, nil
+}
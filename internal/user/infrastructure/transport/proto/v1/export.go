@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	defaultExportChunkSize = 100
+	maxExportChunkSize     = 1000
+
+	passwordHashField protoreflect.Name = "password_hash"
+)
+
+func (s *Server) ExportUsers(req *pb.ExportUsersRequest, stream pb.UserService_ExportUsersServer) error {
+	fields, err := exportFields(req.Fields, req.IncludePasswordHash)
+	if err != nil {
+		return err
+	}
+
+	chunkSize := int(req.ChunkSize)
+	if chunkSize == 0 {
+		chunkSize = defaultExportChunkSize
+	}
+	if chunkSize > maxExportChunkSize {
+		return status.Errorf(codes.InvalidArgument, "chunk_size must not exceed %d", maxExportChunkSize)
+	}
+
+	users, revision, err := s.uc.ExportUsers(stream.Context(), req.IncludePasswordHash)
+	if err != nil {
+		return handleGRPCError(err)
+	}
+
+	for start := 0; start == 0 || start < len(users); start += chunkSize {
+		end := start + chunkSize
+		if end > len(users) {
+			end = len(users)
+		}
+
+		chunk := make([]*pb.ExportedUser, 0, end-start)
+		for _, u := range users[start:end] {
+			chunk = append(chunk, toExportedUser(u, fields))
+		}
+
+		err := stream.Send(&pb.ExportUsersResponse{
+			Revision: revision,
+			Users:    chunk,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exportFields(requested []string, includePasswordHash bool) (map[protoreflect.Name]bool, error) {
+	descriptors := (&pb.ExportedUser{}).ProtoReflect().Descriptor().Fields()
+	fields := make(map[protoreflect.Name]bool, descriptors.Len())
+
+	if len(requested) == 0 {
+		for i := 0; i < descriptors.Len(); i++ {
+			fields[descriptors.Get(i).Name()] = true
+		}
+	}
+
+	for _, name := range requested {
+		fd := descriptors.ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown export field %q", name)
+		}
+		if fd.Name() == passwordHashField && !includePasswordHash {
+			return nil, status.Errorf(codes.InvalidArgument, "%s requires include_password_hash", passwordHashField)
+		}
+		fields[fd.Name()] = true
+	}
+
+	fields[passwordHashField] = includePasswordHash
+
+	return fields, nil
+}
+
+func toExportedUser(user *model.User, fields map[protoreflect.Name]bool) *pb.ExportedUser {
+	exported := &pb.ExportedUser{
+		Id:                 user.ID,
+		Email:              user.Email,
+		Username:           user.Username,
+		Admin:              user.Admin,
+		Permissions:        fromPermissions(user.Permissions),
+		MustChangePassword: user.MustChangePassword,
+		PasswordHash:       user.Password,
+	}
+	if !user.PasswordChangedAt.IsZero() {
+		exported.PasswordChangedAt = timestamppb.New(user.PasswordChangedAt)
+	}
+
+	m := exported.ProtoReflect()
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !fields[fd.Name()] {
+			m.Clear(fd)
+		}
+		return true
+	})
+
+	return exported
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+)
+
+func exportUsers(ctx context.Context, client pb.UserServiceClient, req *pb.ExportUsersRequest) ([]*pb.ExportUsersResponse, error) {
+	stream, err := client.ExportUsers(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*pb.ExportUsersResponse
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestExportUsers(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	adminCtx := basicAuth(ctx, "admin", "admin")
+
+	for i := 0; i < 4; i++ {
+		_, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{
+			Email:    fmt.Sprintf("export%d@example.com", i),
+			Username: fmt.Sprintf("exportUser%d", i),
+			Password: "password",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	chunks, err := exportUsers(adminCtx, ts.client, &pb.ExportUsersRequest{Fields: []string{"id", "email"}, ChunkSize: 2})
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 5 users in 3 chunks, got %d chunks", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if chunk.Revision != chunks[0].Revision {
+			t.Errorf("All chunks must share the snapshot revision")
+		}
+		for _, u := range chunk.Users {
+			if u.Id == "" || u.Email == "" || u.Username != "" || u.PasswordHash != "" {
+				t.Errorf("Expected only id and email, got %+v", u)
+			}
+		}
+	}
+
+	_, err = exportUsers(adminCtx, ts.client, &pb.ExportUsersRequest{IncludePasswordHash: true})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied without users.export_secrets, got %v", err)
+	}
+
+	_, err = exportUsers(adminCtx, ts.client, &pb.ExportUsersRequest{Fields: []string{"password"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown field, got %v", err)
+	}
+
+	_, err = ts.client.NewUser(adminCtx, &pb.NewUserRequest{
+		Email:       "backup@example.com",
+		Username:    "backupAdmin",
+		Password:    "password",
+		Admin:       true,
+		Permissions: []string{"users.export_secrets"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create backup admin: %v", err)
+	}
+
+	chunks, err = exportUsers(basicAuth(ctx, "backupAdmin", "password"), ts.client, &pb.ExportUsersRequest{IncludePasswordHash: true})
+	if err != nil {
+		t.Fatalf("Failed to export with secrets: %v", err)
+	}
+	if len(chunks) != 1 || len(chunks[0].Users) != 6 || chunks[0].Users[0].PasswordHash == "" {
+		t.Errorf("Expected password hashes for all users, got %+v", chunks)
+	}
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/domain/model"
+)
+
+func (s *Server) LinkExternalIdentity(ctx context.Context, req *pb.LinkExternalIdentityRequest) (*pb.ExternalIdentity, error) {
+	identity, err := s.fc.LinkIdentity(ctx, &model.ExternalIdentity{
+		Provider: req.Provider,
+		Subject:  req.Subject,
+		UserID:   req.UserId,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return toExternalIdentityResponse(identity), nil
+}
+
+func (s *Server) UnlinkExternalIdentity(ctx context.Context, req *pb.UnlinkExternalIdentityRequest) (*pb.UnlinkExternalIdentityResponse, error) {
+	err := s.fc.UnlinkIdentity(ctx, &model.ExternalIdentityRef{
+		Provider: req.Provider,
+		Subject:  req.Subject,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return &pb.UnlinkExternalIdentityResponse{}, nil
+}
+
+func (s *Server) ListExternalIdentities(ctx context.Context, req *pb.ListExternalIdentitiesRequest) (*pb.ListExternalIdentitiesResponse, error) {
+	identities, err := s.fc.ListIdentities(ctx, &model.UserByID{
+		ID: req.UserId,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	identitiesResp := make([]*pb.ExternalIdentity, len(identities))
+	for i, identity := range identities {
+		identitiesResp[i] = toExternalIdentityResponse(identity)
+	}
+
+	return &pb.ListExternalIdentitiesResponse{
+		Identities: identitiesResp,
+	}, nil
+}
+
+func toExternalIdentityResponse(identity *model.ExternalIdentity) *pb.ExternalIdentity {
+	return &pb.ExternalIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UserId:   identity.UserID,
+		LinkedAt: timestamppb.New(identity.LinkedAt),
+	}
+}
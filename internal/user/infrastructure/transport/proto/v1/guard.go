@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/model"
+)
+
+type Guard struct {
+	al  *AccessLog
+	rec *Recovery
+	ls  *LoadShedder
+	rl  *RateLimiter
+}
+
+func NewGuard(al *AccessLog, rec *Recovery, ls *LoadShedder, rl *RateLimiter) *Guard {
+	return &Guard{
+		al:  al,
+		rec: rec,
+		ls:  ls,
+		rl:  rl,
+	}
+}
+
+func (g *Guard) Run(ctx context.Context, method string, read bool, fn func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	defer func() {
+		g.log(ctx, method, start, err)
+	}()
+
+	if retryAfter, limited := g.rl.limited(ctx, method, ratelimit.ClassAnonymous, peerHost(ctx)); limited {
+		return rateLimitError(retryAfter)
+	}
+	if class, id, ok := callerClass(ctx); ok {
+		if retryAfter, limited := g.rl.limited(ctx, method, class, id); limited {
+			return rateLimitError(retryAfter)
+		}
+	}
+	ctx = ratelimit.WithCheck(ctx, func(ctx context.Context, class, id string) error {
+		if _, limited := g.rl.limited(ctx, method, class, id); limited {
+			return ratelimit.ErrLimited
+		}
+		return nil
+	})
+
+	priority := loadshed.PriorityMutation
+	if read {
+		priority = loadshed.PriorityRead
+	}
+	slot, err := g.ls.acquire(priority)
+	if err != nil {
+		return shed(method, priority)
+	}
+	if slot != nil {
+		ctx = loadshed.WithSlot(ctx, slot)
+	}
+
+	panicked := true
+	defer func() {
+		if slot != nil {
+			slot.Release(panicked || overloaded(err))
+		}
+		if p := recover(); p != nil {
+			err = g.rec.recovered(ctx, method, p, nil)
+		}
+	}()
+
+	err = fn(ctx)
+	panicked = false
+
+	return err
+}
+
+func (g *Guard) log(ctx context.Context, method string, start time.Time, err error) {
+	if !g.al.sampled(method, err) {
+		return
+	}
+
+	entry := &accessEntry{}
+	entry.caller, _ = ctx.Value(constants.UserContextKey).(*model.User)
+	fields := g.al.fields(ctx, entry, method, start, err)
+	g.al.l.Info(ctx, strings.ToUpper(strings.TrimPrefix(path.Dir(method), "/"))+" call", fields...)
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/model"
+)
+
+func TestGuard(t *testing.T) {
+	limits, err := ratelimit.ParseLimits("Bind.anonymous=1/m")
+	if err != nil {
+		t.Fatalf("Failed to parse limits: %v", err)
+	}
+	l := &recordingLogger{}
+	limiter := loadshed.NewLimiter(1, 1, 1, time.Minute, time.Minute, nil)
+	g := NewGuard(NewAccessLog(l, false, nil), NewRecovery(l, ""), NewLoadShedder(limiter), NewRateLimiter(limits, ratelimit.NewMemoryStore(), l))
+	ctx := context.WithValue(context.Background(), constants.PeerAddr, "10.0.0.1:389")
+	ok := func(ctx context.Context) error { return nil }
+
+	if err := g.Run(ctx, "/ldap/Bind", false, ok); err != nil {
+		t.Fatalf("Expected the first bind to pass, got %v", err)
+	}
+	if err := g.Run(ctx, "/ldap/Bind", false, ok); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected the peer to be rate limited, got %v", err)
+	}
+	if len(l.records) == 0 || l.records[len(l.records)-1].msg != "LDAP call" {
+		t.Errorf("Expected calls to be access logged, got %+v", l.records)
+	}
+
+	err = g.Run(ctx, "/http/SCIM", true, func(ctx context.Context) error {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal for a panicking handler, got %v", err)
+	}
+	if !limiter.Allow(loadshed.PriorityRead) {
+		t.Error("Expected the slot to be released after a panic")
+	}
+
+	blocked, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		g.Run(ctx, "/http/SCIM", true, func(ctx context.Context) error {
+			<-blocked
+			return nil
+		})
+		close(done)
+	}()
+	for limiter.Allow(loadshed.PriorityRead) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := g.Run(ctx, "/http/SCIM", true, ok); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable when the concurrency limit is reached, got %v", err)
+	}
+	close(blocked)
+	<-done
+}
+
+func TestGuardLimitsAuthenticatedCallers(t *testing.T) {
+	limits, err := ratelimit.ParseLimits("Bind.user=1/m,SCIM.apikey=1/m,Search.user=1/m")
+	if err != nil {
+		t.Fatalf("Failed to parse limits: %v", err)
+	}
+	l := &recordingLogger{}
+	limiter := loadshed.NewLimiter(4, 1, 4, time.Minute, time.Minute, nil)
+	g := NewGuard(NewAccessLog(l, false, nil), NewRecovery(l, ""), NewLoadShedder(limiter), NewRateLimiter(limits, ratelimit.NewMemoryStore(), l))
+	ctx := context.WithValue(context.Background(), constants.PeerAddr, "10.0.0.1:389")
+	authenticate := func(class, id string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			return ratelimit.CheckCaller(ctx, class, id)
+		}
+	}
+
+	if err := g.Run(ctx, "/ldap/Bind", false, authenticate(ratelimit.ClassUser, "alice")); err != nil {
+		t.Fatalf("Expected the first bind to pass, got %v", err)
+	}
+	if err := g.Run(ctx, "/ldap/Bind", false, authenticate(ratelimit.ClassUser, "alice")); !errors.Is(err, ratelimit.ErrLimited) {
+		t.Errorf("Expected the user to be limited once authenticated, got %v", err)
+	}
+	if err := g.Run(ctx, "/ldap/Bind", false, authenticate(ratelimit.ClassUser, "bob")); err != nil {
+		t.Errorf("Expected users to be limited independently, got %v", err)
+	}
+
+	if err := g.Run(ctx, "/http/SCIM", false, authenticate(ratelimit.ClassAPIKey, ratelimit.TokenID("token-1"))); err != nil {
+		t.Fatalf("Expected the first API key call to pass, got %v", err)
+	}
+	if err := g.Run(ctx, "/http/SCIM", false, authenticate(ratelimit.ClassAPIKey, ratelimit.TokenID("token-1"))); !errors.Is(err, ratelimit.ErrLimited) {
+		t.Errorf("Expected the API key to be limited, got %v", err)
+	}
+
+	bound := context.WithValue(ctx, constants.UserContextKey, &model.User{ID: "alice"})
+	if err := g.Run(bound, "/ldap/Search", true, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected the first search to pass, got %v", err)
+	}
+	if err := g.Run(bound, "/ldap/Search", true, func(ctx context.Context) error { return nil }); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected a bound user to be limited before the search runs, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/idempotency"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	IdempotencyKeyHeader     = "idempotency-key"
+	IdempotentReplayedHeader = "idempotent-replayed"
+)
+
+var idempotentMethods = map[string]func() proto.Message{
+	pb.UserService_NewUser_FullMethodName:    func() proto.Message { return &pb.UserResponse{} },
+	pb.UserService_UpdateUser_FullMethodName: func() proto.Message { return &pb.UserResponse{} },
+	pb.UserService_DeleteUser_FullMethodName: func() proto.Message { return &pb.DeleteUserResponse{} },
+}
+
+var transientCodes = map[codes.Code]bool{
+	codes.Canceled:          true,
+	codes.Unknown:           true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.Internal:          true,
+	codes.Unavailable:       true,
+}
+
+type Idempotency struct {
+	store *idempotency.Store
+}
+
+func NewIdempotency(store *idempotency.Store) *Idempotency {
+	return &Idempotency{store: store}
+}
+
+func (i *Idempotency) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	newResponse, ok := idempotentMethods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	key := idempotencyKey(ctx)
+	if key == "" {
+		return handler(ctx, req)
+	}
+	if !idempotency.ValidKey(key) {
+		return nil, status.Error(codes.InvalidArgument, idempotency.ErrInvalidKey.Error())
+	}
+
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return handler(ctx, req)
+	}
+	fingerprint, err := requestFingerprint(msg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	scoped := info.FullMethod + "|" + callerID(ctx) + "|" + key
+	result, err := i.store.Begin(scoped, fingerprint)
+	switch {
+	case errors.Is(err, idempotency.ErrKeyReused):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, idempotency.ErrInProgress):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case result != nil:
+		grpc.SetHeader(ctx, metadata.Pairs(IdempotentReplayedHeader, "true"))
+		return replay(result, newResponse())
+	}
+
+	panicked := true
+	defer func() {
+		if panicked {
+			i.store.Finish(scoped, nil)
+		}
+	}()
+
+	resp, err := handler(ctx, req)
+	panicked = false
+	i.store.Finish(scoped, storedResult(resp, err))
+
+	return resp, err
+}
+
+func idempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(IdempotencyKeyHeader)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func callerID(ctx context.Context) string {
+	if user, ok := ctx.Value(constants.UserContextKey).(*model.User); ok {
+		return user.ID
+	}
+
+	return ""
+}
+
+func requestFingerprint(req proto.Message) (string, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func storedResult(resp interface{}, err error) *idempotency.Result {
+	if err != nil {
+		s := status.Convert(err)
+		if transientCodes[s.Code()] {
+			return nil
+		}
+
+		body, marshalErr := proto.Marshal(s.Proto())
+		if marshalErr != nil {
+			return nil
+		}
+		return &idempotency.Result{Status: body}
+	}
+
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+
+	return &idempotency.Result{Response: body}
+}
+
+func replay(result *idempotency.Result, resp proto.Message) (interface{}, error) {
+	if result.Status != nil {
+		s := &spb.Status{}
+		if err := proto.Unmarshal(result.Status, s); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return nil, status.ErrorProto(s)
+	}
+
+	if err := proto.Unmarshal(result.Response, resp); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return resp, nil
+}
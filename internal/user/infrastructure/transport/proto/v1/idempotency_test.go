@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/idempotency"
+)
+
+func TestIdempotentNewUser(t *testing.T) {
+	ts := newTestServer(t)
+	adminCtx := basicAuth(context.Background(), "admin", "admin")
+	keyCtx := metadata.AppendToOutgoingContext(adminCtx, IdempotencyKeyHeader, "create-alice-1")
+	req := &pb.NewUserRequest{Username: "alice", Email: "alice@example.com", Password: "password"}
+
+	first, err := ts.client.NewUser(keyCtx, req)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	var header metadata.MD
+	retried, err := ts.client.NewUser(keyCtx, req, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Expected the retry to be replayed, got %v", err)
+	}
+	if retried.Id != first.Id {
+		t.Errorf("Expected the replayed user %s, got %s", first.Id, retried.Id)
+	}
+	if got := header.Get(IdempotentReplayedHeader); len(got) != 1 || got[0] != "true" {
+		t.Errorf("Expected the replay to be marked, got %v", got)
+	}
+
+	req.Email = "other@example.com"
+	if _, err := ts.client.NewUser(keyCtx, req); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a reused key with another payload, got %v", err)
+	}
+
+	if _, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{Username: "alice", Email: "alice@example.com", Password: "password"}); status.Code(err) == codes.OK {
+		t.Error("Expected calls without a key to run again")
+	}
+
+	badKey := metadata.AppendToOutgoingContext(adminCtx, IdempotencyKeyHeader, "has space")
+	if _, err := ts.client.NewUser(badKey, req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a malformed key, got %v", err)
+	}
+}
+
+func TestIdempotentDeleteUser(t *testing.T) {
+	ts := newTestServer(t)
+	adminCtx := basicAuth(context.Background(), "admin", "admin")
+
+	created, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{Username: "bobby", Email: "bob@example.com", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	keyCtx := metadata.AppendToOutgoingContext(adminCtx, IdempotencyKeyHeader, "delete-bob")
+	for i := 0; i < 2; i++ {
+		if _, err := ts.client.DeleteUser(keyCtx, &pb.DeleteUserRequest{Id: created.Id}); err != nil {
+			t.Fatalf("Expected delete %d to succeed, got %v", i+1, err)
+		}
+	}
+	if _, err := ts.client.DeleteUser(adminCtx, &pb.DeleteUserRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for a repeated delete without a key, got %v", err)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	i := NewIdempotency(idempotency.NewStore(time.Hour))
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_NewUser_FullMethodName}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(IdempotencyKeyHeader, "create-bob-1"))
+	req := &pb.NewUserRequest{Username: "bob", Email: "bob@example.com", Password: "password"}
+
+	func() {
+		defer func() { recover() }()
+		i.Interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	resp, err := i.Interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.UserResponse{Id: "bob"}, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the retry after a panic to run, got %v", err)
+	}
+	if resp.(*pb.UserResponse).Id != "bob" {
+		t.Errorf("Expected the retry to return the new response, got %v", resp)
+	}
+}
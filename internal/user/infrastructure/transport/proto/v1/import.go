@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+)
+
+var importStatuses = map[model.ImportStatus]pb.ImportUserResult_Status{
+	model.ImportCreated:           pb.ImportUserResult_CREATED,
+	model.ImportDuplicateUsername: pb.ImportUserResult_DUPLICATE_USERNAME,
+	model.ImportDuplicateEmail:    pb.ImportUserResult_DUPLICATE_EMAIL,
+	model.ImportInvalid:           pb.ImportUserResult_INVALID,
+	model.ImportAborted:           pb.ImportUserResult_ABORTED,
+	model.ImportFailed:            pb.ImportUserResult_FAILED,
+}
+
+func (s *Server) ImportUsers(stream pb.UserService_ImportUsersServer) error {
+	ctx := stream.Context()
+	if err := s.uc.AuthorizeImport(ctx); err != nil {
+		return handleGRPCError(err)
+	}
+
+	var (
+		mode     pb.ImportUsersRequest_Mode
+		first    = true
+		received int
+		pending  []*model.User
+		resp     = &pb.ImportUsersResponse{}
+	)
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if first {
+			mode, first = req.Mode, false
+		} else if req.Mode != mode {
+			return status.Errorf(codes.InvalidArgument, "import mode must be the same in every batch")
+		}
+
+		users := make([]*model.User, len(req.Users))
+		for i, u := range req.Users {
+			users[i] = &model.User{
+				Email:              u.Email,
+				Username:           u.Username,
+				Password:           u.Password,
+				Admin:              u.Admin,
+				Permissions:        toPermissions(u.Permissions),
+				MustChangePassword: u.MustChangePassword,
+			}
+		}
+
+		offset := received
+		received += len(users)
+		if received > command.MaxImportUsers {
+			return handleGRPCError(command.ErrImportTooLarge)
+		}
+
+		if mode == pb.ImportUsersRequest_ALL_OR_NOTHING {
+			pending = append(pending, users...)
+			continue
+		}
+
+		results, err := s.uc.ImportUsers(ctx, users, false, offset)
+		if err != nil {
+			return handleGRPCError(err)
+		}
+		appendImportResults(resp, results)
+	}
+
+	if len(pending) > 0 {
+		results, err := s.uc.ImportUsers(ctx, pending, true, 0)
+		if err != nil {
+			return handleGRPCError(err)
+		}
+		appendImportResults(resp, results)
+	}
+
+	return stream.SendAndClose(resp)
+}
+
+func appendImportResults(resp *pb.ImportUsersResponse, results []*model.ImportResult) {
+	for _, r := range results {
+		resp.Results = append(resp.Results, &pb.ImportUserResult{
+			Index:  uint32(r.Index),
+			Status: importStatuses[r.Status],
+			Id:     r.ID,
+			Error:  r.Error,
+		})
+
+		if r.Status == model.ImportCreated {
+			resp.Created++
+		}
+	}
+}
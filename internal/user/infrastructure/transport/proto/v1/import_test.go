@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+)
+
+func importUsers(t *testing.T, client pb.UserServiceClient, ctx context.Context, batches ...*pb.ImportUsersRequest) *pb.ImportUsersResponse {
+	stream, err := client.ImportUsers(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open import stream: %v", err)
+	}
+
+	for _, batch := range batches {
+		if err := stream.Send(batch); err != nil {
+			t.Fatalf("Failed to send batch: %v", err)
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	return resp
+}
+
+func TestImportUsersBestEffort(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	adminCtx := basicAuth(ctx, "admin", "admin")
+
+	resp := importUsers(t, ts.client, adminCtx,
+		&pb.ImportUsersRequest{Users: []*pb.NewUserRequest{
+			{Email: "alice@example.com", Username: "aliceUser", Password: "password"},
+			{Email: "not-an-email", Username: "brokenUser", Password: "password"},
+		}},
+		&pb.ImportUsersRequest{Users: []*pb.NewUserRequest{
+			{Email: "alice2@example.com", Username: "aliceUser", Password: "password"},
+			{Email: "alice@example.com", Username: "otherUser", Password: "password"},
+			{Email: "bob@example.com", Username: "bobUser", Password: "password"},
+		}},
+	)
+
+	expected := []pb.ImportUserResult_Status{
+		pb.ImportUserResult_CREATED,
+		pb.ImportUserResult_INVALID,
+		pb.ImportUserResult_DUPLICATE_USERNAME,
+		pb.ImportUserResult_DUPLICATE_EMAIL,
+		pb.ImportUserResult_CREATED,
+	}
+	if len(resp.Results) != len(expected) || resp.Created != 2 {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	for i, r := range resp.Results {
+		if r.Index != uint32(i) || r.Status != expected[i] {
+			t.Errorf("Result %d: expected %v, got %+v", i, expected[i], r)
+		}
+	}
+
+	if _, err := ts.client.GetUserByUsername(ctx, &pb.GetUserByUsernameRequest{Username: "bobUser"}); err != nil {
+		t.Errorf("Imported user must be retrievable: %v", err)
+	}
+}
+
+func TestImportUsersAllOrNothing(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	adminCtx := basicAuth(ctx, "admin", "admin")
+
+	resp := importUsers(t, ts.client, adminCtx,
+		&pb.ImportUsersRequest{Mode: pb.ImportUsersRequest_ALL_OR_NOTHING, Users: []*pb.NewUserRequest{
+			{Email: "carol@example.com", Username: "carolUser", Password: "password"},
+		}},
+		&pb.ImportUsersRequest{Mode: pb.ImportUsersRequest_ALL_OR_NOTHING, Users: []*pb.NewUserRequest{
+			{Email: "dave@example.com", Username: "carolUser", Password: "password"},
+		}},
+	)
+
+	if resp.Created != 0 || resp.Results[0].Status != pb.ImportUserResult_ABORTED || resp.Results[1].Status != pb.ImportUserResult_DUPLICATE_USERNAME {
+		t.Errorf("Expected the whole batch to be rejected, got %+v", resp.Results)
+	}
+	if _, err := ts.client.GetUserByUsername(ctx, &pb.GetUserByUsernameRequest{Username: "carolUser"}); err == nil {
+		t.Errorf("No user must be created in a rejected batch")
+	}
+
+	resp = importUsers(t, ts.client, adminCtx,
+		&pb.ImportUsersRequest{Mode: pb.ImportUsersRequest_ALL_OR_NOTHING, Users: []*pb.NewUserRequest{
+			{Email: "carol@example.com", Username: "carolUser", Password: "password"},
+			{Email: "dave@example.com", Username: "daveUser", Password: "password"},
+		}},
+	)
+	if resp.Created != 2 {
+		t.Errorf("Expected both users to be created, got %+v", resp.Results)
+	}
+}
+
+func TestImportUsersRejectsNonAdminUpfront(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := ts.client.NewUser(basicAuth(ctx, "admin", "admin"), &pb.NewUserRequest{Email: "erin@example.com", Username: "erinUser", Password: "password"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	stream, err := ts.client.ImportUsers(basicAuth(ctx, "erinUser", "password"))
+	if err != nil {
+		t.Fatalf("Failed to open import stream: %v", err)
+	}
+	if err := stream.RecvMsg(&pb.ImportUsersResponse{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied before any batch is sent, got %v", err)
+	}
+}
+
+func TestImportUsersFixesModeOnEmptyFirstBatch(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := ts.client.ImportUsers(basicAuth(ctx, "admin", "admin"))
+	if err != nil {
+		t.Fatalf("Failed to open import stream: %v", err)
+	}
+	if err := stream.Send(&pb.ImportUsersRequest{Mode: pb.ImportUsersRequest_ALL_OR_NOTHING}); err != nil {
+		t.Fatalf("Failed to send batch: %v", err)
+	}
+	if err := stream.Send(&pb.ImportUsersRequest{Users: []*pb.NewUserRequest{
+		{Email: "frank@example.com", Username: "frankUser", Password: "password"},
+	}}); err != nil {
+		t.Fatalf("Failed to send batch: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument when a later batch switches the mode, got %v", err)
+	}
+	if _, err := ts.client.GetUserByUsername(basicAuth(ctx, "admin", "admin"), &pb.GetUserByUsernameRequest{Username: "frankUser"}); err == nil {
+		t.Error("No user must be created when the mode is switched")
+	}
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+type inProcessAddr struct{}
+
+func (inProcessAddr) Network() string { return "inprocess" }
+func (inProcessAddr) String() string  { return "inprocess" }
+
+type inProcessConn struct {
+	net.Conn
+}
+
+func (inProcessConn) LocalAddr() net.Addr  { return inProcessAddr{} }
+func (inProcessConn) RemoteAddr() net.Addr { return inProcessAddr{} }
+
+type InProcessListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func NewInProcessListener() *InProcessListener {
+	return &InProcessListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *InProcessListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *InProcessListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *InProcessListener) Addr() net.Addr {
+	return inProcessAddr{}
+}
+
+func (l *InProcessListener) DialContext(ctx context.Context, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+
+	select {
+	case l.conns <- inProcessConn{server}:
+		return inProcessConn{client}, nil
+	case <-l.done:
+		server.Close()
+		client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		server.Close()
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
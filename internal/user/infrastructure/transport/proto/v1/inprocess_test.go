@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"net"
+	"strings"
+	"testing"
+	"userCRUD/internal/common/constants"
+)
+
+func TestForwardedForTrustedOnlyFromGateway(t *testing.T) {
+	peers := make(chan string, 1)
+	capture := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		addr, _ := ctx.Value(constants.PeerAddr).(string)
+		peers <- addr
+		return handler(ctx, req)
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(PeerInterceptor, capture))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	gateway := NewInProcessListener()
+	loopback, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve(gateway)
+	go server.Serve(loopback)
+	t.Cleanup(server.Stop)
+
+	check := func(target string, dialer func(context.Context, string) (net.Conn, error)) string {
+		options := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+		if dialer != nil {
+			options = append(options, grpc.WithContextDialer(dialer))
+		}
+		conn, err := grpc.Dial(target, options...)
+		if err != nil {
+			t.Fatalf("Failed to dial %s: %v", target, err)
+		}
+		defer conn.Close()
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), ForwardedForHeader, "192.0.2.7")
+		if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Health check over %s failed: %v", target, err)
+		}
+
+		return <-peers
+	}
+
+	if got := check("passthrough:///inprocess", gateway.DialContext); got != "192.0.2.7" {
+		t.Errorf("Expected the gateway to forward the client address, got %q", got)
+	}
+	if got := check(loopback.Addr().String(), nil); strings.Contains(got, "192.0.2.7") {
+		t.Errorf("Expected x-forwarded-for from a loopback client to be ignored, got %q", got)
+	}
+}
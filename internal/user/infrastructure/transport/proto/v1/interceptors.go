@@ -2,99 +2,197 @@ package v1
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
-	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
-	"strings"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	pb "userCRUD/api/proto"
 	"userCRUD/internal/common/constants"
 	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/requestid"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/auth"
 	"userCRUD/internal/user/infrastructure/persistence"
 )
 
 const (
-	AuthHeader  = "authorization"
-	BasicPrefix = "Basic "
+	AuthHeader         = "authorization"
+	ForwardedForHeader = "x-forwarded-for"
+	BasicPrefix        = auth.BasicPrefix
+	BearerPrefix       = auth.BearerPrefix
 )
 
 var (
-	ErrNoAuthHeader           = errors.New("no authorization header provided")
-	ErrNoMetadata             = errors.New("no metadata provided")
-	ErrNoBasicHeader          = errors.New("no basic header")
-	ErrInvalidBasicAuthFormat = errors.New("invalid basic auth format")
+	ErrNoAuthHeader = errors.New("no authorization header provided")
+	ErrNoMetadata   = errors.New("no metadata provided")
 )
 
-type basicAuthCreds struct {
-	username string
-	password string
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
 }
 
 func TraceInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	traceID := uuid.New().String()
-	ctx = context.WithValue(ctx, constants.TraceId, traceID)
+	return handler(tracing.WithTraceID(ctx), req)
+}
+
+func TraceStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &wrappedStream{ss, tracing.WithTraceID(ss.Context())})
+}
+
+func RequestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = withRequestID(ctx)
+	grpc.SetHeader(ctx, responseHeaders(ctx))
+
+	resp, err := handler(ctx, req)
+	return resp, withRequestInfo(ctx, err)
+}
+
+func RequestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withRequestID(ss.Context())
+	ss.SetHeader(responseHeaders(ctx))
+
+	return withRequestInfo(ctx, handler(srv, &wrappedStream{ss, ctx}))
+}
+
+func PeerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withPeerAddr(ctx), req)
+}
 
-	return handler(ctx, req)
+func PeerStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &wrappedStream{ss, withPeerAddr(ss.Context())})
 }
 
-func NewAuthInterceptor(ur persistence.UserRepository, l deps.Logger) grpc.UnaryServerInterceptor {
+func NewAuthInterceptor(ur persistence.UserRepository, tr persistence.TokenRepository, pp *command.PasswordPolicy, l deps.Logger) grpc.UnaryServerInterceptor {
+	a := auth.NewAuthenticator(ur, tr, pp)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		creds, err := getCredsFromHeader(ctx)
+		ctx, err := authorizeCall(ctx, info.FullMethod, a)
 		if err != nil {
-			return handler(ctx, req)
+			return nil, err
 		}
+		recordCaller(ctx)
 
-		user, err := ur.GetUserByUsernameAndPassword(ctx, creds.username, creds.password)
+		return handler(ctx, req)
+	}
+}
+
+func NewAuthStreamInterceptor(ur persistence.UserRepository, tr persistence.TokenRepository, pp *command.PasswordPolicy, l deps.Logger) grpc.StreamServerInterceptor {
+	a := auth.NewAuthenticator(ur, tr, pp)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizeCall(ss.Context(), info.FullMethod, a)
 		if err != nil {
-			return handler(ctx, req)
+			return err
 		}
-		if user != nil {
-			newCtx := context.WithValue(ctx, constants.UserContextKey, user)
-			return handler(newCtx, req)
+		recordCaller(ctx)
+
+		return handler(srv, &wrappedStream{ss, ctx})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	candidate := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestid.Header); len(values) > 0 {
+			candidate = values[0]
 		}
+	}
 
-		return handler(ctx, req)
+	return requestid.WithRequestID(ctx, candidate)
+}
+
+func responseHeaders(ctx context.Context) metadata.MD {
+	md := metadata.Pairs(requestid.Header, requestid.FromContext(ctx))
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		md.Set(requestid.TraceHeader, traceID)
+	}
+
+	return md
+}
+
+func withRequestInfo(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
 	}
+
+	s := status.Convert(err)
+	detailed, detailsErr := s.WithDetails(&errdetails.RequestInfo{
+		RequestId:   requestid.FromContext(ctx),
+		ServingData: tracing.TraceID(ctx),
+	})
+	if detailsErr != nil {
+		return err
+	}
+
+	return detailed.Err()
+}
+
+func withPeerAddr(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ctx
+	}
+
+	addr := p.Addr.String()
+	if _, gateway := p.Addr.(inProcessAddr); gateway {
+		if forwarded := forwardedFor(ctx); forwarded != "" {
+			addr = forwarded
+		}
+	}
+
+	return context.WithValue(ctx, constants.PeerAddr, addr)
 }
 
-func getCredsFromHeader(ctx context.Context) (*basicAuthCreds, error) {
+func forwardedFor(ctx context.Context) string {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, ErrNoMetadata
+		return ""
 	}
 
-	authHeaders, ok := md[AuthHeader]
-	if !ok || len(authHeaders) == 0 {
-		return nil, ErrNoAuthHeader
+	values := md.Get(ForwardedForHeader)
+	if len(values) == 0 {
+		return ""
 	}
 
-	authHeader := authHeaders[0]
-	if !strings.HasPrefix(authHeader, BasicPrefix) {
-		return nil, ErrNoBasicHeader
+	return values[0]
+}
+
+func authorizeCall(ctx context.Context, fullMethod string, a *auth.Authenticator) (context.Context, error) {
+	authHeader, err := getAuthHeader(ctx)
+	if err != nil {
+		return ctx, nil
 	}
 
-	creds, err := decodeBasicAuth(authHeader)
+	ctx, err = a.Authenticate(ctx, authHeader)
 	if err != nil {
-		return nil, ErrInvalidBasicAuthFormat
+		return nil, handleGRPCError(err)
 	}
 
-	return creds, nil
+	if fullMethod != pb.UserService_ChangePassword_FullMethodName {
+		if err := a.CheckPasswordRotation(ctx); err != nil {
+			return nil, handleGRPCError(err)
+		}
+	}
+
+	return ctx, nil
 }
 
-func decodeBasicAuth(authHeader string) (*basicAuthCreds, error) {
-	authBase64 := strings.TrimPrefix(authHeader, BasicPrefix)
-	authBytes, err := base64.StdEncoding.DecodeString(authBase64)
-	if err != nil {
-		return nil, err
+func getAuthHeader(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ErrNoMetadata
 	}
 
-	parts := strings.SplitN(string(authBytes), ":", 2)
-	if len(parts) != 2 {
-		return nil, ErrInvalidBasicAuthFormat
+	authHeaders, ok := md[AuthHeader]
+	if !ok || len(authHeaders) == 0 {
+		return "", ErrNoAuthHeader
 	}
 
-	return &basicAuthCreds{
-		username: parts[0],
-		password: parts[1],
-	}, nil
+	return authHeaders[0], nil
 }
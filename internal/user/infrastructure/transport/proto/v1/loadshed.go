@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"path"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/pkg/common/password"
+)
+
+var readMethods = map[string]bool{
+	pb.UserService_GetUsers_FullMethodName:               true,
+	pb.UserService_GetUserByID_FullMethodName:            true,
+	pb.UserService_GetUserByUsername_FullMethodName:      true,
+	pb.UserService_WatchUsers_FullMethodName:             true,
+	pb.UserService_ExportUsers_FullMethodName:            true,
+	pb.UserService_ListAuditEvents_FullMethodName:        true,
+	pb.UserService_ListWebhooks_FullMethodName:           true,
+	pb.UserService_ListWebhookDeadLetters_FullMethodName: true,
+	pb.UserService_ListOIDCClients_FullMethodName:        true,
+	pb.UserService_ListExternalIdentities_FullMethodName: true,
+}
+
+var watchMethods = map[string]bool{
+	pb.UserService_WatchUsers_FullMethodName: true,
+	healthpb.Health_Watch_FullMethodName:     true,
+}
+
+type LoadShedder struct {
+	limiter *loadshed.Limiter
+}
+
+func NewLoadShedder(limiter *loadshed.Limiter) *LoadShedder {
+	return &LoadShedder{limiter: limiter}
+}
+
+func (s *LoadShedder) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	priority := methodPriority(info.FullMethod)
+	slot, err := s.acquire(priority)
+	if err != nil {
+		return nil, shed(info.FullMethod, priority)
+	}
+	if slot == nil {
+		return handler(ctx, req)
+	}
+
+	panicked := true
+	defer func() {
+		slot.Release(panicked || overloaded(err))
+	}()
+
+	resp, err = handler(loadshed.WithSlot(ctx, slot), req)
+	panicked = false
+
+	return resp, err
+}
+
+func (s *LoadShedder) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	priority := methodPriority(info.FullMethod)
+	if watchMethods[info.FullMethod] {
+		if s.limiter != nil && !s.limiter.Allow(priority) {
+			return shed(info.FullMethod, priority)
+		}
+
+		return handler(srv, ss)
+	}
+
+	slot, err := s.acquire(priority)
+	if err != nil {
+		return shed(info.FullMethod, priority)
+	}
+	if slot == nil {
+		return handler(srv, ss)
+	}
+	slot.Untimed()
+
+	panicked := true
+	defer func() {
+		slot.Release(panicked || overloaded(err))
+	}()
+
+	err = handler(srv, &wrappedStream{ss, loadshed.WithSlot(ss.Context(), slot)})
+	panicked = false
+
+	return err
+}
+
+func (s *LoadShedder) acquire(priority loadshed.Priority) (*loadshed.Slot, error) {
+	if s.limiter == nil {
+		return nil, nil
+	}
+
+	return s.limiter.Acquire(priority)
+}
+
+func methodPriority(method string) loadshed.Priority {
+	switch {
+	case path.Dir(method) == "/"+healthpb.Health_ServiceDesc.ServiceName:
+		return loadshed.PriorityCritical
+	case readMethods[method]:
+		return loadshed.PriorityRead
+	default:
+		return loadshed.PriorityMutation
+	}
+}
+
+func shed(method string, priority loadshed.Priority) error {
+	metrics.RPCShed.WithLabelValues(method, priority.String()).Inc()
+
+	return status.Error(codes.Unavailable, loadshed.ErrOverloaded.Error())
+}
+
+func overloaded(err error) bool {
+	s := status.Convert(err)
+
+	return s.Code() == codes.DeadlineExceeded ||
+		(s.Code() == codes.ResourceExhausted && s.Message() == password.ErrHashingOverloaded.Error())
+}
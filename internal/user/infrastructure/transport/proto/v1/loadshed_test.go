@@ -0,0 +1,198 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"runtime"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+func TestLoadShedInterceptor(t *testing.T) {
+	limiter := loadshed.NewLimiter(5, 1, 10, time.Minute, time.Minute, nil)
+	s := NewLoadShedder(limiter)
+	newUser := &grpc.UnaryServerInfo{FullMethod: pb.UserService_NewUser_FullMethodName}
+	getUser := &grpc.UnaryServerInfo{FullMethod: pb.UserService_GetUserByID_FullMethodName}
+	check := &grpc.UnaryServerInfo{FullMethod: healthpb.Health_Check_FullMethodName}
+	shed := metrics.RPCShed.WithLabelValues(newUser.FullMethod, loadshed.PriorityMutation.String())
+	before := testutil.ToFloat64(shed)
+
+	blocked := make(chan struct{})
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			s.Interceptor(context.Background(), &pb.NewUserRequest{}, newUser, func(ctx context.Context, req interface{}) (interface{}, error) {
+				<-blocked
+				return &pb.UserResponse{}, nil
+			})
+			done <- struct{}{}
+		}()
+	}
+	for limiter.Allow(loadshed.PriorityMutation) {
+		runtime.Gosched()
+	}
+
+	_, err := s.Interceptor(context.Background(), &pb.NewUserRequest{}, newUser, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.UserResponse{}, nil
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable for shed mutations, got %v", err)
+	}
+	if got := testutil.ToFloat64(shed) - before; got != 1 {
+		t.Errorf("Expected shed calls to be counted, got %v", got)
+	}
+
+	if _, err := s.Interceptor(context.Background(), &pb.GetUserByIDRequest{}, getUser, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.UserResponse{}, nil
+	}); err != nil {
+		t.Errorf("Expected reads to use the capacity reserved for them, got %v", err)
+	}
+	if _, err := s.Interceptor(context.Background(), &healthpb.HealthCheckRequest{}, check, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &healthpb.HealthCheckResponse{}, nil
+	}); err != nil {
+		t.Errorf("Expected health checks to be admitted, got %v", err)
+	}
+
+	close(blocked)
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	limiter = loadshed.NewLimiter(10, 1, 10, time.Minute, time.Minute, nil)
+	NewLoadShedder(limiter).Interceptor(context.Background(), &pb.NewUserRequest{}, newUser, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.ResourceExhausted, password.ErrHashingOverloaded.Error())
+	})
+	if got := limiter.Limit(); got != 9 {
+		t.Errorf("Expected hashing overload to shrink the limit, got %d", got)
+	}
+}
+
+func TestLoadShedReleasesOnPanic(t *testing.T) {
+	limiter := loadshed.NewLimiter(1, 1, 1, time.Minute, time.Minute, nil)
+	s := NewLoadShedder(limiter)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_GetUserByID_FullMethodName}
+
+	func() {
+		defer func() { recover() }()
+		s.Interceptor(context.Background(), &pb.GetUserByIDRequest{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	if !limiter.Allow(loadshed.PriorityRead) {
+		t.Error("Expected the slot to be released after a panic")
+	}
+}
+
+func TestLoadShedStreams(t *testing.T) {
+	limiter := loadshed.NewLimiter(1, 1, 1, time.Millisecond, time.Millisecond, nil)
+	s := NewLoadShedder(limiter)
+	stream := &wrappedStream{ctx: context.Background()}
+	export := &grpc.StreamServerInfo{FullMethod: pb.UserService_ExportUsers_FullMethodName}
+	watch := &grpc.StreamServerInfo{FullMethod: pb.UserService_WatchUsers_FullMethodName}
+
+	blocked, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		s.StreamInterceptor(nil, stream, watch, func(srv interface{}, ss grpc.ServerStream) error {
+			<-blocked
+			return nil
+		})
+		done <- struct{}{}
+	}()
+	go func() {
+		s.StreamInterceptor(nil, stream, export, func(srv interface{}, ss grpc.ServerStream) error {
+			<-blocked
+			return nil
+		})
+		done <- struct{}{}
+	}()
+	for limiter.Allow(loadshed.PriorityRead) {
+		runtime.Gosched()
+	}
+
+	err := s.StreamInterceptor(nil, stream, export, func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected exports to hold a slot, got %v", err)
+	}
+
+	close(blocked)
+	<-done
+	<-done
+	if !limiter.Allow(loadshed.PriorityRead) {
+		t.Error("Expected the watch stream not to hold a slot")
+	}
+	if got := limiter.Limit(); got != 1 {
+		t.Errorf("Expected long streams not to count as slow calls, got limit %d", got)
+	}
+}
+
+func TestLoadShedHealthWatch(t *testing.T) {
+	limiter := loadshed.NewLimiter(1, 1, 1, time.Minute, time.Minute, nil)
+	s := NewLoadShedder(limiter)
+	stream := &wrappedStream{ctx: context.Background()}
+	health := &grpc.StreamServerInfo{FullMethod: healthpb.Health_Watch_FullMethodName}
+	export := &grpc.StreamServerInfo{FullMethod: pb.UserService_ExportUsers_FullMethodName}
+
+	started, blocked, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		s.StreamInterceptor(nil, stream, health, func(srv interface{}, ss grpc.ServerStream) error {
+			close(started)
+			<-blocked
+			return nil
+		})
+		close(done)
+	}()
+	<-started
+
+	err := s.StreamInterceptor(nil, stream, export, func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected an open health watch not to hold a slot, got %v", err)
+	}
+
+	close(blocked)
+	<-done
+}
+
+func TestLoadShedExcludesAuthentication(t *testing.T) {
+	l := &deps.MockLogger{}
+	ur := persistence.NewUserRepositoryMemory(l, password.NewPool(12, 1, 4), 1000)
+	authenticate := NewAuthInterceptor(ur, persistence.NewTokenRepositoryMemory(), command.NewPasswordPolicy(5, 0), l)
+	limiter := loadshed.NewLimiter(10, 1, 10, 50*time.Millisecond, 50*time.Millisecond, nil)
+	s := NewLoadShedder(limiter)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_GetUsers_FullMethodName}
+
+	creds := base64.StdEncoding.EncodeToString([]byte("admin:admin"))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthHeader, BasicPrefix+creds))
+	start := time.Now()
+	_, err := s.Interceptor(ctx, &pb.GetUsersRequest{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return authenticate(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &pb.GetUsersResponse{}, nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Expected the call to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed <= 50*time.Millisecond {
+		t.Skipf("bcrypt cost 12 took only %v, the test needs it to exceed the threshold", elapsed)
+	}
+	if got := limiter.Limit(); got != 10 {
+		t.Errorf("Expected password hashing during authentication not to shrink the limit, got %d", got)
+	}
+}
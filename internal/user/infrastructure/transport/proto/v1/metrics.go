@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+	"userCRUD/internal/common/metrics"
+)
+
+func MetricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done := observeRPC(info.FullMethod)
+	resp, err := handler(ctx, req)
+	done(err)
+
+	return resp, err
+}
+
+func MetricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	done := observeRPC(info.FullMethod)
+	err := handler(srv, ss)
+	done(err)
+
+	return err
+}
+
+func observeRPC(method string) func(error) {
+	start := time.Now()
+	metrics.RPCInFlight.WithLabelValues(method).Inc()
+
+	return func(err error) {
+		metrics.RPCInFlight.WithLabelValues(method).Dec()
+		metrics.RPCRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+		metrics.RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+}
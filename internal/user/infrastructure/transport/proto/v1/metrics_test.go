@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"testing"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/metrics"
+)
+
+func TestMetricsInterceptor(t *testing.T) {
+	ts := newTestServer(t)
+	method := pb.UserService_GetUserByUsername_FullMethodName
+
+	ok := metrics.RPCRequests.WithLabelValues(method, codes.OK.String())
+	notFound := metrics.RPCRequests.WithLabelValues(method, codes.NotFound.String())
+	authFailures := metrics.AuthAttempts.WithLabelValues("password", metrics.ResultFailure)
+	okBefore, notFoundBefore, failuresBefore := testutil.ToFloat64(ok), testutil.ToFloat64(notFound), testutil.ToFloat64(authFailures)
+
+	adminCtx := basicAuth(context.Background(), "admin", "admin")
+	if _, err := ts.client.GetUserByUsername(adminCtx, &pb.GetUserByUsernameRequest{Username: "admin"}); err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	ts.client.GetUserByUsername(adminCtx, &pb.GetUserByUsernameRequest{Username: "missingUser"})
+
+	if got := testutil.ToFloat64(ok) - okBefore; got != 1 {
+		t.Errorf("Expected 1 OK request, got %v", got)
+	}
+	if got := testutil.ToFloat64(notFound) - notFoundBefore; got != 1 {
+		t.Errorf("Expected 1 NotFound request, got %v", got)
+	}
+
+	ts.client.GetUserByUsername(basicAuth(context.Background(), "admin", "wrong"), &pb.GetUserByUsernameRequest{Username: "admin"})
+	if got := testutil.ToFloat64(authFailures) - failuresBefore; got != 1 {
+		t.Errorf("Expected 1 failed authentication, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.RPCInFlight.WithLabelValues(method)); got != 0 {
+		t.Errorf("Expected no requests in flight, got %v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/domain/model"
+)
+
+func (s *Server) RegisterOIDCClient(ctx context.Context, req *pb.RegisterOIDCClientRequest) (*pb.OIDCClient, error) {
+	client, err := s.oc.RegisterClient(ctx, &model.OIDCClient{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectUris,
+		Public:       req.Public,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	resp := toOIDCClientResponse(client)
+	resp.ClientSecret = client.Secret
+
+	return resp, nil
+}
+
+func (s *Server) ListOIDCClients(ctx context.Context, req *pb.ListOIDCClientsRequest) (*pb.ListOIDCClientsResponse, error) {
+	clients, err := s.oc.ListClients(ctx)
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	clientsResp := make([]*pb.OIDCClient, len(clients))
+	for i, c := range clients {
+		clientsResp[i] = toOIDCClientResponse(c)
+	}
+
+	return &pb.ListOIDCClientsResponse{
+		Clients: clientsResp,
+	}, nil
+}
+
+func (s *Server) DeleteOIDCClient(ctx context.Context, req *pb.DeleteOIDCClientRequest) (*pb.DeleteOIDCClientResponse, error) {
+	err := s.oc.DeleteClient(ctx, &model.OIDCClientByID{
+		ID: req.Id,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return &pb.DeleteOIDCClientResponse{}, nil
+}
+
+func toOIDCClientResponse(client *model.OIDCClient) *pb.OIDCClient {
+	return &pb.OIDCClient{
+		Id:           client.ID,
+		Name:         client.Name,
+		RedirectUris: client.RedirectURIs,
+		Public:       client.Public,
+		CreatedAt:    timestamppb.New(client.CreatedAt),
+	}
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/model"
+)
+
+const RetryAfterHeader = "retry-after"
+
+type RateLimiter struct {
+	limits ratelimit.Limits
+	store  ratelimit.Store
+	l      deps.Logger
+}
+
+func NewRateLimiter(limits ratelimit.Limits, store ratelimit.Store, l deps.Logger) *RateLimiter {
+	return &RateLimiter{
+		limits: limits,
+		store:  store,
+		l:      l,
+	}
+}
+
+func (r *RateLimiter) PreAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if retryAfter, limited := r.limited(ctx, info.FullMethod, ratelimit.ClassAnonymous, peerHost(ctx)); limited {
+		grpc.SetHeader(ctx, retryAfterMetadata(retryAfter))
+		return nil, rateLimitError(retryAfter)
+	}
+
+	return handler(ctx, req)
+}
+
+func (r *RateLimiter) PreAuthStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if retryAfter, limited := r.limited(ss.Context(), info.FullMethod, ratelimit.ClassAnonymous, peerHost(ss.Context())); limited {
+		ss.SetHeader(retryAfterMetadata(retryAfter))
+		return rateLimitError(retryAfter)
+	}
+
+	return handler(srv, ss)
+}
+
+func (r *RateLimiter) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if class, id, ok := callerClass(ctx); ok {
+		if retryAfter, limited := r.limited(ctx, info.FullMethod, class, id); limited {
+			grpc.SetHeader(ctx, retryAfterMetadata(retryAfter))
+			return nil, rateLimitError(retryAfter)
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+func (r *RateLimiter) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if class, id, ok := callerClass(ss.Context()); ok {
+		if retryAfter, limited := r.limited(ss.Context(), info.FullMethod, class, id); limited {
+			ss.SetHeader(retryAfterMetadata(retryAfter))
+			return rateLimitError(retryAfter)
+		}
+	}
+
+	return handler(srv, ss)
+}
+
+func (r *RateLimiter) limited(ctx context.Context, method, class, id string) (time.Duration, bool) {
+	limit, ok := r.limits.For(method, class)
+	if !ok {
+		return 0, false
+	}
+
+	allowed, retryAfter, err := r.store.Allow(ctx, method+"|"+class+"|"+id, limit)
+	if err != nil {
+		r.l.Error(ctx, "rate limiter unavailable, letting the call through", "error", err)
+		return 0, false
+	}
+	if allowed {
+		return 0, false
+	}
+
+	metrics.RPCRateLimited.WithLabelValues(method, class).Inc()
+
+	return retryAfter, true
+}
+
+func peerHost(ctx context.Context) string {
+	addr, _ := ctx.Value(constants.PeerAddr).(string)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+func callerClass(ctx context.Context) (string, string, bool) {
+	user, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		return "", "", false
+	}
+
+	if authHeader, err := getAuthHeader(ctx); err == nil && strings.HasPrefix(authHeader, BearerPrefix) {
+		return ratelimit.ClassAPIKey, ratelimit.TokenID(strings.TrimPrefix(authHeader, BearerPrefix)), true
+	}
+
+	return ratelimit.ClassUser, user.ID, true
+}
+
+func retryAfterMetadata(retryAfter time.Duration) metadata.MD {
+	seconds := int(math.Max(1, math.Ceil(retryAfter.Seconds())))
+	return metadata.Pairs(RetryAfterHeader, strconv.Itoa(seconds))
+}
+
+func rateLimitError(retryAfter time.Duration) error {
+	s := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	detailed, err := s.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return s.Err()
+	}
+
+	return detailed.Err()
+}
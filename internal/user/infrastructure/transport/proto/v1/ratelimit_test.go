@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/model"
+)
+
+func TestRateLimitInterceptor(t *testing.T) {
+	limits, err := ratelimit.ParseLimits("*.anonymous=1/m, GetUsers.user=2/m, *.apikey=1/m")
+	if err != nil {
+		t.Fatalf("Failed to parse limits: %v", err)
+	}
+	rl := NewRateLimiter(limits, ratelimit.NewMemoryStore(), &recordingLogger{})
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_GetUsers_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.GetUsersResponse{}, nil
+	}
+	preAuth := func(ctx context.Context) error {
+		_, err := rl.PreAuthInterceptor(ctx, &pb.GetUsersRequest{}, info, handler)
+		return err
+	}
+	call := func(ctx context.Context) error {
+		_, err := rl.Interceptor(ctx, &pb.GetUsersRequest{}, info, handler)
+		return err
+	}
+
+	rejected := metrics.RPCRateLimited.WithLabelValues(info.FullMethod, ratelimit.ClassAnonymous)
+	before := testutil.ToFloat64(rejected)
+
+	first := context.WithValue(context.Background(), constants.PeerAddr, "10.0.0.1:5000")
+	if err := preAuth(first); err != nil {
+		t.Fatalf("Expected the first anonymous call to pass, got %v", err)
+	}
+	withCredentials := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthHeader, "Basic Zm9vOmJhcg=="))
+	err = preAuth(context.WithValue(withCredentials, constants.PeerAddr, "10.0.0.1:5001"))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted for the same peer, got %v", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+	if retry == nil || retry.GetRetryDelay().AsDuration() <= 0 {
+		t.Errorf("Expected RetryInfo with a positive delay, got %v", status.Convert(err).Details())
+	}
+	if got := testutil.ToFloat64(rejected) - before; got != 1 {
+		t.Errorf("Expected 1 rejected call, got %v", got)
+	}
+	if err := preAuth(context.WithValue(context.Background(), constants.PeerAddr, "10.0.0.2:5000")); err != nil {
+		t.Errorf("Expected peers to be limited independently, got %v", err)
+	}
+	if err := call(first); err != nil {
+		t.Errorf("Expected the post-auth check to skip unauthenticated calls, got %v", err)
+	}
+
+	user := context.WithValue(context.Background(), constants.UserContextKey, &model.User{ID: "alice"})
+	for i := 0; i < 2; i++ {
+		if err := call(user); err != nil {
+			t.Fatalf("Expected user call %d to pass, got %v", i+1, err)
+		}
+	}
+	if err := call(user); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected the user to be limited after the burst, got %v", err)
+	}
+
+	apiKey := metadata.NewIncomingContext(user, metadata.Pairs(AuthHeader, BearerPrefix+"token-1"))
+	if err := call(apiKey); err != nil {
+		t.Fatalf("Expected API key calls to use their own bucket, got %v", err)
+	}
+	if err := call(apiKey); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected the API key to be limited, got %v", err)
+	}
+
+	info.FullMethod = pb.UserService_GetUserByID_FullMethodName
+	if err := call(user); err != nil {
+		t.Errorf("Expected methods without a limit to pass, got %v", err)
+	}
+}
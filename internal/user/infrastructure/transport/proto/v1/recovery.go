@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/metrics"
+	"userCRUD/internal/common/requestid"
+)
+
+const crashDumpTimeFormat = "20060102T150405.000000000Z"
+
+type Recovery struct {
+	l       deps.Logger
+	dumpDir string
+}
+
+type crashDump struct {
+	Time      time.Time   `json:"time"`
+	Method    string      `json:"method"`
+	TraceID   string      `json:"trace_id"`
+	RequestID string      `json:"request_id"`
+	Panic     string      `json:"panic"`
+	Request   interface{} `json:"request,omitempty"`
+	Stack     string      `json:"stack"`
+}
+
+func NewRecovery(l deps.Logger, dumpDir string) *Recovery {
+	return &Recovery{
+		l:       l,
+		dumpDir: dumpDir,
+	}
+}
+
+func (r *Recovery) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			resp, err = nil, r.recovered(ctx, info.FullMethod, p, req)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func (r *Recovery) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = r.recovered(ss.Context(), info.FullMethod, p, nil)
+		}
+	}()
+
+	return handler(srv, ss)
+}
+
+func (r *Recovery) recovered(ctx context.Context, method string, p, req interface{}) error {
+	stack := string(debug.Stack())
+	metrics.RPCPanics.WithLabelValues(method).Inc()
+	r.l.Error(ctx, "panic while handling RPC", "method", method, "panic", fmt.Sprint(p), "stack", stack)
+
+	if r.dumpDir != "" {
+		traceID, _ := ctx.Value(constants.TraceId).(string)
+		dump := &crashDump{
+			Time:      time.Now().UTC(),
+			Method:    method,
+			TraceID:   traceID,
+			RequestID: requestid.FromContext(ctx),
+			Panic:     fmt.Sprint(p),
+			Request:   redactedPayload(req),
+			Stack:     stack,
+		}
+		if path, err := r.writeDump(dump); err != nil {
+			r.l.Error(ctx, "failed to write crash dump", "error", err)
+		} else {
+			r.l.Info(ctx, "crash dump written", "path", path)
+		}
+	}
+
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func (r *Recovery) writeDump(dump *crashDump) (string, error) {
+	if err := os.MkdirAll(r.dumpDir, 0o700); err != nil {
+		return "", err
+	}
+
+	body, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := "crash-" + dump.Time.Format(crashDumpTimeFormat)
+	if dump.TraceID != "" {
+		name += "-" + dump.TraceID
+	}
+	path := filepath.Join(r.dumpDir, name+".json")
+
+	return path, os.WriteFile(path, body, 0o600)
+}
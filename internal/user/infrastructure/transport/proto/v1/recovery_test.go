@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/metrics"
+)
+
+type panicStream struct {
+	grpc.ServerStream
+}
+
+func (p *panicStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	l := &recordingLogger{}
+	dir := filepath.Join(t.TempDir(), "crashes")
+	r := NewRecovery(l, dir)
+	method := pb.UserService_NewUser_FullMethodName
+	panics := metrics.RPCPanics.WithLabelValues(method)
+	before := testutil.ToFloat64(panics)
+
+	ctx := context.WithValue(context.Background(), constants.TraceId, "trace-1")
+	req := &pb.NewUserRequest{Username: "alice", Password: "s3cret-pass"}
+	resp, err := r.Interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		var user *pb.UserResponse
+		return user.Id, nil
+	})
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Fatalf("Expected Internal, got %v, %v", resp, err)
+	}
+	if got := testutil.ToFloat64(panics) - before; got != 1 {
+		t.Errorf("Expected 1 recovered panic, got %v", got)
+	}
+
+	if len(l.records) != 2 {
+		t.Fatalf("Expected a panic record and a dump record, got %v", l.records)
+	}
+	if stack, _ := l.records[0].fields["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("Expected the stack trace to be logged, got %q", stack)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 crash dump, got %v, %v", files, err)
+	}
+	body, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read crash dump: %v", err)
+	}
+
+	var dump crashDump
+	if err := json.Unmarshal(body, &dump); err != nil {
+		t.Fatalf("Failed to decode crash dump: %v", err)
+	}
+	if dump.Method != method || dump.TraceID != "trace-1" || !strings.Contains(files[0].Name(), "trace-1") || !strings.Contains(dump.Panic, "nil pointer") || dump.Stack == "" {
+		t.Errorf("Unexpected crash dump: %+v", dump)
+	}
+	if strings.Contains(string(body), "s3cret-pass") || !strings.Contains(string(body), "alice") {
+		t.Errorf("Expected the request to be dumped with secrets redacted, got %s", body)
+	}
+}
+
+func TestRecoveryStreamInterceptor(t *testing.T) {
+	r := NewRecovery(&recordingLogger{}, "")
+	info := &grpc.StreamServerInfo{FullMethod: pb.UserService_WatchUsers_FullMethodName}
+
+	err := r.StreamInterceptor(nil, &panicStream{}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		panic("watch failed")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal, got %v", err)
+	}
+}
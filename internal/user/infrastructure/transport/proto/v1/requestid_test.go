@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"context"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/requestid"
+	"userCRUD/internal/common/tracing"
+)
+
+func TestRequestIDInterceptor(t *testing.T) {
+	tracing.Install(sdktrace.NewTracerProvider())
+	ts := newTestServer(t)
+	adminCtx := basicAuth(context.Background(), "admin", "admin")
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(adminCtx, requestid.Header, "support-ticket-42")
+	if _, err := ts.client.GetUserByUsername(ctx, &pb.GetUserByUsernameRequest{Username: "admin"}, grpc.Header(&header)); err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if got := header.Get(requestid.Header); len(got) != 1 || got[0] != "support-ticket-42" {
+		t.Errorf("Expected the client request ID to be echoed, got %v", got)
+	}
+	if got := header.Get(requestid.TraceHeader); len(got) != 1 || got[0] == "" {
+		t.Errorf("Expected a trace ID header, got %v", got)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(adminCtx, requestid.Header, "not valid")
+	_, err := ts.client.GetUserByUsername(ctx, &pb.GetUserByUsernameRequest{Username: "missingUser"}, grpc.Header(&header))
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+
+	generated := header.Get(requestid.Header)
+	if len(generated) != 1 || !requestid.Valid(generated[0]) {
+		t.Fatalf("Expected a generated request ID, got %v", generated)
+	}
+
+	var info *errdetails.RequestInfo
+	for _, detail := range status.Convert(err).Details() {
+		if d, ok := detail.(*errdetails.RequestInfo); ok {
+			info = d
+		}
+	}
+	if info == nil || info.RequestId != generated[0] || info.ServingData != header.Get(requestid.TraceHeader)[0] {
+		t.Errorf("Expected RequestInfo with request ID %s and trace ID %v, got %v", generated[0], header.Get(requestid.TraceHeader), info)
+	}
+}
@@ -5,65 +5,102 @@ import (
 	"errors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	pb "userCRUD/api/proto"
 	"userCRUD/internal/common"
 	"userCRUD/internal/common/deps"
 	"userCRUD/internal/user/domain/command"
 	"userCRUD/internal/user/domain/model"
 	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
 )
 
 type Server struct {
 	pb.UnimplementedUserServiceServer
-	l  deps.Logger
-	uc *command.User
+	l     deps.Logger
+	uc    *command.User
+	ic    *command.Impersonation
+	wc    *command.Webhook
+	watch *command.Watch
+	tc    *command.APIToken
+	oc    *command.OIDC
+	fc    *command.Federation
 }
 
-func NewServer(l deps.Logger, uc *command.User) *Server {
+func NewServer(l deps.Logger, uc *command.User, ic *command.Impersonation, wc *command.Webhook, watch *command.Watch, tc *command.APIToken, oc *command.OIDC, fc *command.Federation) *Server {
 	return &Server{
-		l:  l,
-		uc: uc,
+		l:     l,
+		uc:    uc,
+		ic:    ic,
+		wc:    wc,
+		watch: watch,
+		tc:    tc,
+		oc:    oc,
+		fc:    fc,
 	}
 }
 
 func (s *Server) NewUser(ctx context.Context, req *pb.NewUserRequest) (*pb.UserResponse, error) {
 	user, err := s.uc.CreateUser(ctx, &model.User{
-		Email:    req.Email,
-		Username: req.Username,
-		Password: req.Password,
-		Admin:    req.Admin,
+		Email:              req.Email,
+		Username:           req.Username,
+		Password:           req.Password,
+		Admin:              req.Admin,
+		Permissions:        toPermissions(req.Permissions),
+		MustChangePassword: req.MustChangePassword,
 	})
 
 	if err != nil {
 		return nil, handleGRPCError(err)
 	}
 
-	return &pb.UserResponse{
-		Id:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Admin:    user.Admin,
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
 	user, err := s.uc.UpdateUser(ctx, &model.UpdateUser{
-		ID:       req.Id,
-		Email:    req.Email,
-		Username: req.Username,
-		Password: req.Password,
-		Admin:    req.Admin,
+		ID:                 req.Id,
+		Email:              req.Email,
+		Username:           req.Username,
+		Password:           req.Password,
+		Admin:              req.Admin,
+		Permissions:        toPermissions(req.Permissions),
+		MustChangePassword: req.MustChangePassword,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return toUserResponse(user), nil
+}
+
+func (s *Server) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.UserResponse, error) {
+	user, err := s.uc.ChangePassword(ctx, &model.ChangePassword{
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return toUserResponse(user), nil
+}
+
+func (s *Server) Impersonate(ctx context.Context, req *pb.ImpersonateRequest) (*pb.ImpersonateResponse, error) {
+	token, user, err := s.ic.Impersonate(ctx, &model.UserByID{
+		ID: req.UserId,
 	})
 
 	if err != nil {
 		return nil, handleGRPCError(err)
 	}
 
-	return &pb.UserResponse{
-		Id:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Admin:    user.Admin,
+	return &pb.ImpersonateResponse{
+		Token:     token.Value,
+		ExpiresAt: timestamppb.New(token.ExpiresAt),
+		User:      toUserResponse(user),
 	}, nil
 }
 
@@ -88,12 +125,7 @@ func (s *Server) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*
 		return nil, handleGRPCError(err)
 	}
 
-	return &pb.UserResponse{
-		Id:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Admin:    user.Admin,
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *Server) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernameRequest) (*pb.UserResponse, error) {
@@ -105,12 +137,7 @@ func (s *Server) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernam
 		return nil, handleGRPCError(err)
 	}
 
-	return &pb.UserResponse{
-		Id:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Admin:    user.Admin,
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *Server) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
@@ -125,12 +152,7 @@ func (s *Server) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.Get
 
 	usersResp := make([]*pb.UserResponse, len(users))
 	for i, u := range users {
-		usersResp[i] = &pb.UserResponse{
-			Id:       u.ID,
-			Email:    u.Email,
-			Username: u.Username,
-			Admin:    u.Admin,
-		}
+		usersResp[i] = toUserResponse(u)
 	}
 
 	return &pb.GetUsersResponse{
@@ -138,15 +160,72 @@ func (s *Server) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.Get
 	}, nil
 }
 
+func toUserResponse(user *model.User) *pb.UserResponse {
+	resp := &pb.UserResponse{
+		Id:                 user.ID,
+		Email:              user.Email,
+		Username:           user.Username,
+		Admin:              user.Admin,
+		Permissions:        fromPermissions(user.Permissions),
+		MustChangePassword: user.MustChangePassword,
+	}
+	if !user.PasswordChangedAt.IsZero() {
+		resp.PasswordChangedAt = timestamppb.New(user.PasswordChangedAt)
+	}
+
+	return resp
+}
+
+func toPermissions(permissions []string) []model.Permission {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	result := make([]model.Permission, len(permissions))
+	for i, p := range permissions {
+		result[i] = model.Permission(p)
+	}
+
+	return result
+}
+
+func fromPermissions(permissions []model.Permission) []string {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	result := make([]string, len(permissions))
+	for i, p := range permissions {
+		result[i] = string(p)
+	}
+
+	return result
+}
+
 func handleGRPCError(err error) error {
 	if err == nil {
 		return nil
 	}
 
 	switch {
-	case errors.Is(err, persistence.ErrUserNotFound):
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, password.ErrHashingOverloaded):
+		return status.Errorf(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, command.ErrRevisionAhead), errors.Is(err, persistence.ErrRevisionCompacted):
+		return status.Errorf(codes.OutOfRange, err.Error())
+	case errors.Is(err, command.ErrWebhookDeliveryFailed), errors.Is(err, command.ErrWatchClosed):
+		return status.Errorf(codes.Unavailable, err.Error())
+	case errors.Is(err, persistence.ErrUserNotFound), errors.Is(err, persistence.ErrWebhookNotFound),
+		errors.Is(err, persistence.ErrDeadLetterNotFound), errors.Is(err, persistence.ErrOIDCClientNotFound),
+		errors.Is(err, persistence.ErrIdentityNotFound):
 		return status.Errorf(codes.NotFound, err.Error())
-	case errors.Is(err, command.ErrNotEnoughPermissions), errors.Is(err, command.ErrAuthFailed):
+	case errors.Is(err, persistence.ErrIdentityAlreadyUsed):
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	case errors.Is(err, command.ErrPasswordRotation):
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, command.ErrNotEnoughPermissions), errors.Is(err, command.ErrAuthFailed),
+		errors.Is(err, command.ErrImpersonateAdmin), errors.Is(err, command.ErrNestedImpersonate):
 		return status.Errorf(codes.PermissionDenied, err.Error())
 	default:
 		return status.Errorf(codes.InvalidArgument, err.Error())
@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/idempotency"
+	"userCRUD/internal/common/loadshed"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/internal/user/infrastructure/webhook"
+	"userCRUD/pkg/common/password"
+)
+
+type testServer struct {
+	client pb.UserServiceClient
+	server *grpc.Server
+	ur     *persistence.UserRepositoryMemory
+	pp     *command.PasswordPolicy
+	watch  *command.Watch
+}
+
+func newTestServer(t *testing.T) *testServer {
+	l := &deps.MockLogger{}
+	v := deps.NewGoPlaygroundValidator()
+	h := tracing.TraceHasher(password.NewPool(bcrypt.MinCost, 4, 64))
+	ur := persistence.NewUserRepositoryMemory(l, h, 1000)
+	tr := persistence.NewTokenRepositoryMemory()
+	wr := persistence.NewWebhookRepositoryMemory()
+	al := auditpersistence.NewAuditLogMemory()
+	pp := command.NewPasswordPolicy(5, 0)
+
+	accessLog := NewAccessLog(l, true, nil)
+	recovery := NewRecovery(l, "")
+	limiter := NewRateLimiter(nil, ratelimit.NewMemoryStore(), l)
+	idem := NewIdempotency(idempotency.NewStore(time.Hour))
+	shedder := NewLoadShedder(loadshed.NewLimiter(64, 4, 512, time.Second, time.Second, nil))
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(MetricsInterceptor, TraceInterceptor, RequestIDInterceptor, PeerInterceptor, accessLog.Interceptor, recovery.Interceptor, shedder.Interceptor, limiter.PreAuthInterceptor, NewAuthInterceptor(ur, tr, pp, l), limiter.Interceptor, idem.Interceptor),
+		grpc.ChainStreamInterceptor(MetricsStreamInterceptor, TraceStreamInterceptor, RequestIDStreamInterceptor, PeerStreamInterceptor, accessLog.StreamInterceptor, recovery.StreamInterceptor, shedder.StreamInterceptor, limiter.PreAuthStreamInterceptor, NewAuthStreamInterceptor(ur, tr, pp, l), limiter.StreamInterceptor),
+	)
+	uc := command.NewUserCommand(ur, v, h, pp, al)
+	watch := command.NewWatchCommand(ur, ur)
+	pb.RegisterUserServiceServer(server, NewServer(
+		l,
+		uc,
+		command.NewImpersonationCommand(ur, tr, al, v, l, time.Minute),
+		command.NewWebhookCommand(wr, webhook.NewSender(time.Second), v),
+		watch,
+		command.NewAPITokenCommand(tr, al, l, time.Hour),
+		command.NewOIDCCommand(persistence.NewOIDCRepositoryMemory(), ur, al, v, time.Minute),
+		command.NewFederationCommand(persistence.NewIdentityRepositoryMemory(), ur, tr, al, v, uc, l, nil, time.Hour),
+	))
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testServer{
+		client: pb.NewUserServiceClient(conn),
+		server: server,
+		ur:     ur,
+		pp:     pp,
+		watch:  watch,
+	}
+}
+
+func basicAuth(ctx context.Context, username, password string) context.Context {
+	creds := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return metadata.AppendToOutgoingContext(ctx, AuthHeader, BasicPrefix+creds)
+}
+
+func TestPasswordRotationRequired(t *testing.T) {
+	ts := newTestServer(t)
+	adminCtx := basicAuth(context.Background(), "admin", "admin")
+
+	if _, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{Username: "rotated", Email: "rotated@example.com", Password: "password", MustChangePassword: true}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{Username: "expired", Email: "expired@example.com", Password: "password"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	rotatedCtx := basicAuth(context.Background(), "rotated", "password")
+	if _, err := ts.client.GetUserByUsername(rotatedCtx, &pb.GetUserByUsernameRequest{Username: "rotated"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition while a password change is required, got %v", err)
+	}
+	if _, err := ts.client.ChangePassword(rotatedCtx, &pb.ChangePasswordRequest{OldPassword: "password", NewPassword: "newPassword"}); err != nil {
+		t.Fatalf("Expected ChangePassword to be allowed, got %v", err)
+	}
+	if _, err := ts.client.GetUserByUsername(basicAuth(context.Background(), "rotated", "newPassword"), &pb.GetUserByUsernameRequest{Username: "rotated"}); err != nil {
+		t.Errorf("Expected calls to pass after the password change, got %v", err)
+	}
+
+	ts.pp.MaxAge = time.Nanosecond
+	expiredCtx := basicAuth(context.Background(), "expired", "password")
+	if _, err := ts.client.GetUserByUsername(expiredCtx, &pb.GetUserByUsernameRequest{Username: "expired"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for an expired password, got %v", err)
+	}
+	if _, err := ts.client.ChangePassword(expiredCtx, &pb.ChangePasswordRequest{OldPassword: "password", NewPassword: "newPassword"}); err != nil {
+		t.Errorf("Expected ChangePassword to be allowed with an expired password, got %v", err)
+	}
+}
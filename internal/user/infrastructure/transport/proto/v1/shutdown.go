@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"google.golang.org/grpc"
+	"time"
+)
+
+func GracefulStop(s *grpc.Server, timeout time.Duration) bool {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return true
+	case <-timer.C:
+		s.Stop()
+		<-stopped
+		return false
+	}
+}
@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"context"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/metadata"
+	"testing"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/common/tracing"
+)
+
+func TestTracePropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.Install(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	ts := newTestServer(t)
+	exporter.Reset()
+
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+	ctx := metadata.AppendToOutgoingContext(basicAuth(context.Background(), "admin", "admin"), "traceparent", "00-"+traceID+"-"+parentID+"-01")
+	if _, err := ts.client.GetUserByUsername(ctx, &pb.GetUserByUsernameRequest{Username: "admin"}); err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("Span %s has trace ID %s, expected %s", span.Name, span.SpanContext.TraceID(), traceID)
+		}
+		spans[span.Name] = span
+	}
+
+	server, ok := spans["user.UserService/GetUserByUsername"]
+	if !ok {
+		t.Fatalf("Expected a server span, got %v", spans)
+	}
+	if server.Parent.SpanID().String() != parentID || !server.Parent.IsRemote() {
+		t.Errorf("Expected the server span to continue the remote parent, got %s", server.Parent.SpanID())
+	}
+
+	command, ok := spans["User.GetUserByUsername"]
+	if !ok {
+		t.Fatal("Expected a command span")
+	}
+	if command.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("Expected the command span to be a child of the server span")
+	}
+	if _, ok := spans["PasswordHasher.Compare"]; !ok {
+		t.Error("Expected a password hashing span for the authentication")
+	}
+}
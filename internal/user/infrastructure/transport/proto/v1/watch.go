@@ -0,0 +1,75 @@
+package v1
+
+import (
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+)
+
+var watchTypes = map[event.Type]pb.WatchUsersResponse_Type{
+	event.UserCreated:     pb.WatchUsersResponse_CREATED,
+	event.UserUpdated:     pb.WatchUsersResponse_UPDATED,
+	event.UserDeleted:     pb.WatchUsersResponse_DELETED,
+	event.PasswordChanged: pb.WatchUsersResponse_PASSWORD_CHANGED,
+}
+
+func (s *Server) WatchUsers(req *pb.WatchUsersRequest, stream pb.UserService_WatchUsersServer) error {
+	ctx := stream.Context()
+	sinceRevision := req.SinceRevision
+
+	if req.Snapshot {
+		users, revision, err := s.watch.Snapshot(ctx)
+		if err != nil {
+			return handleGRPCError(err)
+		}
+
+		for _, u := range users {
+			err := stream.Send(&pb.WatchUsersResponse{
+				Revision: revision,
+				Type:     pb.WatchUsersResponse_SNAPSHOT,
+				UserId:   u.ID,
+				User:     toUserResponse(u),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = stream.Send(&pb.WatchUsersResponse{
+			Revision: revision,
+			Type:     pb.WatchUsersResponse_SNAPSHOT_COMPLETE,
+		})
+		if err != nil {
+			return err
+		}
+
+		sinceRevision = revision
+	}
+
+	err := s.watch.Watch(ctx, sinceRevision, func(record event.Record) error {
+		return stream.Send(toWatchResponse(record))
+	})
+
+	return handleGRPCError(err)
+}
+
+func toWatchResponse(record event.Record) *pb.WatchUsersResponse {
+	resp := &pb.WatchUsersResponse{
+		Revision: record.Offset,
+		Type:     watchTypes[record.Event.Type],
+		UserId:   record.Event.UserID,
+	}
+
+	if u := record.Event.User; u != nil {
+		resp.User = toUserResponse(&model.User{
+			ID:                 u.ID,
+			Email:              u.Email,
+			Username:           u.Username,
+			Admin:              u.Admin,
+			Permissions:        u.Permissions,
+			MustChangePassword: u.MustChangePassword,
+		})
+	}
+
+	return resp
+}
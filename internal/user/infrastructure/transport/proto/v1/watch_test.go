@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+	pb "userCRUD/api/proto"
+)
+
+func TestWatchUsersSnapshotAndResume(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	adminCtx := basicAuth(ctx, "admin", "admin")
+
+	first, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{Email: "first@example.com", Username: "firstUser", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	stream, err := ts.client.WatchUsers(watchCtx, &pb.WatchUsersRequest{Snapshot: true})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+
+	snapshot := map[string]bool{}
+	var revision uint64
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Failed to receive snapshot: %v", err)
+		}
+		if resp.Type == pb.WatchUsersResponse_SNAPSHOT_COMPLETE {
+			revision = resp.Revision
+			break
+		}
+		snapshot[resp.UserId] = true
+	}
+	if !snapshot[first.Id] || len(snapshot) != 2 {
+		t.Errorf("Expected snapshot with admin and %s, got %v", first.Id, snapshot)
+	}
+	if revision != 1 {
+		t.Errorf("Expected snapshot revision 1, got %d", revision)
+	}
+
+	second, err := ts.client.NewUser(adminCtx, &pb.NewUserRequest{Email: "second@example.com", Username: "secondUser", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive change: %v", err)
+	}
+	if resp.Type != pb.WatchUsersResponse_CREATED || resp.UserId != second.Id || resp.Revision != 2 || resp.User.Email != "second@example.com" {
+		t.Errorf("Unexpected change %+v", resp)
+	}
+	stopWatch()
+
+	if _, err := ts.client.DeleteUser(adminCtx, &pb.DeleteUserRequest{Id: first.Id}); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	resumed, err := ts.client.WatchUsers(ctx, &pb.WatchUsersRequest{SinceRevision: resp.Revision})
+	if err != nil {
+		t.Fatalf("Failed to resume watch: %v", err)
+	}
+	missed, err := resumed.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive missed change: %v", err)
+	}
+	if missed.Type != pb.WatchUsersResponse_DELETED || missed.UserId != first.Id || missed.Revision != 3 {
+		t.Errorf("Expected missed delete at revision 3, got %+v", missed)
+	}
+
+	ahead, err := ts.client.WatchUsers(ctx, &pb.WatchUsersRequest{SinceRevision: 100})
+	if err == nil {
+		_, err = ahead.Recv()
+	}
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("Expected OutOfRange for a revision ahead of the log, got %v", err)
+	}
+}
+
+func TestShutdownWithOpenWatchStream(t *testing.T) {
+	openWatch := func(t *testing.T, ts *testServer) pb.UserService_WatchUsersClient {
+		stream, err := ts.client.WatchUsers(basicAuth(context.Background(), "admin", "admin"), &pb.WatchUsersRequest{Snapshot: true})
+		if err != nil {
+			t.Fatalf("Failed to watch: %v", err)
+		}
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("Failed to receive snapshot: %v", err)
+			}
+			if resp.Type == pb.WatchUsersResponse_SNAPSHOT_COMPLETE {
+				return stream
+			}
+		}
+	}
+
+	ts := newTestServer(t)
+	stream := openWatch(t, ts)
+	ts.watch.Close()
+	if !GracefulStop(ts.server, 5*time.Second) {
+		t.Error("Expected closing the watch command to let the server stop gracefully")
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable for a watch closed by shutdown, got %v", err)
+	}
+
+	ts = newTestServer(t)
+	stream = openWatch(t, ts)
+	start := time.Now()
+	if GracefulStop(ts.server, 50*time.Millisecond) {
+		t.Error("Expected the open stream to force a hard stop")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected shutdown to finish after the timeout, took %v", elapsed)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected the stream to be closed by the hard stop")
+	}
+}
@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "userCRUD/api/proto"
+	"userCRUD/internal/user/domain/model"
+)
+
+func (s *Server) RegisterWebhook(ctx context.Context, req *pb.RegisterWebhookRequest) (*pb.Webhook, error) {
+	webhook, err := s.wc.RegisterWebhook(ctx, &model.Webhook{
+		URL:        req.Url,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	resp := toWebhookResponse(webhook)
+	resp.Secret = webhook.Secret
+
+	return resp, nil
+}
+
+func (s *Server) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := s.wc.ListWebhooks(ctx)
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	webhooksResp := make([]*pb.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		webhooksResp[i] = toWebhookResponse(w)
+	}
+
+	return &pb.ListWebhooksResponse{
+		Webhooks: webhooksResp,
+	}, nil
+}
+
+func (s *Server) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	err := s.wc.DeleteWebhook(ctx, &model.WebhookByID{
+		ID: req.Id,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return &pb.DeleteWebhookResponse{}, nil
+}
+
+func (s *Server) ListWebhookDeadLetters(ctx context.Context, req *pb.ListWebhookDeadLettersRequest) (*pb.ListWebhookDeadLettersResponse, error) {
+	deadLetters, err := s.wc.ListDeadLetters(ctx, req.WebhookId)
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	deadLettersResp := make([]*pb.WebhookDeadLetter, len(deadLetters))
+	for i, d := range deadLetters {
+		deadLettersResp[i] = &pb.WebhookDeadLetter{
+			Id:        d.ID,
+			WebhookId: d.WebhookID,
+			EventId:   d.EventID,
+			EventType: d.EventType,
+			Payload:   d.Payload,
+			Attempts:  uint32(d.Attempts),
+			LastError: d.LastError,
+			FailedAt:  timestamppb.New(d.FailedAt),
+		}
+	}
+
+	return &pb.ListWebhookDeadLettersResponse{
+		DeadLetters: deadLettersResp,
+	}, nil
+}
+
+func (s *Server) RedeliverWebhook(ctx context.Context, req *pb.RedeliverWebhookRequest) (*pb.RedeliverWebhookResponse, error) {
+	err := s.wc.Redeliver(ctx, &model.WebhookByID{
+		ID: req.DeadLetterId,
+	})
+
+	if err != nil {
+		return nil, handleGRPCError(err)
+	}
+
+	return &pb.RedeliverWebhookResponse{}, nil
+}
+
+func toWebhookResponse(webhook *model.Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:         webhook.ID,
+		Url:        webhook.URL,
+		EventTypes: webhook.EventTypes,
+		CreatedAt:  timestamppb.New(webhook.CreatedAt),
+	}
+}
@@ -0,0 +1,48 @@
+package v2
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+var (
+	//go:embed schemas/service_provider_config.json
+	serviceProviderConfig json.RawMessage
+	//go:embed schemas/user_schema.json
+	userSchema json.RawMessage
+	//go:embed schemas/user_resource_type.json
+	userResourceTypeDefinition json.RawMessage
+)
+
+func (h *Handler) serveDiscovery(w http.ResponseWriter, r *http.Request, path string) bool {
+	switch {
+	case path == serviceProviderConfigPath:
+		writeJSON(w, http.StatusOK, serviceProviderConfig)
+	case path == schemasPath:
+		writeJSON(w, http.StatusOK, singleItemList(userSchema))
+	case path == schemasPath+"/"+UserSchema:
+		writeJSON(w, http.StatusOK, userSchema)
+	case path == resourceTypesPath:
+		writeJSON(w, http.StatusOK, singleItemList(userResourceTypeDefinition))
+	case path == resourceTypesPath+"/"+userResourceType:
+		writeJSON(w, http.StatusOK, userResourceTypeDefinition)
+	case strings.HasPrefix(path, schemasPath+"/"), strings.HasPrefix(path, resourceTypesPath+"/"):
+		writeError(w, newError(http.StatusNotFound, "", "resource not found"))
+	default:
+		return false
+	}
+
+	return true
+}
+
+func singleItemList(item json.RawMessage) *listResponse {
+	return &listResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: 1,
+		StartIndex:   1,
+		ItemsPerPage: 1,
+		Resources:    []json.RawMessage{item},
+	}
+}
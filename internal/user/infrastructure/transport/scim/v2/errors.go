@@ -0,0 +1,68 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"userCRUD/internal/common/ratelimit"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+const (
+	ScimTypeInvalidFilter = "invalidFilter"
+	ScimTypeInvalidSyntax = "invalidSyntax"
+	ScimTypeInvalidPath   = "invalidPath"
+	ScimTypeInvalidValue  = "invalidValue"
+	ScimTypeNoTarget      = "noTarget"
+	ScimTypeMutability    = "mutability"
+	ScimTypeUniqueness    = "uniqueness"
+)
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+
+	status int
+}
+
+func newError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+		status:   status,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func handleSCIMError(err error) *Error {
+	var scimErr *Error
+	if errors.As(err, &scimErr) {
+		return scimErr
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return newError(http.StatusServiceUnavailable, "", err.Error())
+	case errors.Is(err, password.ErrHashingOverloaded), errors.Is(err, ratelimit.ErrLimited):
+		return newError(http.StatusTooManyRequests, "", err.Error())
+	case errors.Is(err, persistence.ErrUserNotFound):
+		return newError(http.StatusNotFound, "", err.Error())
+	case errors.Is(err, persistence.ErrUsernameTaken), errors.Is(err, persistence.ErrEmailTaken):
+		return newError(http.StatusConflict, ScimTypeUniqueness, err.Error())
+	case errors.Is(err, command.ErrNotEnoughPermissions), errors.Is(err, command.ErrAuthFailed),
+		errors.Is(err, command.ErrPasswordRotation):
+		return newError(http.StatusForbidden, "", err.Error())
+	default:
+		return newError(http.StatusBadRequest, ScimTypeInvalidValue, err.Error())
+	}
+}
@@ -0,0 +1,362 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	opEqual      = "eq"
+	opStartsWith = "sw"
+	opContains   = "co"
+)
+
+type attribute struct {
+	caseExact bool
+}
+
+type attributeValues map[string][]interface{}
+
+var (
+	userFilterAttributes = map[string]attribute{
+		"id":           {caseExact: true},
+		"username":     {},
+		"emails":       {},
+		"emails.value": {},
+		"emails.type":  {},
+		"active":       {},
+	}
+	emailFilterAttributes = map[string]attribute{
+		"value":   {},
+		"type":    {},
+		"primary": {},
+	}
+)
+
+type filter interface {
+	matches(values attributeValues) bool
+}
+
+type comparisonFilter struct {
+	attr      string
+	op        string
+	value     interface{}
+	caseExact bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+type notFilter struct {
+	inner filter
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+type filterParser struct {
+	tokens     []token
+	pos        int
+	attributes map[string]attribute
+}
+
+func parseFilter(input string, attributes map[string]attribute) (filter, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, invalidFilter("filter is empty")
+	}
+
+	p := &filterParser{tokens: tokens, attributes: attributes}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, invalidFilter(fmt.Sprintf("unexpected %q", p.tokens[p.pos].text))
+	}
+
+	return f, nil
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("and") {
+		right, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAtom() (filter, error) {
+	if p.accept("(") {
+		return p.parseGroup()
+	}
+	if p.acceptKeyword("not") {
+		if !p.accept("(") {
+			return nil, invalidFilter("expected ( after not")
+		}
+		inner, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{inner: inner}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseGroup() (filter, error) {
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(")") {
+		return nil, invalidFilter("missing )")
+	}
+
+	return inner, nil
+}
+
+func (p *filterParser) parseComparison() (filter, error) {
+	attrTok, ok := p.next()
+	if !ok || attrTok.quoted {
+		return nil, invalidFilter("expected attribute path")
+	}
+
+	attr := normalizeAttributePath(attrTok.text)
+	definition, known := p.attributes[attr]
+	if !known {
+		return nil, invalidFilter(fmt.Sprintf("filtering by %q is not supported", attrTok.text))
+	}
+
+	opTok, ok := p.next()
+	if !ok || opTok.quoted {
+		return nil, invalidFilter("expected comparison operator")
+	}
+	op := strings.ToLower(opTok.text)
+	if op != opEqual && op != opStartsWith && op != opContains {
+		return nil, invalidFilter(fmt.Sprintf("operator %q is not supported", opTok.text))
+	}
+
+	valueTok, ok := p.next()
+	if !ok {
+		return nil, invalidFilter("expected comparison value")
+	}
+
+	var value interface{}
+	switch {
+	case valueTok.quoted:
+		value = valueTok.text
+	case valueTok.text == "true" || valueTok.text == "false":
+		value = valueTok.text == "true"
+	case valueTok.text == "null":
+		value = nil
+	default:
+		return nil, invalidFilter(fmt.Sprintf("invalid comparison value %q", valueTok.text))
+	}
+
+	if _, isString := value.(string); op != opEqual && !isString {
+		return nil, invalidFilter(fmt.Sprintf("operator %q requires a string value", op))
+	}
+
+	return &comparisonFilter{attr: attr, op: op, value: value, caseExact: definition.caseExact}, nil
+}
+
+func (p *filterParser) next() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	return t, true
+}
+
+func (p *filterParser) accept(text string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) acceptKeyword(keyword string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (f *comparisonFilter) matches(values attributeValues) bool {
+	candidates := values[f.attr]
+	if f.value == nil {
+		return len(candidates) == 0
+	}
+
+	for _, candidate := range candidates {
+		if f.compare(candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *comparisonFilter) compare(candidate interface{}) bool {
+	switch want := f.value.(type) {
+	case bool:
+		got, ok := candidate.(bool)
+		return ok && got == want
+	case string:
+		got, ok := candidate.(string)
+		if !ok {
+			return false
+		}
+		if !f.caseExact {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+
+		switch f.op {
+		case opStartsWith:
+			return strings.HasPrefix(got, want)
+		case opContains:
+			return strings.Contains(got, want)
+		default:
+			return got == want
+		}
+	}
+
+	return false
+}
+
+func (f *logicalFilter) matches(values attributeValues) bool {
+	if f.and {
+		return f.left.matches(values) && f.right.matches(values)
+	}
+
+	return f.left.matches(values) || f.right.matches(values)
+}
+
+func (f *notFilter) matches(values attributeValues) bool {
+	return !f.inner.matches(values)
+}
+
+func tokenizeFilter(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		switch c := input[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, token{text: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(input) && input[end] != '"'; end++ {
+				if input[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(input) {
+				return nil, invalidFilter("unterminated string")
+			}
+
+			var text string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &text); err != nil {
+				return nil, invalidFilter("invalid string literal")
+			}
+			tokens = append(tokens, token{text: text, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(input) && !strings.ContainsRune(" \t()\"", rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{text: input[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+func normalizeAttributePath(path string) string {
+	lower := strings.ToLower(path)
+	prefix := strings.ToLower(UserSchema) + ":"
+
+	return strings.TrimPrefix(lower, prefix)
+}
+
+func invalidFilter(detail string) *Error {
+	return newError(http.StatusBadRequest, ScimTypeInvalidFilter, detail)
+}
+
+func (r *userResource) attributeValues() attributeValues {
+	values := attributeValues{
+		"id":       {r.ID},
+		"username": {r.UserName},
+	}
+	if r.Active != nil {
+		values["active"] = []interface{}{*r.Active}
+	}
+	for _, e := range r.Emails {
+		values["emails"] = append(values["emails"], e.Value)
+		values["emails.value"] = append(values["emails.value"], e.Value)
+		if e.Type != "" {
+			values["emails.type"] = append(values["emails.type"], e.Type)
+		}
+	}
+
+	return values
+}
+
+func (e *email) attributeValues() attributeValues {
+	values := attributeValues{
+		"value":   {e.Value},
+		"primary": {e.Primary},
+	}
+	if e.Type != "" {
+		values["type"] = []interface{}{e.Type}
+	}
+
+	return values
+}
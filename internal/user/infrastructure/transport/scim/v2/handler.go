@@ -0,0 +1,335 @@
+package v2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/common/tracing"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+const (
+	Prefix      = "/scim/v2"
+	ContentType = "application/scim+json"
+
+	usersPath                 = "/Users"
+	serviceProviderConfigPath = "/ServiceProviderConfig"
+	schemasPath               = "/Schemas"
+	resourceTypesPath         = "/ResourceTypes"
+
+	maxResults          = 200
+	maxRequestBodyBytes = 1 << 20
+)
+
+type Handler struct {
+	uc *command.User
+	ur persistence.UserRepository
+	a  *auth.Authenticator
+	l  deps.Logger
+}
+
+func NewHandler(uc *command.User, ur persistence.UserRepository, a *auth.Authenticator, l deps.Logger) *Handler {
+	return &Handler{
+		uc: uc,
+		ur: ur,
+		a:  a,
+		l:  l,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, Prefix), "/")
+
+	if r.Method == http.MethodGet && h.serveDiscovery(w, r, path) {
+		return
+	}
+
+	ctx, scimErr := h.authenticate(r)
+	if scimErr != nil {
+		writeError(w, scimErr)
+		return
+	}
+
+	switch {
+	case path == usersPath:
+		switch r.Method {
+		case http.MethodGet:
+			h.listUsers(ctx, w, r)
+		case http.MethodPost:
+			h.createUser(ctx, w, r)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case strings.HasPrefix(path, usersPath+"/"):
+		id := strings.TrimPrefix(path, usersPath+"/")
+		if strings.Contains(id, "/") {
+			writeError(w, newError(http.StatusNotFound, "", "resource not found"))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			h.getUser(ctx, w, r, id)
+		case http.MethodPut:
+			h.replaceUser(ctx, w, r, id)
+		case http.MethodPatch:
+			h.patchUser(ctx, w, r, id)
+		case http.MethodDelete:
+			h.deleteUser(ctx, w, id)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+		}
+	default:
+		writeError(w, newError(http.StatusNotFound, "", "resource not found"))
+	}
+}
+
+func (h *Handler) authenticate(r *http.Request) (context.Context, *Error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, auth.BearerPrefix) {
+		return nil, newError(http.StatusUnauthorized, "", "bearer token required")
+	}
+
+	ctx := tracing.WithTraceID(r.Context())
+	ctx, err := h.a.Authenticate(ctx, header)
+	if err != nil {
+		return nil, handleSCIMError(err)
+	}
+
+	user, ok := ctx.Value(constants.UserContextKey).(*model.User)
+	if !ok {
+		return nil, newError(http.StatusUnauthorized, "", "invalid or expired bearer token")
+	}
+	if err := h.a.CheckPasswordRotation(ctx); err != nil {
+		return nil, handleSCIMError(err)
+	}
+	if !user.Admin {
+		return nil, handleSCIMError(command.ErrNotEnoughPermissions)
+	}
+
+	return ctx, nil
+}
+
+func (h *Handler) listUsers(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var f filter
+	if expr := query.Get("filter"); expr != "" {
+		var err error
+		if f, err = parseFilter(expr, userFilterAttributes); err != nil {
+			writeError(w, handleSCIMError(err))
+			return
+		}
+	}
+
+	startIndex := queryInt(query.Get("startIndex"), 1)
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := queryInt(query.Get("count"), maxResults)
+	if count < 0 {
+		count = 0
+	}
+	if count > maxResults {
+		count = maxResults
+	}
+
+	users, _, err := h.ur.Snapshot(ctx)
+	if err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	base := baseURL(r)
+	matched := make([]*userResource, 0, len(users))
+	for _, user := range users {
+		res := toResource(user, base)
+		if f == nil || f.matches(res.attributeValues()) {
+			matched = append(matched, res)
+		}
+	}
+
+	page := []*userResource{}
+	if start := startIndex - 1; start < len(matched) {
+		end := start + count
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page = matched[start:end]
+	}
+
+	writeJSON(w, http.StatusOK, &listResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: len(matched),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+func (h *Handler) getUser(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	user, err := h.ur.GetUserByID(ctx, id)
+	if err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toResource(user, baseURL(r)))
+}
+
+func (h *Handler) createUser(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var res userResource
+	if err := readBody(r, &res); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := res.validate(); err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	pw := res.Password
+	if pw == "" {
+		generated, err := generatePassword()
+		if err != nil {
+			writeError(w, handleSCIMError(err))
+			return
+		}
+		pw = generated
+	}
+
+	user, err := h.uc.CreateUser(ctx, &model.User{
+		Email:    res.primaryEmail(),
+		Username: res.UserName,
+		Password: pw,
+	})
+	if err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	created := toResource(user, baseURL(r))
+	w.Header().Set("Location", created.Meta.Location)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) replaceUser(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	existing, err := h.ur.GetUserByID(ctx, id)
+	if err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	var res userResource
+	if err := readBody(r, &res); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := res.validate(); err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	h.update(ctx, w, r, existing, &res)
+}
+
+func (h *Handler) patchUser(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	existing, err := h.ur.GetUserByID(ctx, id)
+	if err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	var patch patchRequest
+	if err := readBody(r, &patch); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := toResource(existing, baseURL(r))
+	if err := patch.apply(res); err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	h.update(ctx, w, r, existing, res)
+}
+
+func (h *Handler) update(ctx context.Context, w http.ResponseWriter, r *http.Request, existing *model.User, res *userResource) {
+	user, err := h.uc.UpdateUser(ctx, res.toUpdate(existing))
+	if err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toResource(user, baseURL(r)))
+}
+
+func (h *Handler) deleteUser(ctx context.Context, w http.ResponseWriter, id string) {
+	if _, err := h.ur.GetUserByID(ctx, id); err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	if err := h.uc.DeleteUser(ctx, &model.UserByID{ID: id}); err != nil {
+		writeError(w, handleSCIMError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func readBody(r *http.Request, v interface{}) *Error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBodyBytes))
+	if err := dec.Decode(v); err != nil {
+		return newError(http.StatusBadRequest, ScimTypeInvalidSyntax, "invalid JSON body: "+err.Error())
+	}
+
+	return nil
+}
+
+func queryInt(value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+
+	return parsed
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func writeError(w http.ResponseWriter, err *Error) {
+	writeJSON(w, err.status, err)
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, newError(http.StatusMethodNotAllowed, "", "method not allowed"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,431 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	auditpersistence "userCRUD/internal/audit/infrastructure/persistence"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/auth"
+	"userCRUD/internal/user/infrastructure/persistence"
+	"userCRUD/pkg/common/password"
+)
+
+// Request bodies below follow the examples in RFC 7643 section 8 and RFC 7644 section 3.
+const (
+	rfcFullUser = `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"externalId": "701984",
+		"userName": "bjensen@example.com",
+		"name": {
+			"formatted": "Ms. Barbara J Jensen, III",
+			"familyName": "Jensen",
+			"givenName": "Barbara"
+		},
+		"displayName": "Babs Jensen",
+		"emails": [
+			{"value": "bjensen@example.com", "type": "work", "primary": true},
+			{"value": "babs@jensen.org", "type": "home"}
+		],
+		"active": true,
+		"password": "t1meMa$heen"
+	}`
+	rfcReplaceUser = `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "bjensen",
+		"externalId": "bjensen",
+		"name": {"formatted": "Ms. Barbara J Jensen III", "familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+		"roles": [],
+		"userType": "Employee",
+		"emails": [
+			{"value": "bjensen@example.com"},
+			{"value": "babs@jensen.org"}
+		]
+	}`
+)
+
+type scimTest struct {
+	handler    *Handler
+	ur         persistence.UserRepository
+	adminToken string
+	userToken  string
+}
+
+type scimResponse struct {
+	code   int
+	header http.Header
+	body   map[string]interface{}
+}
+
+func newSCIMTest(t *testing.T) *scimTest {
+	l := &deps.MockLogger{}
+	h := password.NewPool(bcrypt.MinCost, 4, 64)
+	ur := persistence.NewUserRepositoryMemory(l, h, 1000)
+	tr := persistence.NewTokenRepositoryMemory()
+	al := auditpersistence.NewAuditLogMemory()
+	pp := command.NewPasswordPolicy(5, 0)
+	uc := command.NewUserCommand(ur, deps.NewGoPlaygroundValidator(), h, pp, al)
+
+	admin, err := ur.GetUserByUsername(context.Background(), "admin")
+	if err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+	adminCtx := context.WithValue(context.Background(), constants.UserContextKey, admin)
+
+	adminToken, err := command.NewAPITokenCommand(tr, al, l, time.Hour).IssueAPIToken(adminCtx, 0)
+	if err != nil {
+		t.Fatalf("Failed to issue API token: %v", err)
+	}
+
+	user, err := uc.CreateUser(adminCtx, &model.User{Email: "regular@example.com", Username: "regular", Password: "password"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	userToken := &model.Token{Value: "regular-token", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := tr.CreateToken(context.Background(), userToken); err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	return &scimTest{
+		handler:    NewHandler(uc, ur, auth.NewAuthenticator(ur, tr, pp), l),
+		ur:         ur,
+		adminToken: adminToken.Value,
+		userToken:  userToken.Value,
+	}
+}
+
+func (s *scimTest) do(t *testing.T, method, target, body string) *scimResponse {
+	return s.doAs(t, s.adminToken, method, target, body)
+}
+
+func (s *scimTest) doAs(t *testing.T, token, method, target, body string) *scimResponse {
+	req := httptest.NewRequest(method, "http://example.com"+Prefix+target, strings.NewReader(body))
+	req.Header.Set("Content-Type", ContentType)
+	if token != "" {
+		req.Header.Set("Authorization", auth.BearerPrefix+token)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+
+	resp := &scimResponse{code: rec.Code, header: rec.Header()}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp.body); err != nil {
+			t.Fatalf("%s %s: invalid JSON response %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	if rec.Body.Len() > 0 && rec.Header().Get("Content-Type") != ContentType {
+		t.Errorf("%s %s: expected Content-Type %s, got %s", method, target, ContentType, rec.Header().Get("Content-Type"))
+	}
+
+	return resp
+}
+
+func (s *scimTest) createUser(t *testing.T, body string) string {
+	resp := s.do(t, http.MethodPost, usersPath, body)
+	if resp.code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", resp.code, resp.body)
+	}
+
+	return resp.body["id"].(string)
+}
+
+func listFilter(filter string) string {
+	return usersPath + "?filter=" + url.QueryEscape(filter)
+}
+
+func expectError(t *testing.T, resp *scimResponse, status int, scimType string) {
+	t.Helper()
+
+	if resp.code != status {
+		t.Errorf("Expected status %d, got %d: %v", status, resp.code, resp.body)
+		return
+	}
+	schemas, _ := resp.body["schemas"].([]interface{})
+	if len(schemas) != 1 || schemas[0] != ErrorSchema {
+		t.Errorf("Expected error schema, got %v", resp.body["schemas"])
+	}
+	if resp.body["status"] != strconv.Itoa(status) {
+		t.Errorf("Expected status %q in body, got %v", strconv.Itoa(status), resp.body["status"])
+	}
+	if scimType != "" && resp.body["scimType"] != scimType {
+		t.Errorf("Expected scimType %q, got %v", scimType, resp.body["scimType"])
+	}
+}
+
+func emailValues(body map[string]interface{}) []string {
+	var values []string
+	emails, _ := body["emails"].([]interface{})
+	for _, e := range emails {
+		values = append(values, e.(map[string]interface{})["value"].(string))
+	}
+
+	return values
+}
+
+func TestSCIMCreateUser(t *testing.T) {
+	s := newSCIMTest(t)
+
+	resp := s.do(t, http.MethodPost, usersPath, rfcFullUser)
+	if resp.code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", resp.code, resp.body)
+	}
+
+	id, _ := resp.body["id"].(string)
+	if id == "" {
+		t.Fatal("Expected server assigned id")
+	}
+	if resp.body["userName"] != "bjensen@example.com" {
+		t.Errorf("Unexpected userName %v", resp.body["userName"])
+	}
+	if _, ok := resp.body["password"]; ok {
+		t.Error("password must never be returned")
+	}
+
+	meta, _ := resp.body["meta"].(map[string]interface{})
+	if meta["resourceType"] != "User" {
+		t.Errorf("Unexpected meta.resourceType %v", meta["resourceType"])
+	}
+	if location := "http://example.com/scim/v2/Users/" + id; meta["location"] != location || resp.header.Get("Location") != location {
+		t.Errorf("Unexpected location: meta %v, header %v", meta["location"], resp.header.Get("Location"))
+	}
+
+	user, err := s.ur.GetUserByID(context.Background(), id)
+	if err != nil || user.Email != "bjensen@example.com" {
+		t.Errorf("Expected primary email to be stored, got %v %v", user, err)
+	}
+
+	expectError(t, s.do(t, http.MethodPost, usersPath, rfcFullUser), http.StatusConflict, ScimTypeUniqueness)
+	expectError(t, s.do(t, http.MethodPost, usersPath, `{"userName": "nobody12", "emails": [{"value": "nobody@example.com"}]}`),
+		http.StatusBadRequest, ScimTypeInvalidSyntax)
+	expectError(t, s.do(t, http.MethodPost, usersPath, `{"schemas": ["`+UserSchema+`"], "userName": "noemail1"}`),
+		http.StatusBadRequest, ScimTypeInvalidValue)
+}
+
+func TestSCIMCreateUserWithoutPassword(t *testing.T) {
+	s := newSCIMTest(t)
+
+	id := s.createUser(t, `{"schemas": ["`+UserSchema+`"], "userName": "ssoonly", "emails": [{"value": "sso@example.com"}]}`)
+	user, err := s.ur.GetUserByID(context.Background(), id)
+	if err != nil || user.Password == "" {
+		t.Errorf("Expected a generated password hash, got %v %v", user, err)
+	}
+}
+
+func TestSCIMGetAndDeleteUser(t *testing.T) {
+	s := newSCIMTest(t)
+	id := s.createUser(t, rfcFullUser)
+
+	resp := s.do(t, http.MethodGet, usersPath+"/"+id, "")
+	if resp.code != http.StatusOK || resp.body["id"] != id {
+		t.Fatalf("Unexpected GET response %d: %v", resp.code, resp.body)
+	}
+
+	resp = s.do(t, http.MethodDelete, usersPath+"/"+id, "")
+	if resp.code != http.StatusNoContent || resp.body != nil {
+		t.Errorf("Expected 204 without body, got %d: %v", resp.code, resp.body)
+	}
+
+	expectError(t, s.do(t, http.MethodGet, usersPath+"/"+id, ""), http.StatusNotFound, "")
+	expectError(t, s.do(t, http.MethodDelete, usersPath+"/"+id, ""), http.StatusNotFound, "")
+}
+
+func TestSCIMFilterUsers(t *testing.T) {
+	s := newSCIMTest(t)
+	id := s.createUser(t, rfcFullUser)
+
+	tests := []struct {
+		filter string
+		total  float64
+	}{
+		{`userName eq "bjensen@example.com"`, 1},
+		{`userName Eq "BJENSEN@EXAMPLE.COM"`, 1},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bj"`, 1},
+		{`userName sw "J"`, 0},
+		{`emails co "example.com"`, 2},
+		{`emails.value co "@example.com" and userName sw "b"`, 1},
+		{`userName eq "admin" or userName eq "regular"`, 2},
+		{`not (userName eq "admin")`, 2},
+		{`id eq "` + id + `"`, 1},
+		{`active eq true`, 3},
+	}
+
+	for _, tt := range tests {
+		resp := s.do(t, http.MethodGet, listFilter(tt.filter), "")
+		if resp.code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %v", tt.filter, resp.code, resp.body)
+			continue
+		}
+		if resp.body["totalResults"] != tt.total {
+			t.Errorf("%s: expected %v results, got %v", tt.filter, tt.total, resp.body["totalResults"])
+		}
+		schemas, _ := resp.body["schemas"].([]interface{})
+		if len(schemas) != 1 || schemas[0] != ListResponseSchema {
+			t.Errorf("%s: unexpected schemas %v", tt.filter, resp.body["schemas"])
+		}
+	}
+
+	for _, invalid := range []string{
+		`meta.lastModified gt "2011-05-13T04:42:34Z"`,
+		`title pr`,
+		`userName eq`,
+		`userName eq "bjensen" and`,
+		`(userName eq "bjensen"`,
+		`name.familyName co "O'Malley"`,
+	} {
+		expectError(t, s.do(t, http.MethodGet, listFilter(invalid), ""), http.StatusBadRequest, ScimTypeInvalidFilter)
+	}
+}
+
+func TestSCIMPagination(t *testing.T) {
+	s := newSCIMTest(t)
+	s.createUser(t, rfcFullUser)
+
+	resp := s.do(t, http.MethodGet, usersPath+"?startIndex=2&count=1", "")
+	if resp.code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.code)
+	}
+	if resp.body["totalResults"] != float64(3) || resp.body["startIndex"] != float64(2) || resp.body["itemsPerPage"] != float64(1) {
+		t.Errorf("Unexpected pagination %v", resp.body)
+	}
+	resources, _ := resp.body["Resources"].([]interface{})
+	if len(resources) != 1 || resources[0].(map[string]interface{})["userName"] != "regular" {
+		t.Errorf("Unexpected page %v", resources)
+	}
+
+	resp = s.do(t, http.MethodGet, usersPath+"?startIndex=10", "")
+	if resources, _ := resp.body["Resources"].([]interface{}); resp.code != http.StatusOK || len(resources) != 0 {
+		t.Errorf("Expected an empty page, got %d: %v", resp.code, resp.body)
+	}
+}
+
+func TestSCIMReplaceUser(t *testing.T) {
+	s := newSCIMTest(t)
+	id := s.createUser(t, rfcFullUser)
+
+	resp := s.do(t, http.MethodPut, usersPath+"/"+id, rfcReplaceUser)
+	if resp.code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", resp.code, resp.body)
+	}
+	if resp.body["userName"] != "bjensen" || resp.body["id"] != id {
+		t.Errorf("Unexpected replaced user %v", resp.body)
+	}
+
+	user, _ := s.ur.GetUserByID(context.Background(), id)
+	if user.Username != "bjensen" || user.Email != "bjensen@example.com" {
+		t.Errorf("Unexpected stored user %+v", user)
+	}
+
+	expectError(t, s.do(t, http.MethodPut, usersPath+"/"+id, strings.Replace(rfcReplaceUser, `"userType"`, `"active": false, "userType"`, 1)),
+		http.StatusBadRequest, ScimTypeMutability)
+}
+
+func TestSCIMPatchUser(t *testing.T) {
+	s := newSCIMTest(t)
+	id := s.createUser(t, `{"schemas": ["`+UserSchema+`"], "userName": "bjensen", "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}]}`)
+
+	patch := func(operations string) *scimResponse {
+		return s.do(t, http.MethodPatch, usersPath+"/"+id, `{"schemas": ["`+PatchOpSchema+`"], "Operations": `+operations+`}`)
+	}
+
+	resp := patch(`[{"op": "add", "value": {"emails": [{"value": "babs@jensen.org", "type": "home"}], "nickName": "Babs"}}]`)
+	if resp.code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", resp.code, resp.body)
+	}
+	if got := emailValues(resp.body); len(got) != 1 || got[0] != "bjensen@example.com" {
+		t.Errorf("Expected primary email to stay, got %v", got)
+	}
+
+	resp = patch(`[{"op": "replace", "path": "userName", "value": "babsjensen"}]`)
+	if resp.code != http.StatusOK || resp.body["userName"] != "babsjensen" {
+		t.Errorf("Unexpected replace userName result %d: %v", resp.code, resp.body)
+	}
+
+	resp = patch(`[{"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "barbara@example.com"}]`)
+	if got := emailValues(resp.body); resp.code != http.StatusOK || len(got) != 1 || got[0] != "barbara@example.com" {
+		t.Errorf("Unexpected value path replace result %d: %v", resp.code, resp.body)
+	}
+
+	resp = patch(`[{"op": "replace", "value": {"userName": "bjensen2", "name": {"givenName": "Barbara"}}}, {"op": "replace", "path": "password", "value": "n3wPassword"}]`)
+	if resp.code != http.StatusOK || resp.body["userName"] != "bjensen2" {
+		t.Errorf("Unexpected multi-operation result %d: %v", resp.code, resp.body)
+	}
+	user, _ := s.ur.GetUserByUsernameAndPassword(context.Background(), "bjensen2", "n3wPassword")
+	if user == nil || user.ID != id {
+		t.Error("Expected password to be replaced")
+	}
+
+	expectError(t, patch(`[{"op": "remove", "path": "emails[type eq \"work\"]"}]`), http.StatusBadRequest, ScimTypeInvalidValue)
+	expectError(t, patch(`[{"op": "replace", "path": "emails[type eq \"home\"].value", "value": "x@example.com"}]`), http.StatusBadRequest, ScimTypeNoTarget)
+	expectError(t, patch(`[{"op": "remove"}]`), http.StatusBadRequest, ScimTypeNoTarget)
+	expectError(t, patch(`[{"op": "move", "path": "userName"}]`), http.StatusBadRequest, ScimTypeInvalidSyntax)
+	expectError(t, patch(`[{"op": "replace", "path": "emails[type eq", "value": "x"}]`), http.StatusBadRequest, ScimTypeInvalidPath)
+	expectError(t, patch(`[{"op": "replace", "path": "active", "value": "False"}]`), http.StatusBadRequest, ScimTypeMutability)
+	expectError(t, s.do(t, http.MethodPatch, usersPath+"/"+id, `{"Operations": [{"op": "remove", "path": "title"}]}`), http.StatusBadRequest, ScimTypeInvalidSyntax)
+
+	resp = patch(`[{"op": "remove", "path": "title"}]`)
+	if resp.code != http.StatusOK {
+		t.Errorf("Expected unknown attributes to be ignored, got %d: %v", resp.code, resp.body)
+	}
+}
+
+func TestSCIMAuthentication(t *testing.T) {
+	s := newSCIMTest(t)
+
+	expectError(t, s.doAs(t, "", http.MethodGet, usersPath, ""), http.StatusUnauthorized, "")
+	expectError(t, s.doAs(t, "unknown", http.MethodGet, usersPath, ""), http.StatusUnauthorized, "")
+	expectError(t, s.doAs(t, s.userToken, http.MethodGet, usersPath, ""), http.StatusForbidden, "")
+
+	req := httptest.NewRequest(http.MethodGet, Prefix+usersPath, nil)
+	req.Header.Set("Authorization", "Basic YWRtaW46YWRtaW4=")
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected basic auth to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestSCIMDiscovery(t *testing.T) {
+	s := newSCIMTest(t)
+
+	resp := s.doAs(t, "", http.MethodGet, serviceProviderConfigPath, "")
+	if resp.code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.code)
+	}
+	for _, feature := range []string{"patch", "filter"} {
+		if supported := resp.body[feature].(map[string]interface{})["supported"]; supported != true {
+			t.Errorf("Expected %s to be supported", feature)
+		}
+	}
+	if supported := resp.body["bulk"].(map[string]interface{})["supported"]; supported != false {
+		t.Error("Expected bulk to be unsupported")
+	}
+
+	resp = s.doAs(t, "", http.MethodGet, schemasPath, "")
+	resources, _ := resp.body["Resources"].([]interface{})
+	if resp.code != http.StatusOK || len(resources) != 1 || resources[0].(map[string]interface{})["id"] != UserSchema {
+		t.Errorf("Unexpected schemas response %d: %v", resp.code, resp.body)
+	}
+
+	resp = s.doAs(t, "", http.MethodGet, schemasPath+"/"+UserSchema, "")
+	if resp.code != http.StatusOK || resp.body["name"] != "User" {
+		t.Errorf("Unexpected schema response %d: %v", resp.code, resp.body)
+	}
+
+	resp = s.doAs(t, "", http.MethodGet, resourceTypesPath+"/User", "")
+	if resp.code != http.StatusOK || resp.body["endpoint"] != "/Users" {
+		t.Errorf("Unexpected resource type response %d: %v", resp.code, resp.body)
+	}
+
+	expectError(t, s.doAs(t, "", http.MethodGet, schemasPath+"/urn:unknown", ""), http.StatusNotFound, "")
+}
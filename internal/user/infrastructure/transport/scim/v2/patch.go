@@ -0,0 +1,314 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchAdd     = "add"
+	patchReplace = "replace"
+	patchRemove  = "remove"
+)
+
+var (
+	valuePathPattern     = regexp.MustCompile(`^emails\[(.+)\](?:\.(value|type|primary))?$`)
+	attributePathPattern = regexp.MustCompile(`^[a-z][a-z0-9_$-]*(\.[a-z][a-z0-9_$-]*)?$`)
+)
+
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (p *patchRequest) apply(res *userResource) error {
+	if !containsFold(p.Schemas, PatchOpSchema) {
+		return newError(http.StatusBadRequest, ScimTypeInvalidSyntax, "request must declare the "+PatchOpSchema+" schema")
+	}
+	if len(p.Operations) == 0 {
+		return newError(http.StatusBadRequest, ScimTypeInvalidSyntax, "at least one operation is required")
+	}
+
+	for _, op := range p.Operations {
+		name := strings.ToLower(op.Op)
+		if name != patchAdd && name != patchReplace && name != patchRemove {
+			return newError(http.StatusBadRequest, ScimTypeInvalidSyntax, fmt.Sprintf("unsupported operation %q", op.Op))
+		}
+
+		if err := applyOperation(res, name, normalizeAttributePath(op.Path), op.Value); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Emails) == 0 {
+		return newError(http.StatusBadRequest, ScimTypeInvalidValue, "at least one email is required")
+	}
+
+	return nil
+}
+
+func applyOperation(res *userResource, op, path string, value json.RawMessage) error {
+	if path == "" {
+		if op == patchRemove {
+			return newError(http.StatusBadRequest, ScimTypeNoTarget, "remove requires a path")
+		}
+
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attributes); err != nil {
+			return invalidValue("value must be an object when path is omitted")
+		}
+		for attr, v := range attributes {
+			if err := applyOperation(res, op, normalizeAttributePath(attr), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	switch path {
+	case "username":
+		if op == patchRemove {
+			return invalidValue("userName is required")
+		}
+		return decodeValue(value, &res.UserName)
+	case "password":
+		if op == patchRemove {
+			return invalidValue("password cannot be removed")
+		}
+		return decodeValue(value, &res.Password)
+	case "active":
+		if op == patchRemove {
+			return nil
+		}
+		active, err := decodeBool(value)
+		if err != nil {
+			return err
+		}
+		res.Active = &active
+		return res.validateActive()
+	case "emails":
+		return applyEmails(res, op, value)
+	case "emails.value":
+		if op == patchRemove {
+			return invalidValue("at least one email is required")
+		}
+		var v string
+		if err := decodeValue(value, &v); err != nil {
+			return err
+		}
+		if len(res.Emails) == 0 {
+			res.Emails = []email{{Value: v, Primary: true}}
+			return nil
+		}
+		res.Emails[primaryEmailIndex(res.Emails)].Value = v
+		return nil
+	}
+
+	if match := valuePathPattern.FindStringSubmatch(path); match != nil {
+		return applyEmailValuePath(res, op, match[1], match[2], value)
+	}
+
+	if !attributePathPattern.MatchString(path) {
+		return newError(http.StatusBadRequest, ScimTypeInvalidPath, fmt.Sprintf("invalid path %q", path))
+	}
+
+	return nil
+}
+
+func applyEmails(res *userResource, op string, value json.RawMessage) error {
+	if op == patchRemove {
+		res.Emails = nil
+		return nil
+	}
+
+	var emails []email
+	if err := json.Unmarshal(value, &emails); err != nil {
+		var single email
+		if err := json.Unmarshal(value, &single); err != nil {
+			return invalidValue("emails must be an array of email objects")
+		}
+		emails = []email{single}
+	}
+
+	if op == patchReplace {
+		res.Emails = emails
+		return nil
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			for i := range res.Emails {
+				res.Emails[i].Primary = false
+			}
+		}
+		res.Emails = append(res.Emails, e)
+	}
+
+	return nil
+}
+
+func applyEmailValuePath(res *userResource, op, expr, subAttr string, value json.RawMessage) error {
+	f, err := parseFilter(expr, emailFilterAttributes)
+	if err != nil {
+		return newError(http.StatusBadRequest, ScimTypeInvalidPath, err.Error())
+	}
+
+	var matched []int
+	for i := range res.Emails {
+		if f.matches(res.Emails[i].attributeValues()) {
+			matched = append(matched, i)
+		}
+	}
+
+	if op == patchRemove {
+		if subAttr == "value" {
+			return invalidValue("email value cannot be removed")
+		}
+		kept := res.Emails[:0]
+		for i, e := range res.Emails {
+			if !containsIndex(matched, i) {
+				kept = append(kept, e)
+			} else if subAttr != "" {
+				clearEmailAttribute(&e, subAttr)
+				kept = append(kept, e)
+			}
+		}
+		res.Emails = kept
+		return nil
+	}
+
+	if len(matched) == 0 {
+		if op == patchReplace {
+			return newError(http.StatusBadRequest, ScimTypeNoTarget, fmt.Sprintf("no email matches %q", expr))
+		}
+
+		created := emailFromFilter(f)
+		res.Emails = append(res.Emails, created)
+		matched = []int{len(res.Emails) - 1}
+	}
+
+	for _, i := range matched {
+		if err := setEmailAttribute(&res.Emails[i], subAttr, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setEmailAttribute(e *email, subAttr string, value json.RawMessage) error {
+	switch subAttr {
+	case "":
+		return decodeValue(value, e)
+	case "value":
+		return decodeValue(value, &e.Value)
+	case "type":
+		return decodeValue(value, &e.Type)
+	default:
+		primary, err := decodeBool(value)
+		if err != nil {
+			return err
+		}
+		e.Primary = primary
+		return nil
+	}
+}
+
+func clearEmailAttribute(e *email, subAttr string) {
+	switch subAttr {
+	case "type":
+		e.Type = ""
+	case "primary":
+		e.Primary = false
+	}
+}
+
+func emailFromFilter(f filter) email {
+	var e email
+	populateEmail(&e, f)
+
+	return e
+}
+
+func populateEmail(e *email, f filter) {
+	switch f := f.(type) {
+	case *comparisonFilter:
+		if f.op != opEqual {
+			return
+		}
+		switch v := f.value.(type) {
+		case string:
+			if f.attr == "type" {
+				e.Type = v
+			}
+		case bool:
+			if f.attr == "primary" {
+				e.Primary = v
+			}
+		}
+	case *logicalFilter:
+		if f.and {
+			populateEmail(e, f.left)
+			populateEmail(e, f.right)
+		}
+	}
+}
+
+func primaryEmailIndex(emails []email) int {
+	for i, e := range emails {
+		if e.Primary {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func containsIndex(indexes []int, target int) bool {
+	for _, i := range indexes {
+		if i == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeValue(value json.RawMessage, target interface{}) error {
+	if err := json.Unmarshal(value, target); err != nil {
+		return invalidValue(fmt.Sprintf("invalid value: %s", string(value)))
+	}
+
+	return nil
+}
+
+func decodeBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if parsed, err := strconv.ParseBool(s); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return false, invalidValue(fmt.Sprintf("invalid boolean: %s", string(value)))
+}
+
+func invalidValue(detail string) *Error {
+	return newError(http.StatusBadRequest, ScimTypeInvalidValue, detail)
+}
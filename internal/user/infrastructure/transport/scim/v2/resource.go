@@ -0,0 +1,130 @@
+package v2
+
+import (
+	"net/http"
+	"strings"
+	"userCRUD/internal/user/domain/model"
+)
+
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	userResourceType = "User"
+)
+
+type userResource struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id,omitempty"`
+	UserName string   `json:"userName"`
+	Password string   `json:"password,omitempty"`
+	Emails   []email  `json:"emails,omitempty"`
+	Active   *bool    `json:"active,omitempty"`
+	Meta     *meta    `json:"meta,omitempty"`
+}
+
+type email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+type listResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+func toResource(user *model.User, baseURL string) *userResource {
+	active := true
+	return &userResource{
+		Schemas:  []string{UserSchema},
+		ID:       user.ID,
+		UserName: user.Username,
+		Emails:   []email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:   &active,
+		Meta: &meta{
+			ResourceType: userResourceType,
+			Location:     baseURL + usersPath + "/" + user.ID,
+		},
+	}
+}
+
+func (r *userResource) primaryEmail() string {
+	for _, e := range r.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(r.Emails) > 0 {
+		return r.Emails[0].Value
+	}
+
+	return ""
+}
+
+func (r *userResource) validate() error {
+	if !containsFold(r.Schemas, UserSchema) {
+		return newError(http.StatusBadRequest, ScimTypeInvalidSyntax, "request must declare the "+UserSchema+" schema")
+	}
+	if r.UserName == "" {
+		return newError(http.StatusBadRequest, ScimTypeInvalidValue, "userName is required")
+	}
+	if r.primaryEmail() == "" {
+		return newError(http.StatusBadRequest, ScimTypeInvalidValue, "at least one email is required")
+	}
+
+	return r.validateActive()
+}
+
+func (r *userResource) validateActive() error {
+	if r.Active != nil && !*r.Active {
+		return newError(http.StatusBadRequest, ScimTypeMutability, "users cannot be deactivated, delete the user instead")
+	}
+
+	return nil
+}
+
+func (r *userResource) toUpdate(existing *model.User) *model.UpdateUser {
+	return &model.UpdateUser{
+		ID:                 existing.ID,
+		Email:              r.primaryEmail(),
+		Username:           r.UserName,
+		Password:           r.Password,
+		Admin:              existing.Admin,
+		Permissions:        existing.Permissions,
+		MustChangePassword: existing.MustChangePassword,
+	}
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host + Prefix
+}
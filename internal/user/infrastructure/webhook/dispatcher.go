@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+type Payload struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	UserID     string       `json:"user_id"`
+	User       *UserPayload `json:"user,omitempty"`
+}
+
+type UserPayload struct {
+	ID                 string   `json:"id"`
+	Email              string   `json:"email"`
+	Username           string   `json:"username"`
+	Admin              bool     `json:"admin"`
+	Permissions        []string `json:"permissions,omitempty"`
+	MustChangePassword bool     `json:"must_change_password"`
+}
+
+type Dispatcher struct {
+	wr          persistence.WebhookRepository
+	sender      command.WebhookSender
+	l           deps.Logger
+	queue       chan *model.Delivery
+	wake        chan struct{}
+	workers     int
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+func NewDispatcher(
+	wr persistence.WebhookRepository,
+	s command.WebhookSender,
+	l deps.Logger,
+	workers, maxAttempts int,
+	backoffBase, backoffMax time.Duration,
+) *Dispatcher {
+	return &Dispatcher{
+		wr:          wr,
+		sender:      s,
+		l:           l,
+		queue:       make(chan *model.Delivery),
+		wake:        make(chan struct{}, 1),
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		backoffBase: backoffBase,
+		backoffMax:  backoffMax,
+		inflight:    make(map[string]bool),
+	}
+}
+
+func (d *Dispatcher) Name() string {
+	return "webhook"
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, record event.Record) error {
+	webhooks, err := d.wr.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(newPayload(record.Event))
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]*model.Delivery, 0, len(webhooks))
+	for _, w := range webhooks {
+		if !w.Accepts(string(record.Event.Type)) {
+			continue
+		}
+
+		deliveries = append(deliveries, &model.Delivery{
+			ID:            uuid.New().String(),
+			WebhookID:     w.ID,
+			EventID:       record.Event.ID,
+			EventType:     string(record.Event.Type),
+			Payload:       payload,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.wr.AddDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+	d.notify()
+
+	return nil
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.queue:
+					d.deliver(ctx, job)
+					d.release(job)
+				}
+			}
+		}()
+	}
+
+	for {
+		var retry <-chan time.Time
+		if wait, ok := d.dispatchDue(ctx); ok {
+			retry = time.After(wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-d.wake:
+		case <-retry:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchDue(ctx context.Context) (time.Duration, bool) {
+	deliveries, err := d.wr.ListDeliveries(ctx)
+	if err != nil {
+		d.l.Error(ctx, "Failed to list pending webhook deliveries", "error", err)
+		return d.backoffBase, true
+	}
+
+	var wait time.Duration
+	scheduled := false
+	for _, job := range deliveries {
+		if delay := time.Until(job.NextAttemptAt); delay > 0 {
+			if !scheduled || delay < wait {
+				wait, scheduled = delay, true
+			}
+			continue
+		}
+		if !d.claim(job) {
+			continue
+		}
+
+		select {
+		case d.queue <- job:
+		case <-ctx.Done():
+			return 0, false
+		}
+	}
+
+	return wait, scheduled
+}
+
+func (d *Dispatcher) claim(job *model.Delivery) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.inflight[job.ID] {
+		return false
+	}
+	d.inflight[job.ID] = true
+
+	return true
+}
+
+func (d *Dispatcher) release(job *model.Delivery) {
+	d.mu.Lock()
+	delete(d.inflight, job.ID)
+	d.mu.Unlock()
+
+	d.notify()
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job *model.Delivery) {
+	webhook, err := d.wr.GetWebhook(ctx, job.WebhookID)
+	if errors.Is(err, persistence.ErrWebhookNotFound) {
+		d.l.Info(ctx, "Webhook deleted, dropping delivery", "webhookID", job.WebhookID, "eventID", job.EventID)
+		d.remove(ctx, job)
+		return
+	}
+
+	job.Attempts++
+	if err == nil {
+		err = d.sender.Send(ctx, webhook, job.EventID, job.EventType, job.Payload)
+	}
+	if err == nil {
+		d.remove(ctx, job)
+		return
+	}
+
+	d.l.Error(ctx, "Webhook delivery failed",
+		"webhookID", job.WebhookID,
+		"eventID", job.EventID,
+		"attempt", job.Attempts,
+		"error", err,
+	)
+	job.LastError = err.Error()
+
+	if job.Attempts >= d.maxAttempts {
+		d.deadLetter(ctx, job)
+		d.remove(ctx, job)
+		return
+	}
+
+	job.NextAttemptAt = time.Now().Add(d.backoff(job.Attempts))
+	if err := d.wr.UpdateDelivery(ctx, job); err != nil && !errors.Is(err, persistence.ErrDeliveryNotFound) {
+		d.l.Error(ctx, "Failed to reschedule webhook delivery", "webhookID", job.WebhookID, "eventID", job.EventID, "error", err)
+	}
+}
+
+func (d *Dispatcher) remove(ctx context.Context, job *model.Delivery) {
+	if err := d.wr.DeleteDelivery(ctx, job.ID); err != nil && !errors.Is(err, persistence.ErrDeliveryNotFound) {
+		d.l.Error(ctx, "Failed to remove webhook delivery", "webhookID", job.WebhookID, "eventID", job.EventID, "error", err)
+	}
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.backoffBase
+	for i := 1; i < attempts && backoff < d.backoffMax; i++ {
+		backoff *= 2
+	}
+
+	if backoff > d.backoffMax {
+		return d.backoffMax
+	}
+
+	return backoff
+}
+
+func (d *Dispatcher) deadLetter(ctx context.Context, job *model.Delivery) {
+	err := d.wr.AddDeadLetter(ctx, &model.DeadLetter{
+		ID:        uuid.New().String(),
+		WebhookID: job.WebhookID,
+		EventID:   job.EventID,
+		EventType: job.EventType,
+		Payload:   job.Payload,
+		Attempts:  job.Attempts,
+		LastError: job.LastError,
+		FailedAt:  time.Now(),
+	})
+	if err != nil {
+		d.l.Error(ctx, "Failed to store webhook dead letter", "webhookID", job.WebhookID, "eventID", job.EventID, "error", err)
+	}
+}
+
+func newPayload(e event.Event) *Payload {
+	payload := &Payload{
+		ID:         e.ID,
+		Type:       string(e.Type),
+		OccurredAt: e.OccurredAt,
+		UserID:     e.UserID,
+	}
+
+	if e.User != nil {
+		permissions := make([]string, len(e.User.Permissions))
+		for i, p := range e.User.Permissions {
+			permissions[i] = string(p)
+		}
+
+		payload.User = &UserPayload{
+			ID:                 e.User.ID,
+			Email:              e.User.Email,
+			Username:           e.User.Username,
+			Admin:              e.User.Admin,
+			Permissions:        permissions,
+			MustChangePassword: e.User.MustChangePassword,
+		}
+	}
+
+	return payload
+}
@@ -0,0 +1,304 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"userCRUD/internal/common/constants"
+	"userCRUD/internal/common/deps"
+	"userCRUD/internal/user/domain/command"
+	"userCRUD/internal/user/domain/event"
+	"userCRUD/internal/user/domain/model"
+	"userCRUD/internal/user/infrastructure/persistence"
+)
+
+var adminCtx = context.WithValue(context.Background(), constants.UserContextKey, &model.User{Admin: true})
+
+type receiver struct {
+	sync.Mutex
+	secret   string
+	failures int
+	payloads []Payload
+	invalid  int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	if !Verify(r.secret, req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body) {
+		r.invalid++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.failures != 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var payload Payload
+	json.Unmarshal(body, &payload)
+	r.payloads = append(r.payloads, payload)
+}
+
+func (r *receiver) received() []Payload {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]Payload(nil), r.payloads...)
+}
+
+func newTestDispatcher(t *testing.T, maxAttempts int) (*Dispatcher, *command.Webhook, *persistence.WebhookRepositoryMemory) {
+	wr := persistence.NewWebhookRepositoryMemory()
+	sender := NewSender(time.Second)
+	d := NewDispatcher(wr, sender, &deps.MockLogger{}, 2, maxAttempts, time.Millisecond, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return d, command.NewWebhookCommand(wr, sender, deps.NewGoPlaygroundValidator()), wr
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func record(offset uint64, t event.Type) event.Record {
+	return event.Record{
+		Offset: offset,
+		Event: event.Event{
+			ID:     "event-" + string(t),
+			Type:   t,
+			UserID: "user-1",
+			User:   &event.UserData{ID: "user-1", Email: "user@example.com", Username: "user1"},
+		},
+	}
+}
+
+func TestDispatcherRetriesSignedDeliveries(t *testing.T) {
+	d, wc, _ := newTestDispatcher(t, 5)
+
+	rcv := &receiver{secret: "0123456789abcdef0123", failures: 2}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	filtered := &receiver{secret: "0123456789abcdef0123"}
+	filteredSrv := httptest.NewServer(filtered)
+	defer filteredSrv.Close()
+
+	if _, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: srv.URL, Secret: rcv.secret}); err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+	if _, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: filteredSrv.URL, Secret: filtered.secret, EventTypes: []string{"user.deleted"}}); err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+
+	if err := d.Publish(context.Background(), record(1, event.UserCreated)); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	waitFor(t, func() bool { return len(rcv.received()) == 1 })
+
+	payload := rcv.received()[0]
+	if payload.Type != "user.created" || payload.User == nil || payload.User.Email != "user@example.com" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+	if rcv.invalid != 0 {
+		t.Errorf("Expected all signatures to verify, %d failed", rcv.invalid)
+	}
+
+	d.Publish(context.Background(), record(2, event.UserDeleted))
+	waitFor(t, func() bool { return len(filtered.received()) == 1 && len(rcv.received()) == 2 })
+
+	if filtered.received()[0].Type != "user.deleted" {
+		t.Errorf("Filtered webhook must receive only user.deleted, got %+v", filtered.received())
+	}
+}
+
+func TestDispatcherDeadLettersAndRedelivers(t *testing.T) {
+	d, wc, wr := newTestDispatcher(t, 3)
+
+	rcv := &receiver{secret: "0123456789abcdef0123", failures: -1}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	webhook, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: srv.URL, Secret: rcv.secret})
+	if err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+
+	d.Publish(context.Background(), record(1, event.PasswordChanged))
+
+	var deadLetters []*model.DeadLetter
+	waitFor(t, func() bool {
+		deadLetters, _ = wr.ListDeadLetters(context.Background(), webhook.ID)
+		return len(deadLetters) == 1
+	})
+
+	if deadLetters[0].Attempts != 3 || deadLetters[0].EventType != "user.password_changed" {
+		t.Errorf("Unexpected dead letter %+v", deadLetters[0])
+	}
+
+	rcv.Lock()
+	rcv.failures = 0
+	rcv.Unlock()
+
+	if err := wc.Redeliver(adminCtx, &model.WebhookByID{ID: deadLetters[0].ID}); err != nil {
+		t.Fatalf("Failed to redeliver: %v", err)
+	}
+
+	if len(rcv.received()) != 1 {
+		t.Errorf("Expected redelivered event, got %d", len(rcv.received()))
+	}
+	if remaining, _ := wc.ListDeadLetters(adminCtx, webhook.ID); len(remaining) != 0 {
+		t.Errorf("Expected dead letter to be removed, got %d", len(remaining))
+	}
+}
+
+func TestDispatcherAcksQueuedDeliveries(t *testing.T) {
+	d, wc, wr := newTestDispatcher(t, 1000)
+
+	rcv := &receiver{secret: "0123456789abcdef0123", failures: -1}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	webhook, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: srv.URL, Secret: rcv.secret})
+	if err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+
+	if err := d.Publish(context.Background(), record(1, event.UserCreated)); err != nil {
+		t.Fatalf("Expected Publish to ack a queued delivery, got %v", err)
+	}
+	if err := d.Publish(context.Background(), record(1, event.UserCreated)); err != nil {
+		t.Fatalf("Expected a replayed event to be acked, got %v", err)
+	}
+	if deliveries, _ := wr.ListDeliveries(context.Background()); len(deliveries) != 1 {
+		t.Fatalf("Expected the replayed event to be queued once, got %d deliveries", len(deliveries))
+	}
+
+	waitFor(t, func() bool {
+		deliveries, _ := wr.ListDeliveries(context.Background())
+		return len(deliveries) == 1 && deliveries[0].Attempts > 1
+	})
+	if err := wr.DeleteWebhook(context.Background(), webhook.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		deliveries, _ := wr.ListDeliveries(context.Background())
+		return len(deliveries) == 0
+	})
+	if deadLetters, _ := wr.ListDeadLetters(context.Background(), webhook.ID); len(deadLetters) != 0 {
+		t.Errorf("Expected no dead letters for a deleted webhook, got %d", len(deadLetters))
+	}
+}
+
+func TestDispatcherIsolatesFailingWebhook(t *testing.T) {
+	wr := persistence.NewWebhookRepositoryMemory()
+	sender := NewSender(time.Second)
+	d := NewDispatcher(wr, sender, &deps.MockLogger{}, 2, 8, time.Second, 5*time.Minute)
+	wc := command.NewWebhookCommand(wr, sender, deps.NewGoPlaygroundValidator())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	broken := &receiver{secret: "0123456789abcdef0123", failures: -1}
+	brokenSrv := httptest.NewServer(broken)
+	defer brokenSrv.Close()
+
+	healthy := &receiver{secret: "0123456789abcdef0123"}
+	healthySrv := httptest.NewServer(healthy)
+	defer healthySrv.Close()
+
+	if _, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: brokenSrv.URL, Secret: broken.secret}); err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+	if _, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: healthySrv.URL, Secret: healthy.secret}); err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+
+	types := []event.Type{event.UserCreated, event.UserUpdated, event.PasswordChanged, event.UserDeleted}
+	start := time.Now()
+	for i, typ := range types {
+		if err := d.Publish(context.Background(), record(uint64(i+1), typ)); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+
+	waitFor(t, func() bool { return len(healthy.received()) == len(types) })
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the healthy webhook to get events before the broken one is retried, took %v", elapsed)
+	}
+}
+
+func TestFailedRedeliveryKeepsDeadLetter(t *testing.T) {
+	d, wc, wr := newTestDispatcher(t, 1)
+
+	rcv := &receiver{secret: "0123456789abcdef0123", failures: -1}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	webhook, err := wc.RegisterWebhook(adminCtx, &model.Webhook{URL: srv.URL, Secret: rcv.secret})
+	if err != nil {
+		t.Fatalf("Failed to register webhook: %v", err)
+	}
+	if err := d.Publish(context.Background(), record(1, event.UserDeleted)); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	var deadLetters []*model.DeadLetter
+	waitFor(t, func() bool {
+		deadLetters, _ = wr.ListDeadLetters(context.Background(), webhook.ID)
+		return len(deadLetters) == 1
+	})
+
+	if err := wc.Redeliver(adminCtx, &model.WebhookByID{ID: deadLetters[0].ID}); !errors.Is(err, command.ErrWebhookDeliveryFailed) {
+		t.Fatalf("Expected ErrWebhookDeliveryFailed, got %v", err)
+	}
+	remaining, _ := wr.ListDeadLetters(context.Background(), webhook.ID)
+	if len(remaining) != 1 || remaining[0].Attempts != 2 {
+		t.Errorf("Expected the dead letter to be kept with 2 attempts, got %+v", remaining)
+	}
+
+	if err := wr.DeleteWebhook(context.Background(), webhook.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if err := wc.Redeliver(adminCtx, &model.WebhookByID{ID: deadLetters[0].ID}); !errors.Is(err, persistence.ErrWebhookNotFound) {
+		t.Fatalf("Expected ErrWebhookNotFound, got %v", err)
+	}
+	if remaining, _ := wr.ListDeadLetters(context.Background(), webhook.ID); len(remaining) != 1 {
+		t.Errorf("Expected the dead letter to survive a missing webhook, got %d", len(remaining))
+	}
+}
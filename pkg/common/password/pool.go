@@ -0,0 +1,71 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrHashingOverloaded = errors.New("password hashing is overloaded, try again later")
+
+type Pool struct {
+	cost     int
+	workers  chan struct{}
+	admitted chan struct{}
+}
+
+func NewPool(cost, concurrency, queueDepth int) *Pool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+
+	return &Pool{
+		cost:     cost,
+		workers:  make(chan struct{}, concurrency),
+		admitted: make(chan struct{}, concurrency+queueDepth),
+	}
+}
+
+func (p *Pool) Hash(ctx context.Context, password string) (string, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), p.cost)
+	return string(bytes), err
+}
+
+func (p *Pool) Compare(ctx context.Context, password, hash string) (bool, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil, nil
+}
+
+func (p *Pool) acquire(ctx context.Context) (func(), error) {
+	select {
+	case p.admitted <- struct{}{}:
+	default:
+		return nil, ErrHashingOverloaded
+	}
+
+	select {
+	case p.workers <- struct{}{}:
+	case <-ctx.Done():
+		<-p.admitted
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-p.workers
+		<-p.admitted
+	}, nil
+}
@@ -0,0 +1,127 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolHashAndCompare(t *testing.T) {
+	pool := NewPool(bcrypt.MinCost, 2, 2)
+
+	hash, err := pool.Hash(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	match, err := pool.Compare(context.Background(), "password", hash)
+	if err != nil || !match {
+		t.Errorf("Expected password to match, got %v, %v", match, err)
+	}
+
+	match, err = pool.Compare(context.Background(), "wrong", hash)
+	if err != nil || match {
+		t.Errorf("Expected password not to match, got %v, %v", match, err)
+	}
+}
+
+func TestPoolRejectsWhenQueueIsFull(t *testing.T) {
+	pool := NewPool(bcrypt.MinCost, 1, 1)
+
+	releaseWorker, err := pool.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire worker: %v", err)
+	}
+
+	queued := make(chan error, 1)
+	go func() {
+		release, err := pool.acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for len(pool.admitted) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := pool.Hash(context.Background(), "password"); !errors.Is(err, ErrHashingOverloaded) {
+		t.Errorf("Expected ErrHashingOverloaded, got %v", err)
+	}
+
+	releaseWorker()
+	if err := <-queued; err != nil {
+		t.Errorf("Queued call failed: %v", err)
+	}
+}
+
+func TestPoolRespectsContextWhileWaiting(t *testing.T) {
+	pool := NewPool(bcrypt.MinCost, 1, 4)
+
+	release, err := pool.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire worker: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := pool.Hash(ctx, "password"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(pool.admitted) != 1 {
+		t.Errorf("Expected waiting slot to be released, %d still admitted", len(pool.admitted))
+	}
+}
+
+// BenchmarkPoolUnderLoad floods a small pool from many goroutines. Calls beyond
+// the queue depth are rejected immediately (and back off for a millisecond, as
+// a client would), so the latency of accepted calls stays bounded by roughly
+// (concurrency+queueDepth)/concurrency hash durations.
+func BenchmarkPoolUnderLoad(b *testing.B) {
+	pool := NewPool(bcrypt.MinCost+2, 2, 4)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		rejected  int
+	)
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			start := time.Now()
+			_, err := pool.Hash(context.Background(), "password")
+			elapsed := time.Since(start)
+
+			mu.Lock()
+			if errors.Is(err, ErrHashingOverloaded) {
+				rejected++
+			} else {
+				latencies = append(latencies, elapsed)
+			}
+			mu.Unlock()
+
+			if err != nil {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+	b.StopTimer()
+
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Microseconds()), "p99-µs")
+	b.ReportMetric(float64(latencies[len(latencies)-1].Microseconds()), "max-µs")
+	b.ReportMetric(float64(rejected)/float64(b.N), "rejected/op")
+}